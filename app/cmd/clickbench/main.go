@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/domain/click"
+	"github.com/clickcounter/app/internal/infrastructure/config"
+	"github.com/clickcounter/app/internal/infrastructure/database/postgres"
+	"github.com/clickcounter/app/pkg/logger"
+)
+
+// Бенчмарк массовой вставки кликов: сравнивает pgx.Batch INSERT и COPY protocol
+// на одном и том же наборе данных. Запускать против тестовой базы: вставленные
+// строки помечаются уникальным User-Agent и удаляются после прогона
+func main() {
+	var (
+		bannerID   = flag.Int64("banner", 1, "ID существующего баннера")
+		batchSize  = flag.Int("batch", 2000, "Размер батча")
+		batches    = flag.Int("batches", 50, "Количество батчей на каждый способ")
+		cleanup    = flag.Bool("cleanup", true, "Удалить вставленные строки после прогона")
+		randomSeed = flag.Int64("seed", 42, "Seed генератора данных")
+	)
+	flag.Parse()
+
+	appLogger := logger.NewLogger()
+	appLogger.SetLevel(logrus.WarnLevel)
+
+	cfg, err := config.Load()
+	if err != nil {
+		appLogger.WithError(err).Fatal("Failed to load configuration")
+	}
+
+	dbConn, err := postgres.NewDB(&postgres.Config{
+		Host:            cfg.Database.Host,
+		Port:            cfg.Database.Port,
+		User:            cfg.Database.User,
+		Password:        cfg.Database.Password,
+		DBName:          cfg.Database.Database,
+		SSLMode:         cfg.Database.SSLMode,
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: time.Duration(cfg.Database.ConnMaxLifetime) * time.Second,
+	}, appLogger)
+	if err != nil {
+		appLogger.WithError(err).Fatal("Failed to connect to database")
+	}
+	defer dbConn.Close()
+
+	repo := postgres.NewClickRepository(dbConn, appLogger)
+	marker := fmt.Sprintf("clickbench/%d", time.Now().UnixNano())
+	dataset := generateDataset(*bannerID, *batchSize, *batches, marker, *randomSeed)
+
+	fmt.Printf("Click bulk insert benchmark\n")
+	fmt.Printf("===========================\n")
+	fmt.Printf("Batch size: %d, batches: %d, rows per mode: %d\n\n", *batchSize, *batches, *batchSize**batches)
+
+	ctx := context.Background()
+	modes := []struct {
+		name string
+		fn   func(context.Context, []*click.Click) error
+	}{
+		{"batch", repo.InsertBatch},
+		{"copy", repo.CopyBatch},
+	}
+
+	fmt.Printf("%-8s | %-12s | %-12s | %-12s | %-12s\n", "Mode", "Total", "Rows/sec", "P50/batch", "P99/batch")
+	fmt.Printf("----------------------------------------------------------------\n")
+
+	for _, mode := range modes {
+		durations := make([]time.Duration, 0, len(dataset))
+		start := time.Now()
+
+		for _, batch := range dataset {
+			batchStart := time.Now()
+			if err := mode.fn(ctx, batch); err != nil {
+				appLogger.WithError(err).WithField("mode", mode.name).Fatal("Bulk insert failed")
+			}
+			durations = append(durations, time.Since(batchStart))
+		}
+
+		total := time.Since(start)
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+		fmt.Printf("%-8s | %-12v | %-12.0f | %-12v | %-12v\n",
+			mode.name,
+			total.Truncate(time.Millisecond),
+			float64(*batchSize**batches)/total.Seconds(),
+			durations[len(durations)*50/100].Truncate(time.Microsecond),
+			durations[len(durations)*99/100].Truncate(time.Microsecond),
+		)
+	}
+
+	if *cleanup {
+		tag, err := dbConn.Pool.Exec(ctx, `DELETE FROM clicks WHERE user_agent = $1`, marker)
+		if err != nil {
+			appLogger.WithError(err).Fatal("Failed to clean up benchmark rows")
+		}
+		fmt.Printf("\nCleaned up %d benchmark rows\n", tag.RowsAffected())
+	}
+}
+
+// generateDataset создает одинаковый набор батчей для всех способов вставки
+func generateDataset(bannerID int64, batchSize, batches int, userAgent string, seed int64) [][]*click.Click {
+	rnd := rand.New(rand.NewSource(seed))
+	now := time.Now().Add(-time.Hour)
+
+	dataset := make([][]*click.Click, batches)
+	for b := range dataset {
+		batch := make([]*click.Click, batchSize)
+		for i := range batch {
+			batch[i] = &click.Click{
+				BannerID:  bannerID,
+				Timestamp: now.Add(time.Duration(rnd.Int63n(int64(time.Hour)))),
+				UserIP:    fmt.Sprintf("10.%d.%d.%d", rnd.Intn(256), rnd.Intn(256), rnd.Intn(256)),
+				UserAgent: userAgent,
+			}
+		}
+		dataset[b] = batch
+	}
+
+	return dataset
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/application/worker"
+	"github.com/clickcounter/app/internal/domain/stats"
+	"github.com/clickcounter/app/internal/infrastructure/config"
+	"github.com/clickcounter/app/internal/infrastructure/database/postgres"
+	"github.com/clickcounter/app/internal/interfaces/http/middleware"
+)
+
+// runCommand выполняет служебную подкоманду вместо запуска HTTP сервера
+// и возвращает код завершения процесса
+func runCommand(args []string, appLogger *logrus.Logger) int {
+	switch args[0] {
+	case "deadletter":
+		return runDeadLetterCommand(args[1:], appLogger)
+	case "migrate":
+		return runMigrateCommand(args[1:], appLogger)
+	case "tenant":
+		return runTenantCommand(args[1:], appLogger)
+	case "help", "-h", "--help":
+		printUsage()
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", args[0])
+		printUsage()
+		return 2
+	}
+}
+
+// printUsage выводит список доступных подкоманд
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: server [command]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Without a command the HTTP server is started.")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  deadletter list      List dead-lettered click batches")
+	fmt.Fprintln(os.Stderr, "  deadletter reingest  Save dead-lettered click batches to the database")
+	fmt.Fprintln(os.Stderr, "  migrate up           Apply all pending migrations")
+	fmt.Fprintln(os.Stderr, "  migrate down [N]     Revert the last N migrations (default 1)")
+	fmt.Fprintln(os.Stderr, "  migrate status       Show the schema version and migrations")
+	fmt.Fprintln(os.Stderr, "  migrate to N         Apply or revert migrations up to version N")
+	fmt.Fprintln(os.Stderr, "  tenant list          List tenants")
+	fmt.Fprintln(os.Stderr, "  tenant create NAME   Create a tenant and print its ID")
+}
+
+// newDBConfig формирует конфигурацию подключения к БД из конфигурации приложения
+func newDBConfig(cfg *config.Config) *postgres.Config {
+	return &postgres.Config{
+		Host:            cfg.Database.Host,
+		Port:            cfg.Database.Port,
+		User:            cfg.Database.User,
+		Password:        cfg.Database.Password,
+		DBName:          cfg.Database.Database,
+		SSLMode:         cfg.Database.SSLMode,
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: time.Duration(cfg.Database.ConnMaxLifetime) * time.Second,
+	}
+}
+
+// newRateLimitRules собирает правила rate limiting из конфигурации
+func newRateLimitRules(rules []config.RateLimitConfig) []middleware.RateLimitRule {
+	result := make([]middleware.RateLimitRule, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, middleware.RateLimitRule{
+			Name:   rule.Name,
+			Key:    rule.Key,
+			Routes: rule.Routes,
+			RPS:    rule.RPS,
+			Burst:  rule.Burst,
+		})
+	}
+	return result
+}
+
+// newRollupSourceRetention возвращает сроки хранения исходных таблиц уровней свертки
+// (nil, если очистка отключена)
+func newRollupSourceRetention(cfg *config.Config) map[stats.RollupLevel]time.Duration {
+	if !cfg.Retention.Enabled {
+		return nil
+	}
+
+	policy := newRetentionPolicy(cfg)
+	return map[stats.RollupLevel]time.Duration{
+		stats.RollupHourly: policy.Stats,
+		stats.RollupDaily:  policy.Hourly,
+	}
+}
+
+// newRetentionPolicy преобразует конфигурацию сроков хранения в политику очистки
+func newRetentionPolicy(cfg *config.Config) worker.RetentionPolicy {
+	day := 24 * time.Hour
+	return worker.RetentionPolicy{
+		Clicks:     time.Duration(cfg.Retention.ClicksDays) * day,
+		Stats:      time.Duration(cfg.Retention.StatsDays) * day,
+		Hourly:     time.Duration(cfg.Retention.HourlyDays) * day,
+		Daily:      time.Duration(cfg.Retention.DailyDays) * day,
+		ChunkSize:  cfg.Retention.ChunkSize,
+		ChunkPause: time.Duration(cfg.Retention.ChunkPause) * time.Millisecond,
+		// Без фоновой агрегации клики учитываются в stats в одной транзакции с записью
+		RequireAggregated: cfg.StatsAggregator.Enabled,
+		// Без свертки запросы читают только минутную статистику
+		RequireRollup: cfg.StatsRollup.Enabled,
+	}
+}
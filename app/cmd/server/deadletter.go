@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/domain/click"
+	"github.com/clickcounter/app/internal/domain/tenant"
+	"github.com/clickcounter/app/internal/infrastructure/config"
+	"github.com/clickcounter/app/internal/infrastructure/database/postgres"
+	"github.com/clickcounter/app/internal/infrastructure/deadletter"
+)
+
+// runDeadLetterCommand обрабатывает подкоманды "deadletter list" и "deadletter reingest"
+func runDeadLetterCommand(args []string, appLogger *logrus.Logger) int {
+	if len(args) != 1 || (args[0] != "list" && args[0] != "reingest") {
+		fmt.Fprintln(os.Stderr, "Usage: server deadletter list|reingest")
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		appLogger.WithError(err).Error("Failed to load configuration")
+		return 1
+	}
+
+	if cfg.ClickFlusher.DeadLetterDir == "" {
+		appLogger.Error("Dead-letter directory is not configured (click_flusher.dead_letter_dir)")
+		return 1
+	}
+
+	store, err := deadletter.NewFileStore(cfg.ClickFlusher.DeadLetterDir, appLogger)
+	if err != nil {
+		appLogger.WithError(err).Error("Failed to open dead-letter directory")
+		return 1
+	}
+
+	files, err := store.List()
+	if err != nil {
+		appLogger.WithError(err).Error("Failed to list dead-letter files")
+		return 1
+	}
+
+	if args[0] == "list" {
+		total := 0
+		for _, path := range files {
+			clicks, err := store.Read(path)
+			if err != nil {
+				appLogger.WithError(err).WithField("file", filepath.Base(path)).Error("Failed to read dead-letter file")
+				return 1
+			}
+			total += len(clicks)
+			fmt.Printf("%s\t%d\n", filepath.Base(path), len(clicks))
+		}
+		fmt.Printf("Total: %d files, %d clicks\n", len(files), total)
+		return 0
+	}
+
+	return reingestDeadLetters(cfg, store, files, appLogger)
+}
+
+// reingestDeadLetters сохраняет клики из dead-letter файлов в БД и удаляет обработанные файлы.
+// Обработка останавливается на первой ошибке, чтобы не терять порядок и не дублировать клики
+func reingestDeadLetters(cfg *config.Config, store *deadletter.FileStore, files []string, appLogger *logrus.Logger) int {
+	if len(files) == 0 {
+		appLogger.Info("No dead-letter files to reingest")
+		return 0
+	}
+
+	dbConn, err := postgres.NewDB(newDBConfig(cfg), appLogger)
+	if err != nil {
+		appLogger.WithError(err).Error("Failed to connect to database")
+		return 1
+	}
+	defer dbConn.Close()
+
+	clickRepo := postgres.NewClickRepository(dbConn, appLogger)
+	insertMode, err := postgres.ParseInsertMode(cfg.ClickFlusher.InsertMode)
+	if err != nil {
+		appLogger.WithError(err).Error("Invalid click flusher configuration")
+		return 1
+	}
+	clickRepo.SetInsertMode(insertMode)
+
+	ctx := tenant.WithSystem(context.Background())
+	reingested := 0
+	for i, path := range files {
+		name := filepath.Base(path)
+
+		clicks, err := store.Read(path)
+		if err != nil {
+			appLogger.WithError(err).WithField("file", name).Error("Failed to read dead-letter file")
+			return 1
+		}
+
+		// Батч мог попасть в dead-letter после коммита, исход которого сервис не узнал
+		unsaved, err := clickRepo.FilterUnsaved(ctx, clicks)
+		if err == nil && len(unsaved) > 0 {
+			err = clickRepo.CreateBatchWithCounters(ctx, unsaved, click.CountByMinute(unsaved))
+		}
+		if err != nil {
+			appLogger.WithError(err).WithFields(logrus.Fields{
+				"file":            name,
+				"files_processed": i,
+				"clicks":          reingested,
+			}).Error("Failed to reingest dead-letter file")
+			return 1
+		}
+
+		if err := store.Remove(path); err != nil {
+			// Клики уже сохранены; повторный запуск пропустит их по EventID
+			appLogger.WithError(err).WithField("file", name).Error("Reingested dead-letter file could not be removed, delete it manually")
+			return 1
+		}
+
+		reingested += len(unsaved)
+		appLogger.WithFields(logrus.Fields{
+			"file":   name,
+			"clicks": len(clicks),
+		}).Info("Dead-letter file reingested")
+	}
+
+	appLogger.WithFields(logrus.Fields{
+		"files":  len(files),
+		"clicks": reingested,
+	}).Info("Dead-letter reingest completed")
+
+	return 0
+}
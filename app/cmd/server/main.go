@@ -11,17 +11,28 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 
 	"github.com/clickcounter/app/internal/application/usecase"
+	"github.com/clickcounter/app/internal/application/worker"
+	"github.com/clickcounter/app/internal/domain/apikey"
 	"github.com/clickcounter/app/internal/domain/banner"
 	"github.com/clickcounter/app/internal/domain/click"
 	"github.com/clickcounter/app/internal/domain/stats"
+	"github.com/clickcounter/app/internal/domain/tenant"
+	"github.com/clickcounter/app/internal/infrastructure/botfilter"
 	"github.com/clickcounter/app/internal/infrastructure/cache"
 	"github.com/clickcounter/app/internal/infrastructure/config"
 	"github.com/clickcounter/app/internal/infrastructure/database/postgres"
+	"github.com/clickcounter/app/internal/infrastructure/deadletter"
+	"github.com/clickcounter/app/internal/infrastructure/livestats"
+	"github.com/clickcounter/app/internal/infrastructure/metrics"
+	"github.com/clickcounter/app/internal/infrastructure/wal"
 	"github.com/clickcounter/app/internal/interfaces/http/handlers"
+	"github.com/clickcounter/app/internal/interfaces/http/middleware"
 	"github.com/clickcounter/app/internal/interfaces/http/router"
+	"github.com/clickcounter/app/migrations"
 	"github.com/clickcounter/app/pkg/logger"
 )
 
@@ -41,9 +52,19 @@ import (
 // @BasePath /
 // @schemes http https
 
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+
 func main() {
 	// Инициализация логгера
 	appLogger := logger.NewLogger()
+
+	// Служебные подкоманды (например, deadletter reingest) выполняются без запуска сервера
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:], appLogger))
+	}
+
 	appLogger.Info("Starting Click Counter service...")
 
 	// Загрузка конфигурации
@@ -64,19 +85,7 @@ func main() {
 	}).Info("Configuration loaded successfully")
 
 	// Инициализация базы данных
-	dbConfig := &postgres.Config{
-		Host:            cfg.Database.Host,
-		Port:            cfg.Database.Port,
-		User:            cfg.Database.User,
-		Password:        cfg.Database.Password,
-		DBName:          cfg.Database.Database,
-		SSLMode:         cfg.Database.SSLMode,
-		MaxOpenConns:    cfg.Database.MaxOpenConns,
-		MaxIdleConns:    cfg.Database.MaxIdleConns,
-		ConnMaxLifetime: time.Duration(cfg.Database.ConnMaxLifetime) * time.Second,
-	}
-
-	dbConn, err := postgres.NewDB(dbConfig, appLogger)
+	dbConn, err := postgres.NewDB(newDBConfig(cfg), appLogger)
 	if err != nil {
 		appLogger.WithError(err).Fatal("Failed to connect to database")
 	}
@@ -84,6 +93,20 @@ func main() {
 
 	appLogger.Info("Database connection established")
 
+	// Миграции схемы встроены в бинарный файл
+	migrator, err := postgres.NewMigrator(dbConn, migrations.FS, appLogger)
+	if err != nil {
+		appLogger.WithError(err).Fatal("Failed to load migrations")
+	}
+	if cfg.Database.AutoMigrate {
+		// Без таймаута: миграции больших таблиц могут идти долго
+		if _, err := migrator.Up(context.Background()); err != nil {
+			appLogger.WithError(err).Fatal("Failed to migrate database schema")
+		}
+	} else {
+		checkSchemaVersion(migrator, appLogger)
+	}
+
 	// Инициализация кэшей через фабрику
 	cacheFactory := cache.NewCacheFactory(cfg, appLogger)
 
@@ -97,25 +120,218 @@ func main() {
 		appLogger.WithError(err).Fatal("Failed to create stats cache")
 	}
 
+	var apiKeyCache apikey.CacheRepository
+	if cfg.Auth.Enabled {
+		apiKeyCache = cacheFactory.CreateAPIKeyCache()
+	}
+
 	appLogger.WithField("cache_type", cacheFactory.GetCacheType()).Info("Cache initialized successfully")
 
 	// Инициализация репозиториев
 	bannerRepo := postgres.NewBannerRepository(dbConn, appLogger)
 	clickRepo := postgres.NewClickRepository(dbConn, appLogger)
+	insertMode, err := postgres.ParseInsertMode(cfg.ClickFlusher.InsertMode)
+	if err != nil {
+		appLogger.WithError(err).Fatal("Invalid click flusher configuration")
+	}
+	clickRepo.SetInsertMode(insertMode)
 	statsRepo := postgres.NewStatsRepository(dbConn, appLogger)
-	statsAggRepo := postgres.NewStatsAggregationRepository(dbConn, appLogger)
 
 	// Инициализация доменных сервисов с кэшами
 	bannerService := banner.NewService(bannerRepo, bannerCache)
+	apiKeyService := apikey.NewService(postgres.NewAPIKeyRepository(dbConn, appLogger), apiKeyCache, cfg.Auth.APIKeys)
+	tenantService := tenant.NewService(postgres.NewTenantRepository(dbConn, appLogger))
+
+	// Журнал упреждающей записи для буфера кликов (опционально)
+	var clickJournal click.Journal
+	if cfg.ClickJournal.Enabled {
+		syncPolicy, err := wal.ParseSyncPolicy(cfg.ClickJournal.SyncPolicy)
+		if err != nil {
+			appLogger.WithError(err).Fatal("Invalid click journal configuration")
+		}
 
-	// Создаем сервис кликов с параметрами из конфигурации
-	clickService := click.NewService(
-		clickRepo,
-		cfg.ClickFlusher.BatchSize,
-		time.Duration(cfg.ClickFlusher.Interval)*time.Second,
+		segmentLog, err := wal.NewSegmentLog(
+			cfg.ClickJournal.Dir,
+			syncPolicy,
+			time.Duration(cfg.ClickJournal.SyncInterval)*time.Millisecond,
+			appLogger,
+		)
+		if err != nil {
+			appLogger.WithError(err).Fatal("Failed to open click journal")
+		}
+		defer segmentLog.Close()
+
+		clickJournal = segmentLog
+	}
+
+	// Хранилище батчей, не сохраненных после всех повторов (опционально)
+	var deadLetterQueue click.DeadLetterQueue
+	if cfg.ClickFlusher.DeadLetterDir != "" {
+		deadLetterStore, err := deadletter.NewFileStore(cfg.ClickFlusher.DeadLetterDir, appLogger)
+		if err != nil {
+			appLogger.WithError(err).Fatal("Failed to open click dead-letter directory")
+		}
+		deadLetterQueue = deadLetterStore
+	}
+
+	overflowPolicy, err := click.ParseOverflowPolicy(cfg.ClickFlusher.OverflowPolicy)
+	if err != nil {
+		appLogger.WithError(err).Fatal("Invalid click flusher configuration")
+	}
+
+	dedupMode, err := click.ParseDedupMode(cfg.ClickDedup.Mode)
+	if err != nil {
+		appLogger.WithError(err).Fatal("Invalid click dedup configuration")
+	}
+
+	// Хранилище отпечатков для дедупликации повторных кликов
+	var dedupCache click.DedupCache
+	if dedupMode != click.DedupOff {
+		dedupCache = cacheFactory.CreateClickDedupCache()
+	}
+
+	// Фильтр ботов и краулеров
+	var (
+		botDetector click.BotDetector
+		botAction   click.BotAction
+	)
+	if cfg.BotFilter.Enabled {
+		botAction, err = click.ParseBotAction(cfg.BotFilter.Action)
+		if err != nil {
+			appLogger.WithError(err).Fatal("Invalid bot filter configuration")
+		}
+
+		botFilter, err := botfilter.NewFilter(
+			cfg.BotFilter.RulesFile,
+			time.Duration(cfg.BotFilter.ReloadInterval)*time.Second,
+			appLogger,
+		)
+		if err != nil {
+			appLogger.WithError(err).Fatal("Failed to load bot filter rules")
+		}
+		defer botFilter.Close()
+		botDetector = botFilter
+	}
+
+	// Реестр метрик Prometheus (опционально)
+	var (
+		metricsRegistry *prometheus.Registry
+		flushObserver   func(clicks int, duration time.Duration, err error)
 	)
+	if cfg.Metrics.Enabled {
+		metricsRegistry = metrics.NewRegistry()
+		flushObserver = metrics.NewClickFlushMetrics(metricsRegistry).Observe
+	}
 
-	statsService := stats.NewService(statsRepo, statsAggRepo, statsCache)
+	// Секции таблицы кликов создаются заранее; клики вне секций попадают в clicks_default
+	clickPartitions, err := postgres.NewPartitionManager(
+		dbConn,
+		postgres.PartitionInterval(cfg.ClickPartitions.Interval),
+		cfg.ClickPartitions.Premake,
+		cfg.ClickPartitions.RetentionMode == "detach",
+		appLogger,
+	)
+	if err != nil {
+		appLogger.WithError(err).Fatal("Invalid click partitions configuration")
+	}
+	partitionsCtx, partitionsCancel := context.WithTimeout(tenant.WithSystem(context.Background()), 30*time.Second)
+	if err := clickPartitions.Start(partitionsCtx, time.Duration(cfg.ClickPartitions.CheckInterval)*time.Second); err != nil {
+		// Не фатально: созданные ранее секции и секция по умолчанию продолжают принимать клики
+		appLogger.WithError(err).Error("Failed to create click partitions")
+	}
+	partitionsCancel()
+	defer clickPartitions.Close()
+
+	// Поток live-статистики получает клики при регистрации, до сброса в БД
+	var (
+		liveStatsHub   *livestats.Hub
+		clickPublisher click.Publisher
+	)
+	if cfg.LiveStats.Enabled {
+		liveStatsHub = livestats.NewHub(livestats.Options{
+			BufferSize:     cfg.LiveStats.BufferSize,
+			TickInterval:   time.Duration(cfg.LiveStats.TickInterval) * time.Millisecond,
+			MaxSubscribers: cfg.LiveStats.MaxSubscribers,
+		}, appLogger)
+		defer liveStatsHub.Close()
+		clickPublisher = liveStatsHub
+	}
+
+	// Создаем сервис кликов с параметрами из конфигурации
+	clickService := click.NewService(clickRepo, clickJournal, click.Options{
+		BatchSize:      cfg.ClickFlusher.BatchSize,
+		FlushInterval:  time.Duration(cfg.ClickFlusher.Interval) * time.Second,
+		QueueSize:      cfg.ClickFlusher.QueueSize,
+		Workers:        cfg.ClickFlusher.Workers,
+		OverflowPolicy: overflowPolicy,
+		FlushTimeout:   time.Duration(cfg.ClickFlusher.FlushTimeout) * time.Second,
+		MaxRetries:     cfg.ClickFlusher.MaxRetries,
+		RetryBaseDelay: time.Duration(cfg.ClickFlusher.RetryBaseDelay) * time.Millisecond,
+		RetryMaxDelay:  time.Duration(cfg.ClickFlusher.RetryMaxDelay) * time.Millisecond,
+		DeadLetter:     deadLetterQueue,
+		DedupMode:      dedupMode,
+		Dedup:          dedupCache,
+		BotDetector:    botDetector,
+		BotAction:      botAction,
+		ErrorHandler: func(err error, clicks []*click.Click) {
+			appLogger.WithError(err).WithField("clicks", len(clicks)).Error("Failed to flush click batch")
+		},
+		FlushObserver: flushObserver,
+		Publisher:     clickPublisher,
+	})
+	defer clickService.Close()
+
+	// Восстанавливаем клики, не сброшенные предыдущим запуском, до приема трафика
+	replayed, err := clickService.ReplayJournal(tenant.WithSystem(context.Background()))
+	if err != nil {
+		appLogger.WithError(err).Fatal("Failed to replay click journal")
+	}
+	if replayed > 0 {
+		appLogger.WithField("clicks", replayed).Info("Click journal replayed")
+	}
+
+	statsService := stats.NewService(statsRepo, statsCache)
+
+	// Сверка статистики с сырыми кликами (опционально): нужна, только если клики
+	// попадают в БД в обход сервиса, иначе stats обновляется вместе с батчем кликов
+	var statsAggregator *worker.StatsAggregator
+	if cfg.StatsAggregator.Enabled {
+		statsAggregator = worker.NewStatsAggregator(
+			postgres.NewStatsAggregationRepository(dbConn, appLogger),
+			time.Duration(cfg.StatsAggregator.Interval)*time.Second,
+			cfg.StatsAggregator.BatchSize,
+			appLogger,
+		)
+	}
+
+	// Свертка минутной статистики в часовую и дневную для запросов с крупными бакетами
+	var statsRollup *worker.StatsRollup
+	if cfg.StatsRollup.Enabled {
+		statsRollup = worker.NewStatsRollup(
+			postgres.NewStatsRollupRepository(dbConn, appLogger),
+			time.Duration(cfg.StatsRollup.Interval)*time.Second,
+			time.Duration(cfg.StatsRollup.SettleDelay)*time.Second,
+			newRollupSourceRetention(cfg),
+			cfg.StatsRollup.BatchSize,
+			appLogger,
+		)
+	}
+
+	// Удаление данных старше сроков хранения
+	var retention *worker.Retention
+	if cfg.Retention.Enabled {
+		retention = worker.NewRetention(
+			worker.RetentionDependencies{
+				Clicks:      clickPartitions,
+				Stats:       postgres.NewStatsRepository(dbConn, appLogger),
+				Rollups:     postgres.NewStatsRollupRepository(dbConn, appLogger),
+				Aggregation: postgres.NewStatsAggregationRepository(dbConn, appLogger),
+			},
+			newRetentionPolicy(cfg),
+			time.Duration(cfg.Retention.Interval)*time.Second,
+			appLogger,
+		)
+	}
 
 	// Инициализация use cases
 	clickUseCase := usecase.NewClickUseCase(
@@ -130,13 +346,76 @@ func main() {
 		appLogger,
 	)
 
+	bannerUseCase := usecase.NewBannerUseCase(bannerService, appLogger)
+
+	clickSearchUseCase := usecase.NewClickSearchUseCase(clickRepo, bannerService, appLogger)
+
 	// Инициализация handlers
 	clickHandler := handlers.NewClickHandler(clickUseCase, appLogger)
 	statsHandler := handlers.NewStatsHandler(statsUseCase, appLogger)
+	bannerHandler := handlers.NewBannerHandler(bannerUseCase, appLogger)
 	healthHandler := handlers.NewHealthHandler(dbConn, appLogger)
 
 	// Инициализация роутера
-	appRouter := router.NewRouter(clickHandler, statsHandler, healthHandler, appLogger)
+	appRouter := router.NewRouter(clickHandler, statsHandler, bannerHandler, healthHandler, appLogger)
+
+	// Метрики Prometheus
+	if metricsRegistry != nil {
+		metricsRegistry.MustRegister(
+			metrics.NewClickPipelineCollector(clickService.Stats),
+			metrics.NewMemoryCacheCollector(cacheFactory.MemoryCaches()),
+			metrics.NewPoolCollector(dbConn.GetStats),
+			metrics.NewClickPartitionsCollector(clickPartitions.DefaultPartitionRows),
+		)
+		if retention != nil {
+			metricsRegistry.MustRegister(metrics.NewRetentionCollector(retention.Totals))
+		}
+		if liveStatsHub != nil {
+			metricsRegistry.MustRegister(metrics.NewLiveStatsCollector(liveStatsHub.Stats))
+		}
+		appRouter.EnableMetrics(
+			middleware.NewHTTPMetrics(metricsRegistry),
+			cfg.Metrics.Path,
+			metrics.Handler(metricsRegistry),
+		)
+	}
+
+	// Ограничение частоты запросов
+	if cfg.RateLimiting.Enabled {
+		rateLimiter, err := middleware.NewRateLimiter(newRateLimitRules(cfg.RateLimiting.Rules), middleware.RateLimitOptions{
+			MaxKeys: cfg.RateLimiting.MaxKeys,
+			IdleTTL: time.Duration(cfg.RateLimiting.IdleTTL) * time.Second,
+		})
+		if err != nil {
+			appLogger.WithError(err).Fatal("Failed to create rate limiter")
+		}
+		appRouter.EnableRateLimit(rateLimiter)
+	}
+
+	if liveStatsHub != nil {
+		appRouter.EnableStatsStream(handlers.NewStatsStreamHandler(
+			liveStatsHub,
+			bannerUseCase,
+			time.Duration(cfg.LiveStats.Heartbeat)*time.Second,
+			appLogger,
+		))
+	}
+
+	// Доступ к /api/v1 по API ключам; регистрация кликов через /counter остается публичной.
+	// Поиск кликов (IP и User-Agent посетителей) и управление ключами без аутентификации не подключаются
+	if cfg.Auth.Enabled {
+		appRouter.EnableAuth(
+			middleware.Authenticate(apiKeyService.Authenticate, appLogger),
+			handlers.NewAPIKeyHandler(usecase.NewAPIKeyUseCase(apiKeyService, bannerService, appLogger), appLogger),
+		)
+		appRouter.EnableClickSearch(handlers.NewClickSearchHandler(clickSearchUseCase, appLogger))
+	} else {
+		appLogger.Warn("API key authentication is disabled (auth.enabled): /api/v1 is available without a key for the default tenant only, click search and API key management are turned off")
+	}
+
+	// Данные и кэш /api/v1 ограничиваются арендатором API ключа или заголовка X-Tenant-ID
+	appRouter.EnableTenants(middleware.ResolveTenant(tenantService.Get, appLogger))
+
 	appRouter.Setup()
 
 	// Оптимизация Gin для продакшена
@@ -167,10 +446,26 @@ func main() {
 	// Включаем keep-alive соединения
 	server.SetKeepAlivesEnabled(true)
 
+	// Shutdown ждет завершения активных запросов: потоки live-статистики закрываются сразу
+	if liveStatsHub != nil {
+		server.RegisterOnShutdown(liveStatsHub.Close)
+	}
+
 	// Канал для graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
+	// Запуск фоновой сверки статистики
+	if statsAggregator != nil {
+		statsAggregator.Start()
+	}
+	if statsRollup != nil {
+		statsRollup.Start()
+	}
+	if retention != nil {
+		retention.Start()
+	}
+
 	// Запуск сервера в горутине
 	go func() {
 		appLogger.WithField("address", server.Addr).Info("Starting HTTP server")
@@ -187,15 +482,33 @@ func main() {
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
 
-	// Принудительно сбрасываем накопленные клики перед остановкой
+	// Сначала перестаем принимать запросы, чтобы после сброса не появилось новых кликов
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		appLogger.WithError(err).Error("Server forced to shutdown")
+	}
+
+	// Принудительно сбрасываем накопленные клики и останавливаем конвейер
 	appLogger.Info("Flushing pending clicks...")
 	if err := clickUseCase.FlushPendingClicks(shutdownCtx); err != nil {
 		appLogger.WithError(err).Error("Failed to flush pending clicks during shutdown")
 	}
+	clickService.Close()
 
-	// Останавливаем HTTP сервер
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		appLogger.WithError(err).Error("Server forced to shutdown")
+	// Останавливаем агрегатор после сброса кликов, чтобы учесть их финальным проходом
+	if statsAggregator != nil {
+		if err := statsAggregator.Stop(shutdownCtx); err != nil {
+			appLogger.WithError(err).Error("Failed to stop stats aggregator")
+		}
+	}
+	if retention != nil {
+		if err := retention.Stop(shutdownCtx); err != nil {
+			appLogger.WithError(err).Error("Failed to stop retention")
+		}
+	}
+	if statsRollup != nil {
+		if err := statsRollup.Stop(shutdownCtx); err != nil {
+			appLogger.WithError(err).Error("Failed to stop stats rollup")
+		}
 	}
 
 	appLogger.Info("Server exited")
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/infrastructure/config"
+	"github.com/clickcounter/app/internal/infrastructure/database/postgres"
+	"github.com/clickcounter/app/migrations"
+)
+
+// migrateUsage описание аргументов подкоманды migrate
+const migrateUsage = "Usage: server migrate up|down [N]|status|to N"
+
+// runMigrateCommand обрабатывает подкоманды "migrate up", "migrate down [N]",
+// "migrate status" и "migrate to N"
+func runMigrateCommand(args []string, appLogger *logrus.Logger) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	// Аргумент количества шагов или целевой версии
+	var number int64
+	switch {
+	case args[0] == "up" && len(args) == 1, args[0] == "status" && len(args) == 1:
+	case args[0] == "down" && len(args) <= 2:
+		number = 1
+		if len(args) == 2 {
+			steps, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil || steps <= 0 {
+				fmt.Fprintln(os.Stderr, "down: N must be a positive number of migrations")
+				return 2
+			}
+			number = steps
+		}
+	case args[0] == "to" && len(args) == 2:
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			fmt.Fprintln(os.Stderr, "to: N must be a migration version (0 reverts all migrations)")
+			return 2
+		}
+		number = version
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		appLogger.WithError(err).Error("Failed to load configuration")
+		return 1
+	}
+
+	dbConn, err := postgres.NewDB(newDBConfig(cfg), appLogger)
+	if err != nil {
+		appLogger.WithError(err).Error("Failed to connect to database")
+		return 1
+	}
+	defer dbConn.Close()
+
+	migrator, err := postgres.NewMigrator(dbConn, migrations.FS, appLogger)
+	if err != nil {
+		appLogger.WithError(err).Error("Failed to load migrations")
+		return 1
+	}
+
+	ctx := context.Background()
+	if args[0] == "status" {
+		return printMigrationStatus(ctx, migrator, appLogger)
+	}
+
+	var count int
+	switch args[0] {
+	case "up":
+		count, err = migrator.Up(ctx)
+	case "down":
+		count, err = migrator.Down(ctx, int(number))
+	case "to":
+		count, err = migrator.To(ctx, number)
+	}
+	if err != nil {
+		appLogger.WithError(err).WithField("migrations", count).Error("Migration failed")
+		return 1
+	}
+
+	current, err := migrator.Version(ctx)
+	if err != nil {
+		appLogger.WithError(err).Error("Failed to read schema version")
+		return 1
+	}
+
+	appLogger.WithFields(logrus.Fields{
+		"migrations": count,
+		"version":    current.Version,
+	}).Info("Migration completed")
+
+	return 0
+}
+
+// printMigrationStatus выводит текущую версию схемы и список миграций
+func printMigrationStatus(ctx context.Context, migrator *postgres.Migrator, appLogger *logrus.Logger) int {
+	current, statuses, err := migrator.Status(ctx)
+	if err != nil {
+		appLogger.WithError(err).Error("Failed to read migration status")
+		return 1
+	}
+
+	for _, status := range statuses {
+		state := "pending"
+		if status.Applied {
+			state = "applied"
+		}
+		fmt.Printf("%03d\t%s\t%s\n", status.Version, state, status.Name)
+	}
+
+	dirty := ""
+	if current.Dirty {
+		dirty = " (dirty)"
+	}
+	fmt.Printf("Version: %d%s, latest: %d\n", current.Version, dirty, migrator.Latest())
+	return 0
+}
+
+// checkSchemaVersion предупреждает, если схема БД не соответствует встроенным миграциям
+func checkSchemaVersion(migrator *postgres.Migrator, appLogger *logrus.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	current, err := migrator.Version(ctx)
+	if err != nil {
+		appLogger.WithError(err).Warn("Failed to check database schema version")
+		return
+	}
+
+	fields := logrus.Fields{
+		"version": current.Version,
+		"latest":  migrator.Latest(),
+	}
+	switch {
+	case current.Dirty:
+		appLogger.WithFields(fields).Error("Database schema is dirty: a migration was interrupted")
+	case current.Version < migrator.Latest():
+		appLogger.WithFields(fields).Warn("Database schema is behind, run \"server migrate up\" or enable database.auto_migrate")
+	case current.Version > migrator.Latest():
+		appLogger.WithFields(fields).Warn("Database schema is newer than known migrations")
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/domain/tenant"
+	"github.com/clickcounter/app/internal/infrastructure/config"
+	"github.com/clickcounter/app/internal/infrastructure/database/postgres"
+)
+
+// tenantUsage описание аргументов подкоманды tenant
+const tenantUsage = "Usage: server tenant list|create NAME"
+
+// runTenantCommand обрабатывает подкоманды "tenant list" и "tenant create NAME"
+func runTenantCommand(args []string, appLogger *logrus.Logger) int {
+	switch {
+	case len(args) == 1 && args[0] == "list":
+	case len(args) >= 2 && args[0] == "create":
+	default:
+		fmt.Fprintln(os.Stderr, tenantUsage)
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		appLogger.WithError(err).Error("Failed to load configuration")
+		return 1
+	}
+
+	dbConn, err := postgres.NewDB(newDBConfig(cfg), appLogger)
+	if err != nil {
+		appLogger.WithError(err).Error("Failed to connect to database")
+		return 1
+	}
+	defer dbConn.Close()
+
+	tenantService := tenant.NewService(postgres.NewTenantRepository(dbConn, appLogger))
+	ctx := context.Background()
+
+	if args[0] == "list" {
+		tenants, err := tenantService.List(ctx)
+		if err != nil {
+			appLogger.WithError(err).Error("Failed to list tenants")
+			return 1
+		}
+		for _, t := range tenants {
+			fmt.Printf("%d\t%s\t%s\n", t.ID, t.Name, t.CreatedAt.UTC().Format(time.RFC3339))
+		}
+		return 0
+	}
+
+	// Название из нескольких аргументов допускается без кавычек
+	created, err := tenantService.Create(ctx, strings.Join(args[1:], " "))
+	if err != nil {
+		if errors.Is(err, tenant.ErrEmptyName) || errors.Is(err, tenant.ErrNameTooLong) || errors.Is(err, tenant.ErrDuplicateName) {
+			fmt.Fprintf(os.Stderr, "create: %v\n", err)
+			return 2
+		}
+		appLogger.WithError(err).Error("Failed to create tenant")
+		return 1
+	}
+
+	appLogger.WithFields(logrus.Fields{
+		"tenant_id": created.ID,
+		"name":      created.Name,
+	}).Info("Tenant created")
+	fmt.Printf("%d\t%s\n", created.ID, created.Name)
+
+	return 0
+}
@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/domain/apikey"
+	"github.com/clickcounter/app/internal/domain/banner"
+)
+
+// APIKeyUseCase представляет use case для управления API ключами
+type APIKeyUseCase struct {
+	apiKeyService *apikey.Service
+	bannerService *banner.Service
+	logger        *logrus.Logger
+}
+
+// NewAPIKeyUseCase создает новый экземпляр APIKeyUseCase
+func NewAPIKeyUseCase(apiKeyService *apikey.Service, bannerService *banner.Service, logger *logrus.Logger) *APIKeyUseCase {
+	if logger == nil {
+		logger = logrus.New()
+	}
+
+	return &APIKeyUseCase{
+		apiKeyService: apiKeyService,
+		bannerService: bannerService,
+		logger:        logger,
+	}
+}
+
+// APIKeyParams параметры создания API ключа
+type APIKeyParams struct {
+	Name      string         `json:"name"`
+	Scopes    []apikey.Scope `json:"scopes"`
+	BannerIDs []int64        `json:"banner_ids,omitempty"` // пусто - все баннеры
+	ExpiresAt *time.Time     `json:"expires_at,omitempty"`
+}
+
+// CreateAPIKey создает API ключ и возвращает его вместе с самим ключом
+func (uc *APIKeyUseCase) CreateAPIKey(ctx context.Context, params *APIKeyParams) (*apikey.Key, string, error) {
+	if params == nil {
+		return nil, "", fmt.Errorf("request is required")
+	}
+
+	// Ключ к несуществующему баннеру скорее всего опечатка в списке
+	for _, id := range params.BannerIDs {
+		if id <= 0 {
+			return nil, "", apikey.ErrInvalidBannerID
+		}
+		if _, err := uc.bannerService.Get(ctx, id); err != nil {
+			if errors.Is(err, banner.ErrBannerNotFound) {
+				return nil, "", fmt.Errorf("%w: %d", banner.ErrBannerNotFound, id)
+			}
+			return nil, "", fmt.Errorf("failed to check banner existence: %w", err)
+		}
+	}
+
+	key, token, err := uc.apiKeyService.Create(ctx, params.Name, params.Scopes, params.BannerIDs, params.ExpiresAt)
+	if err != nil {
+		uc.logger.WithError(err).WithField("name", params.Name).Error("Failed to create api key")
+		return nil, "", fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	uc.logger.WithFields(logrus.Fields{
+		"api_key_id": key.ID,
+		"tenant_id":  key.TenantID,
+		"name":       key.Name,
+		"prefix":     key.Prefix,
+		"scopes":     key.Scopes,
+		"banners":    len(key.BannerIDs),
+	}).Info("API key created successfully")
+
+	return key, token, nil
+}
+
+// ListAPIKeys возвращает все API ключи, включая отозванные
+func (uc *APIKeyUseCase) ListAPIKeys(ctx context.Context) ([]*apikey.Key, error) {
+	keys, err := uc.apiKeyService.List(ctx)
+	if err != nil {
+		uc.logger.WithError(err).Error("Failed to list api keys")
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+
+	if keys == nil {
+		keys = []*apikey.Key{}
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает API ключ
+func (uc *APIKeyUseCase) RevokeAPIKey(ctx context.Context, id int64) (*apikey.Key, error) {
+	key, err := uc.apiKeyService.Revoke(ctx, id)
+	if err != nil {
+		uc.logger.WithError(err).WithField("api_key_id", id).Error("Failed to revoke api key")
+		return nil, fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	uc.logger.WithFields(logrus.Fields{
+		"api_key_id": key.ID,
+		"name":       key.Name,
+		"prefix":     key.Prefix,
+	}).Info("API key revoked successfully")
+
+	return key, nil
+}
@@ -0,0 +1,158 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/clickcounter/app/internal/domain/banner"
+	"github.com/sirupsen/logrus"
+)
+
+// MaxBannersPageSize максимальный размер страницы списка баннеров
+const MaxBannersPageSize = 500
+
+// BannerUseCase представляет use case для управления баннерами
+type BannerUseCase struct {
+	bannerService *banner.Service
+	logger        *logrus.Logger
+}
+
+// NewBannerUseCase создает новый экземпляр BannerUseCase
+func NewBannerUseCase(bannerService *banner.Service, logger *logrus.Logger) *BannerUseCase {
+	if logger == nil {
+		logger = logrus.New()
+	}
+
+	return &BannerUseCase{
+		bannerService: bannerService,
+		logger:        logger,
+	}
+}
+
+// ListBannersRequest представляет запрос на получение списка баннеров
+type ListBannersRequest struct {
+	Active *bool `json:"active,omitempty"`
+	Limit  int   `json:"limit"`
+	Offset int   `json:"offset"`
+}
+
+// ListBannersResponse представляет страницу списка баннеров
+type ListBannersResponse struct {
+	Banners []*banner.Banner `json:"banners"`
+	Total   int64            `json:"total"`
+	Limit   int              `json:"limit"`
+	Offset  int              `json:"offset"`
+}
+
+// ListBanners возвращает страницу списка баннеров
+func (uc *BannerUseCase) ListBanners(ctx context.Context, req *ListBannersRequest) (*ListBannersResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("request is required")
+	}
+
+	if req.Limit <= 0 || req.Limit > MaxBannersPageSize {
+		return nil, fmt.Errorf("limit must be between 1 and %d", MaxBannersPageSize)
+	}
+
+	if req.Offset < 0 {
+		return nil, fmt.Errorf("offset cannot be negative")
+	}
+
+	banners, total, err := uc.bannerService.List(ctx, banner.ListFilter{
+		Active: req.Active,
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		uc.logger.WithError(err).Error("Failed to list banners")
+		return nil, fmt.Errorf("failed to list banners: %w", err)
+	}
+
+	if banners == nil {
+		banners = []*banner.Banner{}
+	}
+
+	return &ListBannersResponse{
+		Banners: banners,
+		Total:   total,
+		Limit:   req.Limit,
+		Offset:  req.Offset,
+	}, nil
+}
+
+// GetBanner возвращает баннер по ID
+func (uc *BannerUseCase) GetBanner(ctx context.Context, id int64) (*banner.Banner, error) {
+	b, err := uc.bannerService.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get banner: %w", err)
+	}
+
+	return b, nil
+}
+
+// BannerParams параметры создания или изменения баннера
+type BannerParams struct {
+	Name     string     `json:"name"`
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+}
+
+// CreateBanner создает новый активный баннер
+func (uc *BannerUseCase) CreateBanner(ctx context.Context, params *BannerParams) (*banner.Banner, error) {
+	if params == nil {
+		return nil, fmt.Errorf("request is required")
+	}
+
+	b, err := uc.bannerService.Create(ctx, params.Name, params.StartsAt, params.EndsAt)
+	if err != nil {
+		uc.logger.WithError(err).WithField("name", params.Name).Error("Failed to create banner")
+		return nil, fmt.Errorf("failed to create banner: %w", err)
+	}
+
+	uc.logger.WithFields(logrus.Fields{
+		"banner_id": b.ID,
+		"name":      b.Name,
+	}).Info("Banner created successfully")
+
+	return b, nil
+}
+
+// UpdateBanner изменяет название и окно показа баннера
+func (uc *BannerUseCase) UpdateBanner(ctx context.Context, id int64, params *BannerParams) (*banner.Banner, error) {
+	if params == nil {
+		return nil, fmt.Errorf("request is required")
+	}
+
+	b, err := uc.bannerService.Update(ctx, id, params.Name, params.StartsAt, params.EndsAt)
+	if err != nil {
+		uc.logger.WithError(err).WithField("banner_id", id).Error("Failed to update banner")
+		return nil, fmt.Errorf("failed to update banner: %w", err)
+	}
+
+	uc.logger.WithFields(logrus.Fields{
+		"banner_id": b.ID,
+		"name":      b.Name,
+	}).Info("Banner updated successfully")
+
+	return b, nil
+}
+
+// SetBannerActive активирует или деактивирует баннер
+func (uc *BannerUseCase) SetBannerActive(ctx context.Context, id int64, active bool) (*banner.Banner, error) {
+	b, err := uc.bannerService.SetActive(ctx, id, active)
+	if err != nil {
+		uc.logger.WithError(err).WithFields(logrus.Fields{
+			"banner_id": id,
+			"active":    active,
+		}).Error("Failed to change banner activity")
+		return nil, fmt.Errorf("failed to change banner activity: %w", err)
+	}
+
+	uc.logger.WithFields(logrus.Fields{
+		"banner_id": b.ID,
+		"active":    b.IsActive,
+	}).Info("Banner activity changed successfully")
+
+	return b, nil
+}
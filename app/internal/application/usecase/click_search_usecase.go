@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/domain/banner"
+	"github.com/clickcounter/app/internal/domain/click"
+)
+
+// ClickSearchUseCase представляет use case поиска сохраненных кликов (разбор жалоб на фрод)
+type ClickSearchUseCase struct {
+	searchRepo    click.SearchRepository
+	bannerService *banner.Service
+	logger        *logrus.Logger
+}
+
+// NewClickSearchUseCase создает новый экземпляр ClickSearchUseCase
+func NewClickSearchUseCase(
+	searchRepo click.SearchRepository,
+	bannerService *banner.Service,
+	logger *logrus.Logger,
+) *ClickSearchUseCase {
+	if logger == nil {
+		logger = logrus.New()
+	}
+
+	return &ClickSearchUseCase{
+		searchRepo:    searchRepo,
+		bannerService: bannerService,
+		logger:        logger,
+	}
+}
+
+// SearchClicks возвращает страницу кликов баннера, подходящих под фильтр, и их общее количество
+func (uc *ClickSearchUseCase) SearchClicks(ctx context.Context, filter click.SearchFilter) (*click.SearchPage, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Клики доступны и для неактивных баннеров: жалобы приходят и по завершенным кампаниям
+	if _, err := uc.bannerService.Get(ctx, filter.BannerID); err != nil {
+		if errors.Is(err, banner.ErrBannerNotFound) {
+			return nil, fmt.Errorf("%w: %d", banner.ErrBannerNotFound, filter.BannerID)
+		}
+		uc.logger.WithError(err).WithField("banner_id", filter.BannerID).Error("Failed to check banner existence")
+		return nil, fmt.Errorf("failed to check banner existence: %w", err)
+	}
+
+	// Лишний клик показывает, есть ли следующая страница
+	clicks, err := uc.searchRepo.Search(ctx, filter, filter.Limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search clicks: %w", err)
+	}
+
+	total, err := uc.searchRepo.Count(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count clicks: %w", err)
+	}
+
+	page := &click.SearchPage{Clicks: clicks, Total: total}
+	if len(clicks) > filter.Limit {
+		page.Clicks = clicks[:filter.Limit]
+		last := page.Clicks[len(page.Clicks)-1]
+		page.Next = &click.SearchCursor{Timestamp: last.Timestamp, ID: last.ID}
+	}
+
+	uc.logger.WithFields(logrus.Fields{
+		"banner_id": filter.BannerID,
+		"from":      filter.From,
+		"to":        filter.To,
+		"clicks":    len(page.Clicks),
+		"total":     total,
+	}).Info("Clicks searched")
+
+	return page, nil
+}
@@ -2,11 +2,13 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/clickcounter/app/internal/domain/banner"
 	"github.com/clickcounter/app/internal/domain/click"
+	"github.com/clickcounter/app/internal/domain/tenant"
 	"github.com/sirupsen/logrus"
 )
 
@@ -44,6 +46,7 @@ type RegisterClickRequest struct {
 // RegisterClickResponse представляет ответ на регистрацию клика
 type RegisterClickResponse struct {
 	Success   bool      `json:"success"`
+	Counted   bool      `json:"counted"` // false - повторный клик, клик бота или клик, отброшенный при переполнении очереди
 	ClickID   int64     `json:"click_id,omitempty"`
 	BannerID  int64     `json:"banner_id"`
 	Timestamp time.Time `json:"timestamp"`
@@ -65,8 +68,9 @@ func (uc *ClickUseCase) RegisterClick(ctx context.Context, req *RegisterClickReq
 		}, fmt.Errorf("invalid banner ID: %d", req.BannerID)
 	}
 
-	// Проверяем существование баннера
-	exists, err := uc.bannerService.Exists(ctx, req.BannerID)
+	// Проверяем существование баннера. Регистрация клика публичная и не зависит от
+	// арендатора: ID баннеров уникальны среди всех арендаторов
+	exists, err := uc.bannerService.Exists(tenant.WithSystem(ctx), req.BannerID)
 	if err != nil {
 		uc.logger.WithError(err).WithField("banner_id", req.BannerID).Error("Failed to check banner existence")
 		return &RegisterClickResponse{
@@ -87,18 +91,53 @@ func (uc *ClickUseCase) RegisterClick(ctx context.Context, req *RegisterClickReq
 
 	// Регистрируем клик через сервис
 	clickEntity, err := uc.clickService.RegisterClick(ctx, req.BannerID, req.UserIP, req.UserAgent)
+	if errors.Is(err, click.ErrClickDropped) {
+		// Отброшенный клик учитывается только счетчиком dropped конвейера
+		uc.logger.WithField("banner_id", req.BannerID).Debug("Click dropped: queue is full")
+
+		return &RegisterClickResponse{
+			Success:  true,
+			BannerID: req.BannerID,
+			Message:  "Click queue is full, click was not counted",
+		}, nil
+	}
 	if err != nil {
 		uc.logger.WithError(err).WithFields(logrus.Fields{
 			"banner_id": req.BannerID,
 			"user_ip":   req.UserIP,
 		}).Error("Failed to register click")
+
+		message := "Failed to register click"
+		if errors.Is(err, click.ErrQueueFull) {
+			message = "Click queue is full"
+		}
 		return &RegisterClickResponse{
 			Success:  false,
 			BannerID: req.BannerID,
-			Message:  "Failed to register click",
+			Message:  message,
 		}, fmt.Errorf("failed to register click: %w", err)
 	}
 
+	// Клики ботов и повторные клики принимаются, но не учитываются в статистике
+	if clickEntity.Bot || clickEntity.Duplicate {
+		message := "Duplicate click was not counted"
+		if clickEntity.Bot {
+			message = "Bot click was not counted"
+		}
+
+		uc.logger.WithFields(logrus.Fields{
+			"banner_id": req.BannerID,
+			"user_ip":   req.UserIP,
+		}).Debug(message)
+
+		return &RegisterClickResponse{
+			Success:   true,
+			BannerID:  req.BannerID,
+			Timestamp: clickEntity.Timestamp,
+			Message:   message,
+		}, nil
+	}
+
 	uc.logger.WithFields(logrus.Fields{
 		"banner_id": req.BannerID,
 		"click_id":  clickEntity.ID,
@@ -107,6 +146,7 @@ func (uc *ClickUseCase) RegisterClick(ctx context.Context, req *RegisterClickReq
 
 	return &RegisterClickResponse{
 		Success:   true,
+		Counted:   true,
 		ClickID:   clickEntity.ID,
 		BannerID:  req.BannerID,
 		Timestamp: clickEntity.Timestamp,
@@ -2,9 +2,12 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
+	"github.com/clickcounter/app/internal/domain/apikey"
 	"github.com/clickcounter/app/internal/domain/banner"
 	"github.com/clickcounter/app/internal/domain/stats"
 	"github.com/sirupsen/logrus"
@@ -39,17 +42,25 @@ type GetStatsRequest struct {
 	BannerID int64     `json:"banner_id" validate:"required,min=1"`
 	From     time.Time `json:"from" validate:"required"`
 	To       time.Time `json:"to" validate:"required"`
+
+	IncludeBots bool `json:"include_bots"` // учитывать клики ботов
+	Uniques     bool `json:"uniques"`      // оценивать уникальных посетителей
+
+	Granularity stats.Granularity `json:"granularity"` // размер бакета (пустое значение - минута)
 }
 
 // GetStatsResponse представляет ответ со статистикой (согласно ТЗ)
 type GetStatsResponse struct {
-	Stats []*MinuteStatDTO `json:"stats"`
+	Granularity stats.Granularity `json:"granularity"`
+	Stats       []*BucketStatDTO  `json:"stats"`
+	Uniques     *int64            `json:"u,omitempty"` // уникальные посетители за период
 }
 
-// MinuteStatDTO представляет статистику за минуту (формат ТЗ)
-type MinuteStatDTO struct {
+// BucketStatDTO представляет статистику за бакет (формат ТЗ; ts - начало бакета)
+type BucketStatDTO struct {
 	Timestamp time.Time `json:"ts"`
 	Count     int64     `json:"v"`
+	Uniques   *int64    `json:"u,omitempty"`
 }
 
 // GetStats возвращает статистику кликов по баннеру за период
@@ -64,28 +75,32 @@ func (uc *StatsUseCase) GetStats(ctx context.Context, req *GetStatsRequest) (*Ge
 	}
 
 	if req.From.After(req.To) {
-		return nil, fmt.Errorf("from time cannot be after to time")
+		return nil, fmt.Errorf("%w: from time cannot be after to time", stats.ErrInvalidPeriod)
 	}
 
-	// Ограничиваем максимальный период запроса (30 дней)
-	maxPeriod := 30 * 24 * time.Hour
+	// Ограничиваем максимальный период запроса (зависит от размера бакета)
+	maxPeriod := stats.MaxPeriod(req.Granularity)
 	if req.To.Sub(req.From) > maxPeriod {
-		return nil, fmt.Errorf("period too large, maximum allowed: %v", maxPeriod)
+		return nil, fmt.Errorf("%w: maximum allowed %v", stats.ErrPeriodTooLarge, maxPeriod)
 	}
 
-	// Проверяем существование баннера
-	exists, err := uc.bannerService.Exists(ctx, req.BannerID)
-	if err != nil {
+	// Проверяем существование баннера. Статистика доступна и для неактивных баннеров,
+	// и вне окна показа: завершенная кампания остается в отчетах
+	if _, err := uc.bannerService.Get(ctx, req.BannerID); err != nil {
+		if errors.Is(err, banner.ErrBannerNotFound) {
+			return nil, fmt.Errorf("%w: %d", banner.ErrBannerNotFound, req.BannerID)
+		}
 		uc.logger.WithError(err).WithField("banner_id", req.BannerID).Error("Failed to check banner existence")
 		return nil, fmt.Errorf("failed to check banner existence: %w", err)
 	}
 
-	if !exists {
-		return nil, fmt.Errorf("banner not found: %d", req.BannerID)
-	}
-
-	// Получаем статистику через сервис
-	statsResponse, err := uc.statsService.GetStatsWithFallback(ctx, req.BannerID, req.From, req.To)
+	// Получаем статистику через сервис. Таблица stats обновляется в одной транзакции
+	// с записью кликов, поэтому агрегировать сырые клики при чтении не нужно
+	statsResponse, err := uc.statsService.GetStats(ctx, req.BannerID, req.From, req.To, stats.QueryOptions{
+		IncludeBots: req.IncludeBots,
+		Uniques:     req.Uniques,
+		Granularity: req.Granularity,
+	})
 	if err != nil {
 		uc.logger.WithError(err).WithFields(logrus.Fields{
 			"banner_id": req.BannerID,
@@ -96,20 +111,238 @@ func (uc *StatsUseCase) GetStats(ctx context.Context, req *GetStatsRequest) (*Ge
 	}
 
 	// Конвертируем в простой формат ТЗ
-	statsDTO := make([]*MinuteStatDTO, len(statsResponse.Stats))
+	statsDTO := make([]*BucketStatDTO, len(statsResponse.Stats))
 	for i, stat := range statsResponse.Stats {
-		statsDTO[i] = &MinuteStatDTO{
+		statsDTO[i] = &BucketStatDTO{
 			Timestamp: stat.Timestamp,
 			Count:     stat.Value,
+			Uniques:   stat.Uniques,
 		}
 	}
 
 	uc.logger.WithFields(logrus.Fields{
 		"banner_id":   req.BannerID,
+		"granularity": statsResponse.Granularity,
 		"stats_count": len(statsDTO),
 	}).Info("Stats retrieved successfully")
 
 	return &GetStatsResponse{
-		Stats: statsDTO,
+		Granularity: statsResponse.Granularity,
+		Stats:       statsDTO,
+		Uniques:     statsResponse.Uniques,
 	}, nil
 }
+
+// ExportStats выгружает статистику баннера за период потоком: бакеты передаются в fn по мере
+// чтения из БД. Допустимый период больше, чем у GetStats (см. stats.MaxExportPeriod).
+// Возвращает количество переданных бакетов
+func (uc *StatsUseCase) ExportStats(ctx context.Context, req *GetStatsRequest, fn func(*BucketStatDTO) error) (int64, error) {
+	if req == nil {
+		return 0, fmt.Errorf("request is required")
+	}
+
+	if req.BannerID <= 0 {
+		return 0, fmt.Errorf("%w: %d", stats.ErrInvalidBannerID, req.BannerID)
+	}
+
+	if req.From.After(req.To) {
+		return 0, stats.ErrInvalidPeriod
+	}
+
+	maxPeriod := stats.MaxExportPeriod(req.Granularity)
+	if req.To.Sub(req.From) > maxPeriod {
+		return 0, fmt.Errorf("%w: maximum allowed %v", stats.ErrPeriodTooLarge, maxPeriod)
+	}
+
+	if err := uc.checkBannersExist(ctx, []int64{req.BannerID}); err != nil {
+		return 0, err
+	}
+
+	var exported int64
+	err := uc.statsService.ExportStats(ctx, req.BannerID, req.From, req.To, stats.QueryOptions{
+		IncludeBots: req.IncludeBots,
+		Uniques:     req.Uniques,
+		Granularity: req.Granularity,
+	}, func(stat *stats.BucketStat) error {
+		exported++
+		return fn(&BucketStatDTO{
+			Timestamp: stat.Timestamp,
+			Count:     stat.Value,
+			Uniques:   stat.Uniques,
+		})
+	})
+	if err != nil {
+		return exported, fmt.Errorf("failed to export stats: %w", err)
+	}
+
+	uc.logger.WithFields(logrus.Fields{
+		"banner_id":   req.BannerID,
+		"granularity": stats.QueryOptions{Granularity: req.Granularity}.BucketGranularity(),
+		"stats_count": exported,
+	}).Info("Stats exported successfully")
+
+	return exported, nil
+}
+
+// GetMultiStatsRequest представляет запрос статистики набора баннеров
+type GetMultiStatsRequest struct {
+	BannerIDs []int64   `json:"banner_ids"`
+	AllActive bool      `json:"all_active"` // вместо BannerIDs - все активные баннеры
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+
+	IncludeBots bool              `json:"include_bots"`
+	Uniques     bool              `json:"uniques"`
+	Granularity stats.Granularity `json:"granularity"`
+}
+
+// GetMultiStatsResponse представляет статистику набора баннеров
+type GetMultiStatsResponse struct {
+	Granularity stats.Granularity `json:"granularity"`
+	Banners     []*BannerStatsDTO `json:"banners"`
+	Total       int64             `json:"total"`
+}
+
+// BannerStatsDTO представляет ряд статистики одного баннера с итогами за период
+type BannerStatsDTO struct {
+	BannerID int64            `json:"banner_id"`
+	Stats    []*BucketStatDTO `json:"stats"`
+	Total    int64            `json:"total"`
+	Uniques  *int64           `json:"u,omitempty"`
+}
+
+// GetMultiStats возвращает статистику набора баннеров за период одним запросом к БД
+func (uc *StatsUseCase) GetMultiStats(ctx context.Context, req *GetMultiStatsRequest) (*GetMultiStatsResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("request is required")
+	}
+
+	if req.From.After(req.To) {
+		return nil, fmt.Errorf("from time cannot be after to time")
+	}
+
+	bannerIDs := req.BannerIDs
+	if req.AllActive {
+		if len(bannerIDs) > 0 {
+			return nil, fmt.Errorf("banner_ids and all_active are mutually exclusive")
+		}
+
+		var err error
+		if bannerIDs, err = uc.activeBannerIDs(ctx); err != nil {
+			return nil, err
+		}
+		// Ключ, ограниченный списком баннеров, получает только доступные ему активные баннеры
+		if key, ok := apikey.FromContext(ctx); ok && key.IsRestricted() {
+			bannerIDs = slices.DeleteFunc(bannerIDs, func(id int64) bool {
+				return !key.AllowsBanner(id)
+			})
+		}
+		if len(bannerIDs) == 0 {
+			return &GetMultiStatsResponse{
+				Granularity: stats.QueryOptions{Granularity: req.Granularity}.BucketGranularity(),
+				Banners:     []*BannerStatsDTO{},
+			}, nil
+		}
+	} else {
+		normalized, err := stats.NormalizeBannerIDs(bannerIDs)
+		if err != nil {
+			return nil, err
+		}
+		if err := apikey.CheckBanners(ctx, normalized); err != nil {
+			return nil, err
+		}
+		if err := uc.checkBannersExist(ctx, normalized); err != nil {
+			return nil, err
+		}
+		bannerIDs = normalized
+	}
+
+	statsResponse, err := uc.statsService.GetStatsForBanners(ctx, bannerIDs, req.From, req.To, stats.QueryOptions{
+		IncludeBots: req.IncludeBots,
+		Uniques:     req.Uniques,
+		Granularity: req.Granularity,
+	})
+	if err != nil {
+		uc.logger.WithError(err).WithFields(logrus.Fields{
+			"banners": len(bannerIDs),
+			"from":    req.From,
+			"to":      req.To,
+		}).Error("Failed to get banner set stats")
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	banners := make([]*BannerStatsDTO, len(statsResponse.Banners))
+	for i, bannerStats := range statsResponse.Banners {
+		statsDTO := make([]*BucketStatDTO, len(bannerStats.Stats))
+		for j, stat := range bannerStats.Stats {
+			statsDTO[j] = &BucketStatDTO{
+				Timestamp: stat.Timestamp,
+				Count:     stat.Value,
+				Uniques:   stat.Uniques,
+			}
+		}
+
+		banners[i] = &BannerStatsDTO{
+			BannerID: bannerStats.BannerID,
+			Stats:    statsDTO,
+			Total:    bannerStats.Total,
+			Uniques:  bannerStats.Uniques,
+		}
+	}
+
+	uc.logger.WithFields(logrus.Fields{
+		"banners":     len(banners),
+		"granularity": statsResponse.Granularity,
+		"total":       statsResponse.Total,
+	}).Info("Banner set stats retrieved successfully")
+
+	return &GetMultiStatsResponse{
+		Granularity: statsResponse.Granularity,
+		Banners:     banners,
+		Total:       statsResponse.Total,
+	}, nil
+}
+
+// activeBannerIDs возвращает ID всех активных баннеров
+func (uc *StatsUseCase) activeBannerIDs(ctx context.Context) ([]int64, error) {
+	active := true
+	banners, _, err := uc.bannerService.List(ctx, banner.ListFilter{
+		Active: &active,
+		Limit:  stats.MaxBannersPerQuery + 1,
+	})
+	if err != nil {
+		uc.logger.WithError(err).Error("Failed to list active banners")
+		return nil, fmt.Errorf("failed to list active banners: %w", err)
+	}
+
+	if len(banners) > stats.MaxBannersPerQuery {
+		return nil, fmt.Errorf("%w: more than %d active banners", stats.ErrTooManyBanners, stats.MaxBannersPerQuery)
+	}
+
+	ids := make([]int64, len(banners))
+	for i, b := range banners {
+		ids[i] = b.ID
+	}
+	return ids, nil
+}
+
+// checkBannersExist проверяет существование баннеров (через кэш баннеров).
+// Как и для одного баннера, статистика доступна и для неактивных баннеров
+func (uc *StatsUseCase) checkBannersExist(ctx context.Context, bannerIDs []int64) error {
+	var missing []int64
+	for _, id := range bannerIDs {
+		if _, err := uc.bannerService.Get(ctx, id); err != nil {
+			if errors.Is(err, banner.ErrBannerNotFound) {
+				missing = append(missing, id)
+				continue
+			}
+			uc.logger.WithError(err).WithField("banner_id", id).Error("Failed to check banner existence")
+			return fmt.Errorf("failed to check banner existence: %w", err)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %v", banner.ErrBannerNotFound, missing)
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/domain/click"
+	"github.com/clickcounter/app/internal/domain/stats"
+	"github.com/clickcounter/app/internal/domain/tenant"
+)
+
+// Имена таблиц в отчетах об удалении
+const (
+	RetentionClicks = "clicks"
+	RetentionStats  = "stats"
+	RetentionHourly = "stats_hourly"
+	RetentionDaily  = "stats_daily"
+)
+
+// RetentionPolicy сроки хранения данных; нулевой срок - хранить бессрочно
+type RetentionPolicy struct {
+	Clicks time.Duration // сырые клики
+	Stats  time.Duration // минутная статистика
+	Hourly time.Duration // часовая свертка
+	Daily  time.Duration // дневная свертка
+
+	ChunkSize  int           // максимум строк статистики, удаляемых одним запросом
+	ChunkPause time.Duration // пауза между порциями
+
+	// RequireAggregated запрещает удалять клики, еще не учтенные фоновой агрегацией
+	// (нужно, если клики пишутся в БД в обход сервиса и работает stats_aggregator)
+	RequireAggregated bool
+
+	// RequireRollup запрещает удалять минутную и часовую статистику, еще не свернутую в следующий уровень
+	RequireRollup bool
+}
+
+// RetentionResult количество строк, удаленных за проход, по таблицам
+type RetentionResult map[string]int64
+
+// RetentionDependencies репозитории, используемые очисткой
+type RetentionDependencies struct {
+	Clicks      click.RetentionRepository
+	Stats       stats.RetentionRepository
+	Rollups     stats.RollupRepository                 // нужен при RequireRollup
+	Aggregation stats.IncrementalAggregationRepository // нужен при RequireAggregated
+}
+
+// Retention периодически удаляет данные старше сроков хранения
+type Retention struct {
+	deps     RetentionDependencies
+	policy   RetentionPolicy
+	interval time.Duration
+	logger   *logrus.Logger
+
+	// Накопленное количество удаленных строк по таблицам
+	totalsMutex sync.Mutex
+	totals      map[string]int64
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	mutex  sync.Mutex
+}
+
+// NewRetention создает фоновую очистку устаревших данных
+func NewRetention(deps RetentionDependencies, policy RetentionPolicy, interval time.Duration, logger *logrus.Logger) *Retention {
+	if logger == nil {
+		logger = logrus.New()
+	}
+
+	// Устанавливаем разумные значения по умолчанию если переданы некорректные
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	if policy.ChunkSize <= 0 {
+		policy.ChunkSize = 10000
+	}
+
+	return &Retention{
+		deps:     deps,
+		policy:   policy,
+		interval: interval,
+		logger:   logger,
+		totals:   make(map[string]int64),
+	}
+}
+
+// Start запускает фоновую очистку
+func (r *Retention) Start() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(tenant.WithSystem(context.Background()))
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	go r.run(ctx)
+
+	r.logger.WithFields(logrus.Fields{
+		"interval":   r.interval,
+		"clicks":     r.policy.Clicks,
+		"stats":      r.policy.Stats,
+		"hourly":     r.policy.Hourly,
+		"daily":      r.policy.Daily,
+		"chunk_size": r.policy.ChunkSize,
+	}).Info("Retention started")
+}
+
+// Stop останавливает фоновую очистку, прерывая текущий проход между порциями
+func (r *Retention) Stop(ctx context.Context) error {
+	r.mutex.Lock()
+	cancel, done := r.cancel, r.done
+	r.cancel = nil
+	r.mutex.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	r.logger.Info("Retention stopped")
+	return nil
+}
+
+// Totals возвращает количество строк, удаленных с момента запуска, по таблицам
+func (r *Retention) Totals() map[string]int64 {
+	r.totalsMutex.Lock()
+	defer r.totalsMutex.Unlock()
+
+	totals := make(map[string]int64, len(r.totals))
+	for table, deleted := range r.totals {
+		totals[table] = deleted
+	}
+	return totals
+}
+
+// RunOnce удаляет устаревшие данные из всех таблиц и возвращает количество удаленных строк.
+// При ошибке результат содержит строки, удаленные до нее
+func (r *Retention) RunOnce(ctx context.Context) (RetentionResult, error) {
+	started := time.Now()
+	result := RetentionResult{}
+
+	err := r.runTables(ctx, started, result)
+
+	fields := logrus.Fields{"duration": time.Since(started)}
+	for table, deleted := range result {
+		fields[table] = deleted
+	}
+	switch {
+	case err != nil && ctx.Err() != nil:
+		r.logger.WithFields(fields).Info("Retention pass interrupted")
+	case err != nil:
+		r.logger.WithError(err).WithFields(fields).Error("Retention pass failed")
+	default:
+		r.logger.WithFields(fields).Info("Retention pass completed")
+	}
+
+	return result, err
+}
+
+// runTables очищает таблицы по порядку: от сырых данных к самым крупным бакетам
+func (r *Retention) runTables(ctx context.Context, now time.Time, result RetentionResult) error {
+	if r.policy.Clicks > 0 {
+		maxID := int64(0)
+		if r.policy.RequireAggregated {
+			var err error
+			if maxID, err = r.deps.Aggregation.GetAggregatedClickID(ctx); err != nil {
+				return err
+			}
+		}
+
+		if r.policy.RequireAggregated && maxID == 0 {
+			r.logger.Warn("No clicks aggregated yet, skipping clicks retention")
+		} else {
+			// Секции удаляются целиком, порции не нужны
+			deleted, err := r.deps.Clicks.DropOldClicks(ctx, now.Add(-r.policy.Clicks), maxID)
+			r.record(RetentionClicks, deleted, result)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var boundaries map[stats.RollupLevel]time.Time
+	if r.policy.RequireRollup && (r.policy.Stats > 0 || r.policy.Hourly > 0) {
+		var err error
+		if boundaries, err = r.deps.Rollups.GetRollupBoundaries(ctx); err != nil {
+			return err
+		}
+	}
+
+	if r.policy.Stats > 0 {
+		before := r.rolledUpBefore(RetentionStats, now.Add(-r.policy.Stats), boundaries, stats.RollupHourly)
+		err := r.deleteChunks(ctx, RetentionStats, result, func(ctx context.Context) (int64, error) {
+			return r.deps.Stats.DeleteOldStats(ctx, before, r.policy.ChunkSize)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.policy.Hourly > 0 {
+		before := r.rolledUpBefore(RetentionHourly, now.Add(-r.policy.Hourly), boundaries, stats.RollupDaily)
+		err := r.deleteChunks(ctx, RetentionHourly, result, func(ctx context.Context) (int64, error) {
+			return r.deps.Stats.DeleteOldRollups(ctx, stats.RollupHourly, before, r.policy.ChunkSize)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.policy.Daily > 0 {
+		before := now.Add(-r.policy.Daily)
+		err := r.deleteChunks(ctx, RetentionDaily, result, func(ctx context.Context) (int64, error) {
+			return r.deps.Stats.DeleteOldRollups(ctx, stats.RollupDaily, before, r.policy.ChunkSize)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rolledUpBefore ограничивает границу удаления данными, уже свернутыми в следующий уровень
+func (r *Retention) rolledUpBefore(table string, before time.Time, boundaries map[stats.RollupLevel]time.Time, next stats.RollupLevel) time.Time {
+	if !r.policy.RequireRollup {
+		return before
+	}
+
+	if complete := boundaries[next]; complete.Before(before) {
+		r.logger.WithFields(logrus.Fields{
+			"table":           table,
+			"before":          before,
+			"complete_before": complete,
+		}).Warn("Retention limited to rolled up data")
+		return complete
+	}
+	return before
+}
+
+// deleteChunks вызывает deleteChunk, пока он удаляет полные порции
+func (r *Retention) deleteChunks(ctx context.Context, table string, result RetentionResult, deleteChunk func(context.Context) (int64, error)) error {
+	for {
+		deleted, err := deleteChunk(ctx)
+		if err != nil {
+			return err
+		}
+
+		r.record(table, deleted, result)
+
+		if deleted < int64(r.policy.ChunkSize) {
+			return nil
+		}
+
+		// Пауза дает место обычной нагрузке и автовакууму
+		select {
+		case <-time.After(r.policy.ChunkPause):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// record учитывает удаленные строки в результате прохода и накопленных итогах
+func (r *Retention) record(table string, deleted int64, result RetentionResult) {
+	result[table] += deleted
+	r.totalsMutex.Lock()
+	r.totals[table] += deleted
+	r.totalsMutex.Unlock()
+}
+
+// run выполняет очистку по таймеру до отмены контекста
+func (r *Retention) run(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// Ошибка уже залогирована в RunOnce
+			_, _ = r.RunOnce(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/domain/stats"
+	"github.com/clickcounter/app/internal/domain/tenant"
+)
+
+// StatsAggregator периодически переносит новые клики в таблицу статистики
+type StatsAggregator struct {
+	repo      stats.IncrementalAggregationRepository
+	interval  time.Duration
+	batchSize int64
+	logger    *logrus.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	mutex  sync.Mutex
+}
+
+// NewStatsAggregator создает новый фоновый агрегатор статистики
+func NewStatsAggregator(
+	repo stats.IncrementalAggregationRepository,
+	interval time.Duration,
+	batchSize int64,
+	logger *logrus.Logger,
+) *StatsAggregator {
+	if logger == nil {
+		logger = logrus.New()
+	}
+
+	// Устанавливаем разумные значения по умолчанию если переданы некорректные
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	if batchSize < 0 {
+		batchSize = 0
+	}
+
+	return &StatsAggregator{
+		repo:      repo,
+		interval:  interval,
+		batchSize: batchSize,
+		logger:    logger,
+	}
+}
+
+// Start запускает фоновую агрегацию
+func (a *StatsAggregator) Start() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(tenant.WithSystem(context.Background()))
+	a.cancel = cancel
+	a.done = make(chan struct{})
+
+	go a.run(ctx)
+
+	a.logger.WithFields(logrus.Fields{
+		"interval":   a.interval,
+		"batch_size": a.batchSize,
+	}).Info("Stats aggregator started")
+}
+
+// Stop останавливает фоновую агрегацию и выполняет финальный проход
+func (a *StatsAggregator) Stop(ctx context.Context) error {
+	a.mutex.Lock()
+	cancel, done := a.cancel, a.done
+	a.cancel = nil
+	a.mutex.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// Финальный проход учитывает клики, сброшенные при остановке сервиса
+	err := a.RunOnce(ctx)
+	a.logger.Info("Stats aggregator stopped")
+	return err
+}
+
+// RunOnce агрегирует все накопившиеся клики, пока не догонит текущий high-water mark
+func (a *StatsAggregator) RunOnce(ctx context.Context) error {
+	for {
+		result, err := a.repo.AggregatePendingClicks(ctx, a.batchSize)
+		if err != nil {
+			return err
+		}
+
+		if !result.HasMore {
+			return nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// run выполняет агрегацию по таймеру до отмены контекста
+func (a *StatsAggregator) run(ctx context.Context) {
+	defer close(a.done)
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := a.RunOnce(ctx); err != nil && ctx.Err() == nil {
+				a.logger.WithError(err).Error("Stats aggregation pass failed")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
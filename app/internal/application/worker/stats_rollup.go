@@ -0,0 +1,140 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/domain/stats"
+	"github.com/clickcounter/app/internal/domain/tenant"
+)
+
+// StatsRollup периодически сворачивает минутную статистику в часовую и дневную
+type StatsRollup struct {
+	repo        stats.RollupRepository
+	interval    time.Duration
+	settleDelay time.Duration
+	batchSize   int
+	logger      *logrus.Logger
+
+	// Сроки хранения исходной таблицы по уровням (нет записи - бессрочно)
+	sourceRetention map[stats.RollupLevel]time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	mutex  sync.Mutex
+}
+
+// NewStatsRollup создает новый фоновый процесс свертки статистики
+func NewStatsRollup(
+	repo stats.RollupRepository,
+	interval time.Duration,
+	settleDelay time.Duration,
+	sourceRetention map[stats.RollupLevel]time.Duration,
+	batchSize int,
+	logger *logrus.Logger,
+) *StatsRollup {
+	if logger == nil {
+		logger = logrus.New()
+	}
+
+	// Устанавливаем разумные значения по умолчанию если переданы некорректные
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	if settleDelay < 0 {
+		settleDelay = 0
+	}
+	if batchSize <= 0 {
+		batchSize = 5000
+	}
+
+	return &StatsRollup{
+		repo:            repo,
+		interval:        interval,
+		settleDelay:     settleDelay,
+		batchSize:       batchSize,
+		logger:          logger,
+		sourceRetention: sourceRetention,
+	}
+}
+
+// Start запускает фоновую свертку
+func (r *StatsRollup) Start() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(tenant.WithSystem(context.Background()))
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	go r.run(ctx)
+
+	r.logger.WithFields(logrus.Fields{
+		"interval":     r.interval,
+		"settle_delay": r.settleDelay,
+		"batch_size":   r.batchSize,
+	}).Info("Stats rollup started")
+}
+
+// Stop останавливает фоновую свертку. Незавершенный проход откатывается
+// и будет повторен при следующем запуске
+func (r *StatsRollup) Stop(ctx context.Context) error {
+	r.mutex.Lock()
+	cancel, done := r.cancel, r.done
+	r.cancel = nil
+	r.mutex.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	r.logger.Info("Stats rollup stopped")
+	return nil
+}
+
+// RunOnce сворачивает изменения по всем уровням: сначала часовой, затем дневной из часового
+func (r *StatsRollup) RunOnce(ctx context.Context) error {
+	for _, level := range stats.RollupLevels {
+		if _, err := r.repo.RollupStats(ctx, level, r.settleDelay, r.sourceRetention[level], r.batchSize); err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run выполняет свертку по таймеру до отмены контекста
+func (r *StatsRollup) run(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.RunOnce(ctx); err != nil && ctx.Err() == nil {
+				r.logger.WithError(err).Error("Stats rollup pass failed")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
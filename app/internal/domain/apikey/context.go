@@ -0,0 +1,36 @@
+package apikey
+
+import (
+	"context"
+	"fmt"
+)
+
+// contextKey ключ значения контекста с API ключом запроса
+type contextKey struct{}
+
+// NewContext возвращает контекст с API ключом, которым аутентифицирован запрос
+func NewContext(ctx context.Context, key *Key) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// FromContext возвращает API ключ запроса; ok = false, если аутентификация отключена
+func FromContext(ctx context.Context) (key *Key, ok bool) {
+	key, ok = ctx.Value(contextKey{}).(*Key)
+	return key, ok && key != nil
+}
+
+// CheckBanners проверяет доступ API ключа запроса к баннерам. Без ключа в контексте
+// (аутентификация отключена) доступ не ограничивается
+func CheckBanners(ctx context.Context, bannerIDs []int64) error {
+	key, ok := FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	for _, id := range bannerIDs {
+		if !key.AllowsBanner(id) {
+			return fmt.Errorf("%w: %d", ErrBannerForbidden, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Scope область доступа API ключа
+type Scope string
+
+// Области доступа
+const (
+	ScopeClickWrite Scope = "click:write" // зарезервирована: регистрация кликов публичная и ключ не требует
+	ScopeStatsRead  Scope = "stats:read"  // статистика, поток статистики и поиск кликов
+	ScopeAdmin      Scope = "admin"       // все области, управление баннерами и ключами
+)
+
+// Параметры ключа
+const (
+	// TokenPrefix префикс выдаваемых ключей: помогает опознать ключ в логах и сканерах секретов
+	TokenPrefix = "cck_"
+
+	// MaxNameLength максимальная длина названия ключа (api_keys.name VARCHAR(255))
+	MaxNameLength = 255
+
+	// tokenBytes количество случайных байт ключа
+	tokenBytes = 32
+
+	// displayPrefixLength длина сохраняемого начала ключа для опознания в списке ключей
+	displayPrefixLength = len(TokenPrefix) + 8
+)
+
+// Доменные ошибки
+var (
+	ErrKeyNotFound     = errors.New("api key not found")
+	ErrInvalidKey      = errors.New("invalid api key")
+	ErrInvalidKeyID    = errors.New("invalid api key ID")
+	ErrEmptyName       = errors.New("api key name is required")
+	ErrNameTooLong     = errors.New("api key name is too long")
+	ErrNoScopes        = errors.New("api key requires at least one scope")
+	ErrInvalidScope    = errors.New("invalid api key scope")
+	ErrInvalidBannerID = errors.New("invalid banner ID in api key allowlist")
+	ErrAdminRestricted = errors.New("admin api key cannot be restricted to banners")
+	ErrInvalidExpiry   = errors.New("api key expiry must be in the future")
+	ErrBannerForbidden = errors.New("api key is not allowed to access banner")
+)
+
+// Key представляет API ключ. Сам ключ не хранится: только его SHA-256 и начало для опознания
+type Key struct {
+	ID        int64      `json:"id" db:"id"`
+	TenantID  int64      `json:"tenant_id" db:"tenant_id"` // 0 у ключей из конфигурации
+	Name      string     `json:"name" db:"name"`
+	Prefix    string     `json:"prefix" db:"prefix"`
+	Hash      string     `json:"hash" db:"key_hash"`
+	Scopes    []Scope    `json:"scopes" db:"scopes"`
+	BannerIDs []int64    `json:"banner_ids" db:"banner_ids"` // пусто - все баннеры
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"` // nil - бессрочный
+	RevokedAt *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+
+	// Bootstrap - ключ из конфигурации (auth.api_keys), не хранится в БД и не привязан
+	// к арендатору: арендатор запроса выбирается заголовком X-Tenant-ID
+	Bootstrap bool `json:"bootstrap,omitempty" db:"-"`
+}
+
+// ParseScope разбирает область доступа
+func ParseScope(value string) (Scope, error) {
+	switch scope := Scope(strings.TrimSpace(value)); scope {
+	case ScopeClickWrite, ScopeStatsRead, ScopeAdmin:
+		return scope, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidScope, value)
+	}
+}
+
+// NewKey создает ключ с валидацией и возвращает его вместе с самим ключом,
+// который показывается клиенту один раз
+func NewKey(name string, scopes []Scope, bannerIDs []int64, expiresAt *time.Time, now time.Time) (*Key, string, error) {
+	k := &Key{
+		Name:      strings.TrimSpace(name),
+		Scopes:    normalizeScopes(scopes),
+		BannerIDs: normalizeBannerIDs(bannerIDs),
+		ExpiresAt: expiresAt,
+	}
+
+	if err := k.IsValid(); err != nil {
+		return nil, "", err
+	}
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, "", ErrInvalidExpiry
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, "", err
+	}
+	k.Hash = HashToken(token)
+	k.Prefix = token[:displayPrefixLength]
+
+	return k, token, nil
+}
+
+// NewBootstrapKey создает ключ с правами admin для ключа из конфигурации
+func NewBootstrapKey(token string) *Key {
+	prefix := token
+	if len(prefix) > displayPrefixLength {
+		prefix = prefix[:displayPrefixLength]
+	}
+
+	return &Key{
+		Name:      "bootstrap",
+		Prefix:    prefix,
+		Hash:      HashToken(token),
+		Scopes:    []Scope{ScopeAdmin},
+		Bootstrap: true,
+	}
+}
+
+// HashToken возвращает SHA-256 ключа в hex. Ключи случайные и длинные, поэтому
+// медленное хэширование паролей не нужно, а поиск по хэшу остается индексным
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsValid проверяет валидность ключа
+func (k *Key) IsValid() error {
+	if k.ID < 0 {
+		return ErrInvalidKeyID
+	}
+
+	if k.Name == "" {
+		return ErrEmptyName
+	}
+	if utf8.RuneCountInString(k.Name) > MaxNameLength {
+		return ErrNameTooLong
+	}
+
+	if len(k.Scopes) == 0 {
+		return ErrNoScopes
+	}
+	for _, scope := range k.Scopes {
+		if _, err := ParseScope(string(scope)); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range k.BannerIDs {
+		if id <= 0 {
+			return ErrInvalidBannerID
+		}
+	}
+	if len(k.BannerIDs) > 0 && slices.Contains(k.Scopes, ScopeAdmin) {
+		return ErrAdminRestricted
+	}
+
+	return nil
+}
+
+// IsActive проверяет, действует ли ключ в момент now: не отозван и не истек
+func (k *Key) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// HasScope проверяет наличие области доступа. Область admin включает все остальные
+func (k *Key) HasScope(scope Scope) bool {
+	return slices.Contains(k.Scopes, scope) || slices.Contains(k.Scopes, ScopeAdmin)
+}
+
+// IsRestricted сообщает, ограничен ли ключ списком баннеров
+func (k *Key) IsRestricted() bool {
+	return len(k.BannerIDs) > 0
+}
+
+// AllowsBanner проверяет доступ ключа к баннеру
+func (k *Key) AllowsBanner(bannerID int64) bool {
+	if !k.IsRestricted() {
+		return true
+	}
+	_, found := slices.BinarySearch(k.BannerIDs, bannerID)
+	return found
+}
+
+// generateToken генерирует случайный ключ
+func generateToken() (string, error) {
+	buf := make([]byte, tokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	return TokenPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// normalizeScopes удаляет повторы и упорядочивает области доступа
+func normalizeScopes(scopes []Scope) []Scope {
+	result := slices.Clone(scopes)
+	slices.Sort(result)
+	return slices.Compact(result)
+}
+
+// normalizeBannerIDs удаляет повторы и упорядочивает ID баннеров (для бинарного поиска)
+func normalizeBannerIDs(bannerIDs []int64) []int64 {
+	result := slices.Clone(bannerIDs)
+	slices.Sort(result)
+	return slices.Compact(result)
+}
@@ -0,0 +1,32 @@
+package apikey
+
+import (
+	"context"
+)
+
+// Repository определяет интерфейс для работы с API ключами
+type Repository interface {
+	// GetByHash возвращает ключ по SHA-256 (в том числе отозванный или истекший)
+	GetByHash(ctx context.Context, hash string) (*Key, error)
+
+	// List возвращает все ключи, включая отозванные
+	List(ctx context.Context) ([]*Key, error)
+
+	// Create сохраняет ключ, заполняя ID и CreatedAt
+	Create(ctx context.Context, key *Key) error
+
+	// Revoke отзывает ключ и возвращает его; повторный отзыв сохраняет исходное время
+	Revoke(ctx context.Context, id int64) (*Key, error)
+}
+
+// CacheRepository определяет интерфейс для кэширования ключей по SHA-256
+type CacheRepository interface {
+	// Get возвращает ключ из кэша
+	Get(ctx context.Context, hash string) (*Key, error)
+
+	// Set сохраняет ключ в кэш
+	Set(ctx context.Context, key *Key) error
+
+	// Delete удаляет ключ из кэша
+	Delete(ctx context.Context, hash string) error
+}
@@ -0,0 +1,120 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Service представляет доменный сервис для работы с API ключами
+type Service struct {
+	repo      Repository
+	cacheRepo CacheRepository
+
+	// Ключи из конфигурации по SHA-256: позволяют создать первые ключи в пустой БД
+	bootstrap map[string]*Key
+}
+
+// NewService создает новый экземпляр сервиса API ключей.
+// bootstrapTokens - ключи из конфигурации с правами admin
+func NewService(repo Repository, cacheRepo CacheRepository, bootstrapTokens []string) *Service {
+	bootstrap := make(map[string]*Key, len(bootstrapTokens))
+	for _, token := range bootstrapTokens {
+		if token != "" {
+			key := NewBootstrapKey(token)
+			bootstrap[key.Hash] = key
+		}
+	}
+
+	return &Service{
+		repo:      repo,
+		cacheRepo: cacheRepo,
+		bootstrap: bootstrap,
+	}
+}
+
+// Authenticate возвращает действующий ключ по переданному клиентом значению.
+// Неизвестный, отозванный и истекший ключ - ErrInvalidKey
+func (s *Service) Authenticate(ctx context.Context, token string) (*Key, error) {
+	if token == "" {
+		return nil, ErrInvalidKey
+	}
+
+	hash := HashToken(token)
+	if key, ok := s.bootstrap[hash]; ok {
+		return key, nil
+	}
+
+	key, err := s.get(ctx, hash)
+	if err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil, ErrInvalidKey
+		}
+		return nil, fmt.Errorf("failed to authenticate api key: %w", err)
+	}
+
+	if !key.IsActive(time.Now()) {
+		return nil, ErrInvalidKey
+	}
+
+	return key, nil
+}
+
+// get возвращает ключ по SHA-256 через кэш. Отозванные ключи тоже кэшируются:
+// отзыв на другом экземпляре сервиса вступает в силу по истечении TTL кэша
+func (s *Service) get(ctx context.Context, hash string) (*Key, error) {
+	if s.cacheRepo != nil {
+		if key, err := s.cacheRepo.Get(ctx, hash); err == nil && key != nil {
+			return key, nil
+		}
+	}
+
+	key, err := s.repo.GetByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.cacheRepo != nil {
+		_ = s.cacheRepo.Set(ctx, key)
+	}
+
+	return key, nil
+}
+
+// Create создает ключ и возвращает его вместе с самим ключом, который больше нигде не хранится
+func (s *Service) Create(ctx context.Context, name string, scopes []Scope, bannerIDs []int64, expiresAt *time.Time) (*Key, string, error) {
+	key, token, err := NewKey(name, scopes, bannerIDs, expiresAt, time.Now())
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	return key, token, nil
+}
+
+// List возвращает все ключи, хранящиеся в БД
+func (s *Service) List(ctx context.Context) ([]*Key, error) {
+	return s.repo.List(ctx)
+}
+
+// Revoke отзывает ключ. На этом экземпляре сервиса ключ перестает действовать сразу
+func (s *Service) Revoke(ctx context.Context, id int64) (*Key, error) {
+	if id <= 0 {
+		return nil, ErrInvalidKeyID
+	}
+
+	key, err := s.repo.Revoke(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.cacheRepo != nil {
+		_ = s.cacheRepo.Delete(ctx, key.Hash)
+	}
+
+	return key, nil
+}
@@ -2,29 +2,113 @@ package banner
 
 import (
 	"errors"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
+// MaxNameLength максимальная длина названия баннера (banners.name VARCHAR(255))
+const MaxNameLength = 255
+
 // Banner представляет сущность баннера в системе
 type Banner struct {
 	ID        int64     `json:"id" db:"id"`
+	TenantID  int64     `json:"tenant_id" db:"tenant_id"`
 	Name      string    `json:"name" db:"name"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 	IsActive  bool      `json:"is_active" db:"is_active"`
+
+	// Окно показа [StartsAt, EndsAt); nil - без ограничения с соответствующей стороны
+	StartsAt *time.Time `json:"starts_at,omitempty" db:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at,omitempty" db:"ends_at"`
+}
+
+// ListFilter параметры выборки списка баннеров
+type ListFilter struct {
+	Active *bool // nil - все баннеры
+	Limit  int
+	Offset int
 }
 
 // Доменные ошибки
 var (
 	ErrBannerNotFound  = errors.New("banner not found")
 	ErrInvalidBannerID = errors.New("invalid banner ID")
+	ErrEmptyName       = errors.New("banner name is required")
+	ErrNameTooLong     = errors.New("banner name is too long")
+	ErrInvalidSchedule = errors.New("banner ends_at must be after starts_at")
 )
 
+// NewBanner создает новый активный баннер с валидацией
+func NewBanner(name string, startsAt, endsAt *time.Time) (*Banner, error) {
+	b := &Banner{
+		Name:     strings.TrimSpace(name),
+		IsActive: true,
+		StartsAt: startsAt,
+		EndsAt:   endsAt,
+	}
+
+	if err := b.IsValid(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
 // IsValid проверяет валидность баннера
 func (b *Banner) IsValid() error {
 	if b.ID < 0 {
 		return ErrInvalidBannerID
 	}
 
+	if strings.TrimSpace(b.Name) == "" {
+		return ErrEmptyName
+	}
+
+	if utf8.RuneCountInString(b.Name) > MaxNameLength {
+		return ErrNameTooLong
+	}
+
+	if b.StartsAt != nil && b.EndsAt != nil && !b.EndsAt.After(*b.StartsAt) {
+		return ErrInvalidSchedule
+	}
+
 	return nil
 }
+
+// IsLive проверяет, принимает ли баннер клики в момент now:
+// баннер активен и now попадает в окно показа
+func (b *Banner) IsLive(now time.Time) bool {
+	if !b.IsActive {
+		return false
+	}
+
+	if b.StartsAt != nil && now.Before(*b.StartsAt) {
+		return false
+	}
+
+	if b.EndsAt != nil && !now.Before(*b.EndsAt) {
+		return false
+	}
+
+	return true
+}
+
+// NextTransition возвращает ближайшую после now границу окна показа,
+// в которую меняется результат IsLive. ok = false, если таких границ нет
+func (b *Banner) NextTransition(now time.Time) (next time.Time, ok bool) {
+	if !b.IsActive {
+		return time.Time{}, false
+	}
+
+	if b.StartsAt != nil && now.Before(*b.StartsAt) {
+		return *b.StartsAt, true
+	}
+
+	if b.EndsAt != nil && now.Before(*b.EndsAt) {
+		return *b.EndsAt, true
+	}
+
+	return time.Time{}, false
+}
@@ -6,11 +6,17 @@ import (
 
 // Repository определяет интерфейс для работы с баннерами
 type Repository interface {
-	// GetByID возвращает баннер по ID
+	// GetByID возвращает баннер по ID (в том числе неактивный)
 	GetByID(ctx context.Context, id int64) (*Banner, error)
 
-	// Exists проверяет существование баннера
-	Exists(ctx context.Context, id int64) (bool, error)
+	// List возвращает страницу баннеров и общее количество баннеров, подходящих под фильтр
+	List(ctx context.Context, filter ListFilter) ([]*Banner, int64, error)
+
+	// Create создает баннер, заполняя ID и временные метки
+	Create(ctx context.Context, banner *Banner) error
+
+	// Update сохраняет название, флаг активности и окно показа баннера, обновляя UpdatedAt
+	Update(ctx context.Context, banner *Banner) error
 }
 
 // CacheRepository определяет интерфейс для кэширования баннеров
@@ -2,7 +2,10 @@ package banner
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 )
 
 // Service представляет доменный сервис для работы с баннерами
@@ -19,26 +22,40 @@ func NewService(repo Repository, cacheRepo CacheRepository) *Service {
 	}
 }
 
-// Exists проверяет существование активного баннера
+// Exists проверяет, принимает ли баннер клики прямо сейчас:
+// баннер существует, активен и текущее время попадает в окно показа
 func (s *Service) Exists(ctx context.Context, id int64) (bool, error) {
+	banner, err := s.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrBannerNotFound) {
+			return false, nil
+		}
+		if errors.Is(err, ErrInvalidBannerID) {
+			return false, err
+		}
+		return false, fmt.Errorf("failed to check banner existence: %w", err)
+	}
+
+	return banner.IsLive(time.Now()), nil
+}
+
+// Get возвращает баннер по ID (в том числе неактивный или вне окна показа)
+func (s *Service) Get(ctx context.Context, id int64) (*Banner, error) {
 	if id <= 0 {
-		return false, ErrInvalidBannerID
+		return nil, ErrInvalidBannerID
 	}
 
 	// Пытаемся получить из кэша
 	if s.cacheRepo != nil {
 		if banner, err := s.cacheRepo.Get(ctx, id); err == nil && banner != nil {
-			return banner.IsActive, nil
+			return banner, nil
 		}
 	}
 
 	// Получаем из основного репозитория
 	banner, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		if err == ErrBannerNotFound {
-			return false, nil
-		}
-		return false, fmt.Errorf("failed to check banner existence: %w", err)
+		return nil, err
 	}
 
 	// Сохраняем в кэш
@@ -46,5 +63,80 @@ func (s *Service) Exists(ctx context.Context, id int64) (bool, error) {
 		_ = s.cacheRepo.Set(ctx, banner)
 	}
 
-	return banner.IsActive, nil
+	return banner, nil
+}
+
+// List возвращает страницу баннеров и их общее количество
+func (s *Service) List(ctx context.Context, filter ListFilter) ([]*Banner, int64, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	return s.repo.List(ctx, filter)
+}
+
+// Create создает новый активный баннер с опциональным окном показа
+func (s *Service) Create(ctx context.Context, name string, startsAt, endsAt *time.Time) (*Banner, error) {
+	banner, err := NewBanner(name, startsAt, endsAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, banner); err != nil {
+		return nil, fmt.Errorf("failed to create banner: %w", err)
+	}
+
+	return banner, nil
+}
+
+// Update изменяет название и окно показа баннера
+func (s *Service) Update(ctx context.Context, id int64, name string, startsAt, endsAt *time.Time) (*Banner, error) {
+	return s.update(ctx, id, func(b *Banner) {
+		b.Name = strings.TrimSpace(name)
+		b.StartsAt = startsAt
+		b.EndsAt = endsAt
+	})
+}
+
+// SetActive активирует или деактивирует баннер. Деактивированный баннер
+// перестает принимать клики сразу: запись в кэше инвалидируется
+func (s *Service) SetActive(ctx context.Context, id int64, active bool) (*Banner, error) {
+	return s.update(ctx, id, func(b *Banner) {
+		b.IsActive = active
+	})
+}
+
+// update применяет изменение к баннеру, сохраняет его и инвалидирует кэш
+func (s *Service) update(ctx context.Context, id int64, apply func(b *Banner)) (*Banner, error) {
+	if id <= 0 {
+		return nil, ErrInvalidBannerID
+	}
+
+	// Изменяем свежую копию из БД, а не закэшированную
+	banner, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	apply(banner)
+	if err := banner.IsValid(); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, banner); err != nil {
+		return nil, err
+	}
+
+	s.invalidate(ctx, id)
+	return banner, nil
+}
+
+// invalidate удаляет баннер из кэша, чтобы Exists увидел изменения
+func (s *Service) invalidate(ctx context.Context, id int64) {
+	if s.cacheRepo != nil {
+		_ = s.cacheRepo.Delete(ctx, id)
+	}
 }
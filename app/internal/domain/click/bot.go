@@ -0,0 +1,23 @@
+package click
+
+import "fmt"
+
+// BotAction определяет обработку кликов, признанных фильтром ботов
+type BotAction string
+
+const (
+	// BotFlag - клик сохраняется с признаком is_bot и учитывается в статистике отдельно
+	BotFlag BotAction = "flag"
+	// BotDiscard - клик не сохраняется
+	BotDiscard BotAction = "discard"
+)
+
+// ParseBotAction преобразует строку конфигурации в обработку кликов ботов
+func ParseBotAction(value string) (BotAction, error) {
+	switch action := BotAction(value); action {
+	case BotFlag, BotDiscard:
+		return action, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidBotAction, value)
+	}
+}
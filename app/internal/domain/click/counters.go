@@ -0,0 +1,88 @@
+package click
+
+import (
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/clickcounter/app/pkg/hll"
+)
+
+// MinuteKey идентифицирует минутный бакет статистики баннера
+type MinuteKey struct {
+	BannerID int64
+	Minute   time.Time
+}
+
+// BucketCount количество кликов в бакете: клики ботов считаются отдельно
+type BucketCount struct {
+	Count    int64
+	BotCount int64
+	Visitors *hll.Sketch // уникальные посетители среди кликов без ботов (nil - не было таких кликов)
+}
+
+// MinuteCount количество кликов в минутном бакете
+type MinuteCount struct {
+	MinuteKey
+	BucketCount
+}
+
+// MinuteCounters накапливает количество кликов по (баннер, минута) в памяти
+type MinuteCounters map[MinuteKey]BucketCount
+
+// CountByMinute подсчитывает клики по минутным бакетам
+func CountByMinute(clicks []*Click) MinuteCounters {
+	counters := make(MinuteCounters)
+	for _, c := range clicks {
+		counters.Add(c)
+	}
+	return counters
+}
+
+// Add учитывает клик в соответствующем минутном бакете (дубликаты не учитываются)
+func (m MinuteCounters) Add(c *Click) {
+	if c.Duplicate {
+		return
+	}
+
+	key := MinuteKey{BannerID: c.BannerID, Minute: c.GetMinuteTimestamp().UTC()}
+	count := m[key]
+	if c.Bot {
+		count.BotCount++
+	} else {
+		count.Count++
+		if count.Visitors == nil {
+			count.Visitors = hll.NewDefault()
+		}
+		count.Visitors.Add(VisitorHash(c.UserIP, c.UserAgent))
+	}
+	m[key] = count
+}
+
+// VisitorHash возвращает хэш посетителя по IP и User-Agent для оценки уникальных посетителей
+func VisitorHash(userIP, userAgent string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(userIP))
+	h.Write([]byte{0})
+	h.Write([]byte(userAgent))
+	return hll.Mix64(h.Sum64())
+}
+
+// Sorted возвращает счетчики, упорядоченные по баннеру и минуте.
+// Постоянный порядок обновления строк статистики исключает взаимные
+// блокировки между параллельно записываемыми батчами
+func (m MinuteCounters) Sorted() []MinuteCount {
+	result := make([]MinuteCount, 0, len(m))
+	for key, count := range m {
+		result = append(result, MinuteCount{MinuteKey: key, BucketCount: count})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].BannerID != result[j].BannerID {
+			return result[i].BannerID < result[j].BannerID
+		}
+		return result[i].Minute.Before(result[j].Minute)
+	})
+
+	return result
+}
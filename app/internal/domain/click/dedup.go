@@ -0,0 +1,40 @@
+package click
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+)
+
+// DedupMode определяет обработку повторных кликов в окне дедупликации
+type DedupMode string
+
+const (
+	// DedupOff - дедупликация отключена, учитывается каждый клик
+	DedupOff DedupMode = "off"
+	// DedupSuppress - повторный клик не сохраняется
+	DedupSuppress DedupMode = "suppress"
+	// DedupFlag - повторный клик сохраняется с признаком is_duplicate и не учитывается в статистике
+	DedupFlag DedupMode = "flag"
+)
+
+// ParseDedupMode преобразует строку конфигурации в режим дедупликации
+func ParseDedupMode(value string) (DedupMode, error) {
+	switch mode := DedupMode(value); mode {
+	case DedupOff, DedupSuppress, DedupFlag:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidDedupMode, value)
+	}
+}
+
+// Fingerprint возвращает отпечаток клика по баннеру, IP и User-Agent
+func Fingerprint(bannerID int64, userIP, userAgent string) uint64 {
+	h := fnv.New64a()
+	h.Write(strconv.AppendInt(nil, bannerID, 10))
+	h.Write([]byte{0})
+	h.Write([]byte(userIP))
+	h.Write([]byte{0})
+	h.Write([]byte(userAgent))
+	return h.Sum64()
+}
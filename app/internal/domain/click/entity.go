@@ -1,7 +1,9 @@
 package click
 
 import (
+	"crypto/rand"
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -12,6 +14,17 @@ type Click struct {
 	Timestamp time.Time `json:"timestamp" db:"timestamp"`
 	UserIP    string    `json:"user_ip,omitempty" db:"user_ip"`
 	UserAgent string    `json:"user_agent,omitempty" db:"user_agent"`
+
+	// EventID - ключ идемпотентности (UUID), назначаемый при регистрации. Клик сохраняет его
+	// в журнале и dead-letter, поэтому повторная запись уже сохраненного клика распознается
+	EventID string `json:"event_id,omitempty" db:"event_id"`
+
+	// Duplicate - повторный клик с того же отпечатка в окне дедупликации.
+	// Такие клики сохраняются, но не учитываются в статистике
+	Duplicate bool `json:"is_duplicate,omitempty" db:"is_duplicate"`
+
+	// Bot - клик признан фильтром ботов (краулер, монитор, превью ссылок)
+	Bot bool `json:"is_bot,omitempty" db:"is_bot"`
 }
 
 // Доменные ошибки
@@ -19,6 +32,13 @@ var (
 	ErrInvalidBannerID  = errors.New("invalid banner ID")
 	ErrInvalidTimestamp = errors.New("invalid timestamp")
 	ErrClickNotFound    = errors.New("click not found")
+
+	ErrQueueFull             = errors.New("click queue is full")
+	ErrClickDropped          = errors.New("click dropped: queue is full")
+	ErrServiceClosed         = errors.New("click service is closed")
+	ErrInvalidOverflowPolicy = errors.New("invalid click queue overflow policy")
+	ErrInvalidDedupMode      = errors.New("invalid click dedup mode")
+	ErrInvalidBotAction      = errors.New("invalid bot action")
 )
 
 // NewClick создает новый клик с валидацией
@@ -27,12 +47,30 @@ func NewClick(bannerID int64) (*Click, error) {
 		return nil, ErrInvalidBannerID
 	}
 
+	eventID, err := NewEventID()
+	if err != nil {
+		return nil, err
+	}
+
 	return &Click{
 		BannerID:  bannerID,
 		Timestamp: time.Now(),
+		EventID:   eventID,
 	}, nil
 }
 
+// NewEventID возвращает случайный UUID версии 4 для ключа идемпотентности клика
+func NewEventID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate click event ID: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
 // NewClickWithMetadata создает новый клик с дополнительными метаданными
 func NewClickWithMetadata(bannerID int64, userIP, userAgent string) (*Click, error) {
 	click, err := NewClick(bannerID)
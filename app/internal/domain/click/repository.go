@@ -2,6 +2,7 @@ package click
 
 import (
 	"context"
+	"time"
 )
 
 // Repository определяет интерфейс для работы с кликами
@@ -11,4 +12,64 @@ type Repository interface {
 
 	// CreateBatch создает множество кликов за одну операцию (для оптимизации)
 	CreateBatch(ctx context.Context, clicks []*Click) error
+
+	// CreateBatchWithCounters сохраняет клики и прибавляет counters к минутной
+	// статистике в одной транзакции: статистика всегда согласована с сохраненными кликами
+	CreateBatchWithCounters(ctx context.Context, clicks []*Click, counters MinuteCounters) error
+
+	// FilterUnsaved возвращает клики, которых еще нет в БД: клики с EventID, уже сохраненным
+	// ранее (воспроизведение журнала, повтор батча с неизвестным исходом коммита), отбрасываются
+	FilterUnsaved(ctx context.Context, clicks []*Click) ([]*Click, error)
+}
+
+// RetentionRepository определяет интерфейс удаления устаревших кликов.
+// Клики удаляются целыми секциями таблицы, а не построчно
+type RetentionRepository interface {
+	// DropOldClicks удаляет секции, целиком лежащие раньше before, с кликами не новее maxID
+	// (0 - без ограничения по ID), и такие же клики секции по умолчанию. Возвращает
+	// количество удаленных кликов (для секций - оценку по статистике PostgreSQL)
+	DropOldClicks(ctx context.Context, before time.Time, maxID int64) (int64, error)
+}
+
+// Journal определяет интерфейс журнала упреждающей записи для буферизованных кликов
+type Journal interface {
+	// Append дописывает клик в текущий сегмент журнала и возвращает ID этого сегмента
+	Append(click *Click) (uint64, error)
+
+	// Seal закрывает текущий сегмент и возвращает его ID (0, если сегмент пуст)
+	Seal() (uint64, error)
+
+	// Release удаляет сегмент после успешного сохранения всех его кликов в БД
+	Release(segmentID uint64) error
+
+	// Replay передает в fn клики из несброшенных сегментов предыдущего запуска
+	Replay(fn func(segmentID uint64, clicks []*Click) error) error
+}
+
+// DeadLetterQueue определяет интерфейс хранилища батчей, которые не удалось сохранить в БД
+type DeadLetterQueue interface {
+	// Write надежно сохраняет батч для последующей повторной загрузки
+	Write(clicks []*Click) error
+}
+
+// DedupCache определяет интерфейс хранилища отпечатков недавних кликов
+type DedupCache interface {
+	// Seen запоминает отпечаток и сообщает, встречался ли он в окне дедупликации
+	Seen(ctx context.Context, fingerprint uint64) (bool, error)
+
+	// Forget удаляет отпечаток, запомненный Seen для клика, который не удалось принять
+	Forget(ctx context.Context, fingerprint uint64) error
+}
+
+// Publisher определяет интерфейс получателя принятых кликов (live-статистика).
+// Publish вызывается в обработчике запроса и не должен блокироваться
+type Publisher interface {
+	// Publish сообщает о клике, принятом в очередь сброса
+	Publish(click *Click)
+}
+
+// BotDetector определяет интерфейс фильтра ботов и краулеров
+type BotDetector interface {
+	// IsBot сообщает, отправлен ли клик ботом
+	IsBot(userIP, userAgent string) bool
 }
@@ -0,0 +1,85 @@
+package click
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"time"
+)
+
+// Ограничения поиска кликов
+const (
+	DefaultSearchLimit = 100
+	MaxSearchLimit     = 1000
+
+	// MaxSearchPeriod максимальный период поиска: фильтры по IP и User-Agent
+	// проверяются построчно в пределах баннера и периода
+	MaxSearchPeriod = 31 * 24 * time.Hour
+)
+
+// Ошибки поиска кликов
+var (
+	ErrInvalidSearchPeriod = errors.New("invalid click search period")
+	ErrSearchPeriodTooLong = errors.New("click search period is too long")
+	ErrInvalidSearchLimit  = errors.New("invalid click search limit")
+)
+
+// SearchCursor позиция keyset-пагинации: последний клик предыдущей страницы.
+// Клики упорядочены от новых к старым по (Timestamp, ID)
+type SearchCursor struct {
+	Timestamp time.Time
+	ID        int64
+}
+
+// SearchFilter параметры поиска кликов баннера
+type SearchFilter struct {
+	BannerID int64
+	From     time.Time // включительно
+	To       time.Time // не включительно
+
+	// IP - адрес (префикс полной длины) или подсеть; невалидный префикс - без фильтра
+	IP netip.Prefix
+	// UserAgent - подстрока User-Agent без учета регистра (пустая - без фильтра)
+	UserAgent string
+
+	Limit int
+	After *SearchCursor // nil - первая страница
+}
+
+// Validate проверяет фильтр и подставляет размер страницы по умолчанию
+func (f *SearchFilter) Validate() error {
+	if f.BannerID <= 0 {
+		return ErrInvalidBannerID
+	}
+	if f.From.IsZero() || f.To.IsZero() || !f.From.Before(f.To) {
+		return ErrInvalidSearchPeriod
+	}
+	if f.To.Sub(f.From) > MaxSearchPeriod {
+		return ErrSearchPeriodTooLong
+	}
+
+	if f.Limit == 0 {
+		f.Limit = DefaultSearchLimit
+	}
+	if f.Limit < 0 || f.Limit > MaxSearchLimit {
+		return ErrInvalidSearchLimit
+	}
+	return nil
+}
+
+// SearchPage страница результатов поиска кликов
+type SearchPage struct {
+	Clicks []*Click
+	Total  int64         // количество кликов, подходящих под фильтр, без учета пагинации
+	Next   *SearchCursor // nil - последняя страница
+}
+
+// SearchRepository определяет интерфейс поиска сохраненных кликов
+type SearchRepository interface {
+	// Search возвращает до limit кликов, подходящих под фильтр, от новых к старым,
+	// начиная после filter.After
+	Search(ctx context.Context, filter SearchFilter, limit int) ([]*Click, error)
+
+	// Count возвращает количество кликов, подходящих под фильтр (без учета After)
+	Count(ctx context.Context, filter SearchFilter) (int64, error)
+}
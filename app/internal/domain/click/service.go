@@ -2,110 +2,727 @@ package click
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/clickcounter/app/internal/domain/tenant"
+)
+
+// OverflowPolicy определяет поведение при заполненной очереди кликов
+type OverflowPolicy string
+
+const (
+	// OverflowBlock - ждать освобождения места в очереди (с учетом контекста запроса)
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropNewest - отбросить новый клик (ErrClickDropped, клиенту отвечается без ошибки)
+	OverflowDropNewest OverflowPolicy = "drop_newest"
+	// OverflowReject - вернуть ErrQueueFull (HTTP 503)
+	OverflowReject OverflowPolicy = "reject"
 )
 
-// Service представляет доменный сервис для работы с кликами с batch processing
+// ParseOverflowPolicy преобразует строку конфигурации в политику переполнения
+func ParseOverflowPolicy(value string) (OverflowPolicy, error) {
+	switch policy := OverflowPolicy(value); policy {
+	case OverflowBlock, OverflowDropNewest, OverflowReject:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidOverflowPolicy, value)
+	}
+}
+
+// Options параметры конвейера сброса кликов
+type Options struct {
+	BatchSize      int            // максимальный размер батча
+	FlushInterval  time.Duration  // максимальное время ожидания неполного батча
+	QueueSize      int            // емкость очереди кликов
+	Workers        int            // количество параллельных воркеров сброса
+	OverflowPolicy OverflowPolicy // поведение при заполненной очереди
+	FlushTimeout   time.Duration  // таймаут записи одного батча в БД
+
+	// Повторные попытки записи батча с экспоненциальной задержкой и jitter
+	MaxRetries     int           // количество повторов после первой неудачной попытки
+	RetryBaseDelay time.Duration // задержка перед первым повтором
+	RetryMaxDelay  time.Duration // верхняя граница задержки
+
+	// DeadLetter получает батчи, которые не удалось сохранить после всех повторов (может быть nil)
+	DeadLetter DeadLetterQueue
+
+	// Дедупликация повторных кликов по отпечатку (баннер, IP, User-Agent)
+	DedupMode DedupMode  // off, suppress или flag
+	Dedup     DedupCache // хранилище отпечатков с окном дедупликации (nil - дедупликация отключена)
+
+	// Фильтр ботов (nil - отключен)
+	BotDetector BotDetector
+	BotAction   BotAction // flag или discard
+
+	// Publisher получает клики, принятые в очередь, включая помеченные дубликаты и клики ботов (может быть nil)
+	Publisher Publisher
+
+	// ErrorHandler вызывается при ошибке фонового сброса батча (может быть nil)
+	ErrorHandler func(err error, clicks []*Click)
+
+	// FlushObserver вызывается после каждой попытки записи батча в БД (может быть nil)
+	FlushObserver func(clicks int, duration time.Duration, err error)
+}
+
+// PipelineStats представляет счетчики конвейера сброса кликов
+type PipelineStats struct {
+	QueueDepth    int   `json:"queue_depth"`
+	QueueCapacity int   `json:"queue_capacity"`
+	InFlight      int64 `json:"in_flight"`
+	Enqueued      int64 `json:"enqueued"`
+	Dropped       int64 `json:"dropped"`
+	Duplicates    int64 `json:"duplicates"`
+	Bots          int64 `json:"bots"`
+	Rejected      int64 `json:"rejected"`
+	Flushed       int64 `json:"flushed"`
+	FlushErrors   int64 `json:"flush_errors"`
+	Retries       int64 `json:"retries"`
+	DeadLettered  int64 `json:"dead_lettered"`
+}
+
+// queuedClick клик в очереди вместе с сегментом журнала, в который он записан
+type queuedClick struct {
+	click     *Click
+	segmentID uint64
+}
+
+// pendingBatch батч, переданный воркерам сброса
+type pendingBatch struct {
+	clicks   []*Click
+	counters MinuteCounters // предагрегированные счетчики для таблицы статистики
+	segments map[uint64]int
+	waiter   *flushWaiter
+}
+
+// flushWaiter отслеживает завершение батчей, отправленных в рамках FlushPendingClicks
+type flushWaiter struct {
+	wg    sync.WaitGroup
+	mutex sync.Mutex
+	err   error
+}
+
+func (w *flushWaiter) done(err error) {
+	if err != nil {
+		w.mutex.Lock()
+		if w.err == nil {
+			w.err = err
+		}
+		w.mutex.Unlock()
+	}
+	w.wg.Done()
+}
+
+// Service представляет доменный сервис для работы с кликами.
+// Клики попадают в ограниченную очередь, откуда батчер собирает батчи
+// и передает их пулу воркеров; запрос не ждет записи в БД.
+// Вместе с батчем в памяти накапливаются счетчики по (баннер, минута),
+// которые записываются в статистику в одной транзакции с кликами
 type Service struct {
-	repo Repository
+	repo    Repository
+	journal Journal // опциональный журнал для защиты очереди от потери при падении
+	opts    Options
 
-	// Batch processing для highload
-	batchMutex    sync.Mutex
-	batchQueue    []*Click
-	batchSize     int
-	flushInterval time.Duration
-	flushTimer    *time.Timer
+	queue   chan queuedClick
+	slots   chan struct{} // семафор мест в очереди: занимается до записи в журнал
+	batches chan *pendingBatch
+	flushCh chan *flushWaiter
+	done    chan struct{}
+	wg      sync.WaitGroup
+
+	closeOnce sync.Once
+
+	// Количество батчей, переданных воркерам и еще не записанных
+	pendingMutex   sync.Mutex
+	pendingCond    *sync.Cond
+	pendingBatches int
+
+	// Учет ссылок на сегменты журнала: сегмент удаляется, когда он закрыт
+	// и все его клики сохранены в БД
+	journalMutex  sync.Mutex
+	segmentRefs   map[uint64]int
+	sealedSegment map[uint64]bool
+
+	// Счетчики
+	inFlight     int64
+	enqueued     int64
+	dropped      int64
+	duplicates   int64
+	bots         int64
+	rejected     int64
+	flushed      int64
+	flushErrors  int64
+	retries      int64
+	deadLettered int64
 }
 
-// NewService создает новый экземпляр сервиса кликов
-func NewService(repo Repository, batchSize int, flushInterval time.Duration) *Service {
+// NewService создает новый экземпляр сервиса кликов и запускает конвейер сброса.
+// journal может быть nil - тогда очередь кликов хранится только в памяти
+func NewService(repo Repository, journal Journal, opts Options) *Service {
 	// Устанавливаем разумные значения по умолчанию если переданы некорректные
-	if batchSize <= 0 {
-		batchSize = 100
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 2 * time.Second
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = opts.BatchSize * 10
 	}
-	if flushInterval <= 0 {
-		flushInterval = 2 * time.Second
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.OverflowPolicy == "" {
+		opts.OverflowPolicy = OverflowBlock
+	}
+	if opts.FlushTimeout <= 0 {
+		opts.FlushTimeout = 30 * time.Second
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+	if opts.RetryBaseDelay <= 0 {
+		opts.RetryBaseDelay = 100 * time.Millisecond
+	}
+	if opts.RetryMaxDelay < opts.RetryBaseDelay {
+		opts.RetryMaxDelay = opts.RetryBaseDelay
+	}
+	if opts.DedupMode == "" || opts.Dedup == nil {
+		opts.DedupMode = DedupOff
+	}
+	if opts.BotAction == "" {
+		opts.BotAction = BotFlag
 	}
 
-	return &Service{
+	s := &Service{
 		repo:          repo,
-		batchQueue:    make([]*Click, 0),
-		batchSize:     batchSize,
-		flushInterval: flushInterval,
+		journal:       journal,
+		opts:          opts,
+		queue:         make(chan queuedClick, opts.QueueSize),
+		slots:         make(chan struct{}, opts.QueueSize),
+		batches:       make(chan *pendingBatch, opts.Workers),
+		flushCh:       make(chan *flushWaiter),
+		done:          make(chan struct{}),
+		segmentRefs:   make(map[uint64]int),
+		sealedSegment: make(map[uint64]bool),
 	}
+	s.pendingCond = sync.NewCond(&s.pendingMutex)
+
+	s.wg.Add(1)
+	go s.runBatcher()
+
+	for i := 0; i < opts.Workers; i++ {
+		s.wg.Add(1)
+		go s.runWorker()
+	}
+
+	return s
 }
 
-// RegisterClick регистрирует новый клик с batch processing
+// RegisterClick регистрирует новый клик, помещая его в очередь на сброс
 func (s *Service) RegisterClick(ctx context.Context, bannerID int64, userIP, userAgent string) (*Click, error) {
 	click, err := NewClickWithMetadata(bannerID, userIP, userAgent)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create click: %w", err)
 	}
 
-	// Добавляем в batch queue для highload оптимизации
-	s.batchMutex.Lock()
-	defer s.batchMutex.Unlock()
+	select {
+	case <-s.done:
+		return nil, ErrServiceClosed
+	default:
+	}
 
-	s.batchQueue = append(s.batchQueue, click)
+	if s.opts.BotDetector != nil && s.opts.BotDetector.IsBot(userIP, userAgent) {
+		atomic.AddInt64(&s.bots, 1)
+		click.Bot = true
+		if s.opts.BotAction == BotDiscard {
+			return click, nil
+		}
+	}
 
-	// Если батч заполнен - сбрасываем немедленно
-	if len(s.batchQueue) >= s.batchSize {
-		if err := s.flushBatchUnsafe(ctx); err != nil {
-			return nil, fmt.Errorf("failed to flush batch: %w", err)
+	// Занимаем место в очереди до записи в журнал, чтобы отброшенный клик
+	// не был воспроизведен из журнала при следующем старте
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		switch s.opts.OverflowPolicy {
+		case OverflowDropNewest:
+			atomic.AddInt64(&s.dropped, 1)
+			return nil, ErrClickDropped
+		case OverflowReject:
+			atomic.AddInt64(&s.rejected, 1)
+			return nil, ErrQueueFull
+		default:
+			select {
+			case s.slots <- struct{}{}:
+			case <-ctx.Done():
+				return nil, fmt.Errorf("failed to enqueue click: %w", ctx.Err())
+			case <-s.done:
+				return nil, ErrServiceClosed
+			}
+		}
+	}
+
+	// Отпечаток проверяется после резервирования места: клик, отклоненный
+	// из-за переполнения, не подавит повторную попытку клиента
+	var fingerprint uint64
+	remembered := false
+	if s.opts.DedupMode != DedupOff {
+		fingerprint = Fingerprint(bannerID, userIP, userAgent)
+		// Ошибка хранилища отпечатков не должна терять клики, поэтому клик учитывается
+		seen, err := s.opts.Dedup.Seen(ctx, fingerprint)
+		remembered = err == nil && !seen
+		if err == nil && seen {
+			atomic.AddInt64(&s.duplicates, 1)
+			click.Duplicate = true
+			if s.opts.DedupMode == DedupSuppress {
+				<-s.slots
+				return click, nil
+			}
 		}
-	} else {
-		// Устанавливаем таймер для принудительного сброса
-		s.resetFlushTimer(ctx)
+	}
+
+	item := queuedClick{click: click}
+
+	// Фиксируем клик в журнале, чтобы он пережил падение процесса
+	if s.journal != nil {
+		s.journalMutex.Lock()
+		segmentID, err := s.journal.Append(click)
+		if err == nil {
+			s.segmentRefs[segmentID]++
+		}
+		s.journalMutex.Unlock()
+
+		if err != nil {
+			<-s.slots
+			// Отклоненный клик не должен подавить повторную попытку клиента
+			if remembered {
+				_ = s.opts.Dedup.Forget(ctx, fingerprint)
+			}
+			return nil, fmt.Errorf("failed to journal click: %w", err)
+		}
+		item.segmentID = segmentID
+	}
+
+	// Место зарезервировано, поэтому отправка не блокируется
+	s.queue <- item
+	atomic.AddInt64(&s.enqueued, 1)
+
+	if s.opts.Publisher != nil {
+		s.opts.Publisher.Publish(click)
 	}
 
 	return click, nil
 }
 
-// flushBatchUnsafe сбрасывает накопленные клики в БД (без мьютекса)
-func (s *Service) flushBatchUnsafe(ctx context.Context) error {
-	if len(s.batchQueue) == 0 {
+// FlushPendingClicks принудительно сбрасывает все накопленные клики и ждет завершения записи
+func (s *Service) FlushPendingClicks(ctx context.Context) error {
+	waiter := &flushWaiter{}
+	waiter.wg.Add(1) // удерживается батчером до отправки всех батчей
+
+	select {
+	case s.flushCh <- waiter:
+	case <-s.done:
+		return ErrServiceClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		waiter.wg.Wait()
+
+		// Дожидаемся и батчей, отправленных воркерам до запроса сброса
+		s.pendingMutex.Lock()
+		for s.pendingBatches > 0 {
+			s.pendingCond.Wait()
+		}
+		s.pendingMutex.Unlock()
+
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return waiter.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close останавливает конвейер. Клики, оставшиеся в очереди, не сбрасываются -
+// перед вызовом следует выполнить FlushPendingClicks
+func (s *Service) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+	})
+}
+
+// Stats возвращает текущие счетчики конвейера
+func (s *Service) Stats() PipelineStats {
+	return PipelineStats{
+		QueueDepth:    len(s.queue),
+		QueueCapacity: cap(s.queue),
+		InFlight:      atomic.LoadInt64(&s.inFlight),
+		Enqueued:      atomic.LoadInt64(&s.enqueued),
+		Dropped:       atomic.LoadInt64(&s.dropped),
+		Duplicates:    atomic.LoadInt64(&s.duplicates),
+		Bots:          atomic.LoadInt64(&s.bots),
+		Rejected:      atomic.LoadInt64(&s.rejected),
+		Flushed:       atomic.LoadInt64(&s.flushed),
+		FlushErrors:   atomic.LoadInt64(&s.flushErrors),
+		Retries:       atomic.LoadInt64(&s.retries),
+		DeadLettered:  atomic.LoadInt64(&s.deadLettered),
+	}
+}
+
+// ReplayJournal сохраняет в БД клики из сегментов журнала, не сброшенных предыдущим запуском.
+// Должен вызываться до начала приема трафика; возвращает количество восстановленных кликов
+func (s *Service) ReplayJournal(ctx context.Context) (int, error) {
+	if s.journal == nil {
+		return 0, nil
+	}
+
+	replayed := 0
+	err := s.journal.Replay(func(segmentID uint64, clicks []*Click) error {
+		// Процесс мог упасть между коммитом батча и освобождением сегмента
+		unsaved, err := s.repo.FilterUnsaved(ctx, clicks)
+		if err != nil {
+			return fmt.Errorf("failed to check replayed clicks from segment %d: %w", segmentID, err)
+		}
+
+		if len(unsaved) > 0 {
+			if err := s.repo.CreateBatchWithCounters(ctx, unsaved, CountByMinute(unsaved)); err != nil {
+				return fmt.Errorf("failed to save replayed clicks from segment %d: %w", segmentID, err)
+			}
+		}
+
+		if err := s.journal.Release(segmentID); err != nil {
+			return fmt.Errorf("failed to release replayed segment %d: %w", segmentID, err)
+		}
+
+		replayed += len(unsaved)
+		return nil
+	})
+
+	return replayed, err
+}
+
+// runBatcher собирает клики из очереди в батчи по размеру или по таймеру
+func (s *Service) runBatcher() {
+	defer s.wg.Done()
+
+	batch := s.newBatch()
+	timer := time.NewTimer(s.opts.FlushInterval)
+	timer.Stop()
+	timerActive := false
+
+	stopTimer := func() {
+		if timerActive && !timer.Stop() {
+			<-timer.C
+		}
+		timerActive = false
+	}
+
+	add := func(item queuedClick) {
+		<-s.slots
+		batch.clicks = append(batch.clicks, item.click)
+		batch.counters.Add(item.click)
+		if item.segmentID != 0 {
+			batch.segments[item.segmentID]++
+		}
+	}
+
+	dispatch := func(waiter *flushWaiter) bool {
+		stopTimer()
+		if len(batch.clicks) == 0 {
+			return true
+		}
+
+		s.sealJournalSegment()
+
+		batch.waiter = waiter
+		if waiter != nil {
+			waiter.wg.Add(1)
+		}
+		s.addPendingBatches(1)
+
+		select {
+		case s.batches <- batch:
+		case <-s.done:
+			s.addPendingBatches(-1)
+			if waiter != nil {
+				waiter.done(ErrServiceClosed)
+			}
+			return false
+		}
+
+		batch = s.newBatch()
+		return true
+	}
+
+	for {
+		select {
+		case item := <-s.queue:
+			add(item)
+			if len(batch.clicks) >= s.opts.BatchSize {
+				if !dispatch(nil) {
+					return
+				}
+			} else if !timerActive {
+				timer.Reset(s.opts.FlushInterval)
+				timerActive = true
+			}
+
+		case <-timer.C:
+			timerActive = false
+			if !dispatch(nil) {
+				return
+			}
+
+		case waiter := <-s.flushCh:
+			// Забираем все, что уже лежит в очереди, и отправляем батчами
+			ok := true
+		drain:
+			for ok {
+				select {
+				case item := <-s.queue:
+					add(item)
+					if len(batch.clicks) >= s.opts.BatchSize {
+						ok = dispatch(waiter)
+					}
+				default:
+					break drain
+				}
+			}
+			if ok {
+				ok = dispatch(waiter)
+			}
+			waiter.done(nil)
+			if !ok {
+				return
+			}
+
+		case <-s.done:
+			stopTimer()
+			return
+		}
+	}
+}
+
+// runWorker записывает батчи в БД
+func (s *Service) runWorker() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case batch := <-s.batches:
+			s.flushBatch(batch)
+		case <-s.done:
+			// Дописываем батчи, уже переданные батчером
+			for {
+				select {
+				case batch := <-s.batches:
+					s.flushBatch(batch)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// flushBatch сохраняет батч в БД с повторами, а при исчерпании повторов - в dead-letter
+func (s *Service) flushBatch(batch *pendingBatch) {
+	atomic.AddInt64(&s.inFlight, 1)
+	defer atomic.AddInt64(&s.inFlight, -1)
+
+	err := s.saveWithRetry(batch)
+	if err == nil {
+		atomic.AddInt64(&s.flushed, int64(len(batch.clicks)))
+		err = s.ackJournalSegments(batch.segments)
+		if err != nil && s.opts.ErrorHandler != nil {
+			s.opts.ErrorHandler(err, nil)
+		}
+	} else {
+		atomic.AddInt64(&s.flushErrors, 1)
+		err = s.deadLetter(batch, err)
+		if s.opts.ErrorHandler != nil {
+			s.opts.ErrorHandler(err, batch.clicks)
+		}
+	}
+
+	if batch.waiter != nil {
+		batch.waiter.done(err)
+	}
+	s.addPendingBatches(-1)
+}
+
+// saveWithRetry записывает батч в БД, повторяя попытки с экспоненциальной задержкой
+func (s *Service) saveWithRetry(batch *pendingBatch) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(tenant.WithSystem(context.Background()), s.opts.FlushTimeout)
+		start := time.Now()
+		if attempt == 0 {
+			err = s.repo.CreateBatchWithCounters(ctx, batch.clicks, batch.counters)
+		} else {
+			err = s.saveUnsaved(ctx, batch.clicks)
+		}
+		cancel()
+
+		if s.opts.FlushObserver != nil {
+			s.opts.FlushObserver(len(batch.clicks), time.Since(start), err)
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= s.opts.MaxRetries {
+			return fmt.Errorf("failed to save batch clicks after %d attempts: %w", attempt+1, err)
+		}
+
+		atomic.AddInt64(&s.retries, 1)
+
+		// При остановке сервиса не ждем - сразу переходим к dead-letter
+		select {
+		case <-time.After(s.retryDelay(attempt)):
+		case <-s.done:
+			return fmt.Errorf("failed to save batch clicks, retries aborted by shutdown: %w", err)
+		}
+	}
+}
+
+// saveUnsaved сохраняет клики батча, которых еще нет в БД. Ошибка предыдущей попытки
+// могла прийти после успешного коммита (например, обрыв соединения во время COMMIT)
+func (s *Service) saveUnsaved(ctx context.Context, clicks []*Click) error {
+	unsaved, err := s.repo.FilterUnsaved(ctx, clicks)
+	if err != nil {
+		return err
+	}
+	if len(unsaved) == 0 {
 		return nil
 	}
+	return s.repo.CreateBatchWithCounters(ctx, unsaved, CountByMinute(unsaved))
+}
 
-	// Копируем батч для сброса
-	batchToFlush := make([]*Click, len(s.batchQueue))
-	copy(batchToFlush, s.batchQueue)
+// retryDelay возвращает задержку перед повтором (full jitter)
+func (s *Service) retryDelay(attempt int) time.Duration {
+	delay := s.opts.RetryMaxDelay
+	if attempt < 32 {
+		if backoff := s.opts.RetryBaseDelay << attempt; backoff > 0 && backoff < delay {
+			delay = backoff
+		}
+	}
+	return time.Duration(rand.Int64N(int64(delay))) + 1
+}
 
-	// Очищаем очередь
-	s.batchQueue = s.batchQueue[:0]
+// deadLetter сохраняет несохраненный батч в dead-letter. После успешной записи
+// сегменты журнала освобождаются: клики уже надежно лежат на диске
+func (s *Service) deadLetter(batch *pendingBatch, saveErr error) error {
+	if s.opts.DeadLetter == nil {
+		// Сегменты журнала остаются на диске и будут воспроизведены при старте
+		return saveErr
+	}
 
-	// Останавливаем таймер
-	if s.flushTimer != nil {
-		s.flushTimer.Stop()
-		s.flushTimer = nil
+	if err := s.opts.DeadLetter.Write(batch.clicks); err != nil {
+		return errors.Join(saveErr, fmt.Errorf("failed to write dead-letter batch: %w", err))
 	}
 
-	// Сбрасываем в БД
-	if err := s.repo.CreateBatch(ctx, batchToFlush); err != nil {
-		return fmt.Errorf("failed to save batch clicks: %w", err)
+	atomic.AddInt64(&s.deadLettered, int64(len(batch.clicks)))
+
+	if err := s.ackJournalSegments(batch.segments); err != nil {
+		return errors.Join(saveErr, err)
 	}
 
-	return nil
+	return fmt.Errorf("batch dead-lettered: %w", saveErr)
 }
 
-// resetFlushTimer устанавливает/сбрасывает таймер для принудительного сброса
-func (s *Service) resetFlushTimer(ctx context.Context) {
-	if s.flushTimer != nil {
-		s.flushTimer.Stop()
+// addPendingBatches изменяет счетчик незаписанных батчей
+func (s *Service) addPendingBatches(delta int) {
+	s.pendingMutex.Lock()
+	s.pendingBatches += delta
+	if s.pendingBatches == 0 {
+		s.pendingCond.Broadcast()
 	}
+	s.pendingMutex.Unlock()
+}
 
-	s.flushTimer = time.AfterFunc(s.flushInterval, func() {
-		s.batchMutex.Lock()
-		defer s.batchMutex.Unlock()
-		_ = s.flushBatchUnsafe(ctx)
-	})
+// sealJournalSegment закрывает текущий сегмент журнала при формировании батча
+func (s *Service) sealJournalSegment() {
+	if s.journal == nil {
+		return
+	}
+
+	s.journalMutex.Lock()
+	defer s.journalMutex.Unlock()
+
+	segmentID, err := s.journal.Seal()
+	if err != nil {
+		// Сегмент останется активным и будет закрыт при следующем батче
+		if s.opts.ErrorHandler != nil {
+			s.opts.ErrorHandler(fmt.Errorf("failed to seal click journal segment: %w", err), nil)
+		}
+		return
+	}
+
+	if segmentID == 0 {
+		return
+	}
+
+	s.sealedSegment[segmentID] = true
+	if s.segmentRefs[segmentID] == 0 {
+		if err := s.releaseSegmentUnsafe(segmentID); err != nil && s.opts.ErrorHandler != nil {
+			s.opts.ErrorHandler(err, nil)
+		}
+	}
 }
 
-// FlushPendingClicks принудительно сбрасывает все накопленные клики
-func (s *Service) FlushPendingClicks(ctx context.Context) error {
-	s.batchMutex.Lock()
-	defer s.batchMutex.Unlock()
-	return s.flushBatchUnsafe(ctx)
+// ackJournalSegments уменьшает счетчики ссылок на сегменты сохраненного батча
+func (s *Service) ackJournalSegments(segments map[uint64]int) error {
+	if s.journal == nil || len(segments) == 0 {
+		return nil
+	}
+
+	s.journalMutex.Lock()
+	defer s.journalMutex.Unlock()
+
+	var errs []error
+	for segmentID, count := range segments {
+		s.segmentRefs[segmentID] -= count
+		if s.segmentRefs[segmentID] <= 0 && s.sealedSegment[segmentID] {
+			if err := s.releaseSegmentUnsafe(segmentID); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// releaseSegmentUnsafe удаляет сегмент журнала (без мьютекса)
+func (s *Service) releaseSegmentUnsafe(segmentID uint64) error {
+	delete(s.segmentRefs, segmentID)
+	delete(s.sealedSegment, segmentID)
+
+	if err := s.journal.Release(segmentID); err != nil {
+		return fmt.Errorf("failed to release click journal segment: %w", err)
+	}
+
+	return nil
+}
+
+// newBatch создает пустой батч
+func (s *Service) newBatch() *pendingBatch {
+	return &pendingBatch{
+		clicks:   make([]*Click, 0, s.opts.BatchSize),
+		counters: make(MinuteCounters),
+		segments: make(map[uint64]int),
+	}
 }
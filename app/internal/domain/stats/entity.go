@@ -2,7 +2,10 @@ package stats
 
 import (
 	"errors"
+	"sort"
 	"time"
+
+	"github.com/clickcounter/app/pkg/hll"
 )
 
 // Stat представляет статистику кликов за определенный период
@@ -21,18 +24,67 @@ type StatPeriod struct {
 	To   time.Time `json:"to"`
 }
 
+// QueryOptions параметры выборки статистики
+type QueryOptions struct {
+	IncludeBots bool        `json:"include_bots"` // учитывать клики, помеченные фильтром ботов
+	Uniques     bool        `json:"uniques"`      // оценивать количество уникальных посетителей
+	Granularity Granularity `json:"granularity"`  // размер бакета (пустое значение - минута)
+}
+
+// BucketGranularity возвращает размер бакета с учетом значения по умолчанию
+func (o QueryOptions) BucketGranularity() Granularity {
+	if o.Granularity == "" {
+		return GranularityMinute
+	}
+	return o.Granularity
+}
+
+// CacheKey возвращает часть ключа кэша, различающую варианты выборки
+func (o QueryOptions) CacheKey() string {
+	key := "human"
+	if o.IncludeBots {
+		key = "bots"
+	}
+	if o.Uniques {
+		key += ":u"
+	}
+	return key
+}
+
 // StatsResponse представляет ответ с агрегированной статистикой
 type StatsResponse struct {
-	BannerID int64         `json:"banner_id"`
-	Period   StatPeriod    `json:"period"`
-	Stats    []*MinuteStat `json:"stats"`
-	Total    int64         `json:"total"`
+	BannerID    int64         `json:"banner_id"`
+	Period      StatPeriod    `json:"period"`
+	Granularity Granularity   `json:"granularity"`
+	Stats       []*BucketStat `json:"stats"`
+	Total       int64         `json:"total"`
+	Uniques     *int64        `json:"uniques,omitempty"` // уникальные посетители за весь период
+}
+
+// MultiStatsResponse представляет статистику набора баннеров за общий период
+type MultiStatsResponse struct {
+	Period      StatPeriod       `json:"period"`
+	Granularity Granularity      `json:"granularity"`
+	Banners     []*StatsResponse `json:"banners"` // по возрастанию ID, в том числе баннеры без кликов
+	Total       int64            `json:"total"`   // сумма кликов по всем баннерам
 }
 
-// MinuteStat представляет статистику за одну минуту
-type MinuteStat struct {
-	Timestamp time.Time `json:"ts"`
+// BucketStat представляет статистику за один бакет (минуту, час, день и т.д.)
+type BucketStat struct {
+	Timestamp time.Time `json:"ts"` // начало бакета
 	Value     int64     `json:"v"`
+	Uniques   *int64    `json:"u,omitempty"` // оценка уникальных посетителей (только при QueryOptions.Uniques)
+
+	// Visitors скетч посетителей бакета для слияния по периоду
+	Visitors *hll.Sketch `json:"-"`
+}
+
+// AggregationResult представляет результат инкрементальной агрегации кликов
+type AggregationResult struct {
+	FromClickID  int64 `json:"from_click_id"` // high-water mark до прохода (не включительно)
+	ToClickID    int64 `json:"to_click_id"`   // high-water mark после прохода (включительно)
+	RowsAffected int64 `json:"rows_affected"` // количество затронутых строк статистики
+	HasMore      bool  `json:"has_more"`      // остались ли неагрегированные клики
 }
 
 // Доменные ошибки
@@ -43,13 +95,45 @@ var (
 	ErrStatNotFound     = errors.New("stat not found")
 	ErrPeriodTooLarge   = errors.New("time period is too large")
 	ErrNegativeCount    = errors.New("count cannot be negative")
+	ErrNoBanners        = errors.New("at least one banner ID is required")
+	ErrTooManyBanners   = errors.New("too many banners requested")
 )
 
 // Константы для валидации
 const (
-	MaxPeriodDays = 30 // Максимальный период запроса в днях
+	MaxPeriodDays       = 30   // Максимальный период запроса в днях для минутных бакетов
+	MaxHourlyPeriodDays = 366  // Максимальный период запроса в днях для часовых бакетов
+	MaxDailyPeriodDays  = 3660 // Максимальный период запроса в днях для дневных и недельных бакетов
+
+	MaxBannersPerQuery = 500 // Максимум баннеров в одном запросе статистики набора
+
+	// MaxExportPeriodDays максимальный период потоковой выгрузки в днях: выгрузка не
+	// накапливается в памяти, поэтому минутные бакеты допускают больший период, чем ответ JSON
+	MaxExportPeriodDays = 366
 )
 
+// MaxPeriod возвращает максимальный период запроса для размера бакета.
+// Крупные бакеты читаются из свернутой статистики, поэтому допускают больший период
+func MaxPeriod(granularity Granularity) time.Duration {
+	days := MaxPeriodDays
+	switch granularity {
+	case GranularityHour:
+		days = MaxHourlyPeriodDays
+	case GranularityDay, GranularityWeek:
+		days = MaxDailyPeriodDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// MaxExportPeriod возвращает максимальный период потоковой выгрузки для размера бакета
+func MaxExportPeriod(granularity Granularity) time.Duration {
+	maxPeriod := MaxPeriod(granularity)
+	if exportPeriod := time.Duration(MaxExportPeriodDays) * 24 * time.Hour; exportPeriod > maxPeriod {
+		return exportPeriod
+	}
+	return maxPeriod
+}
+
 // NewStat создает новую статистику с валидацией
 func NewStat(bannerID int64, timestamp time.Time, count int64) (*Stat, error) {
 	if bannerID <= 0 {
@@ -74,8 +158,18 @@ func NewStat(bannerID int64, timestamp time.Time, count int64) (*Stat, error) {
 	}, nil
 }
 
-// NewStatPeriod создает новый период с валидацией
-func NewStatPeriod(from, to time.Time) (*StatPeriod, error) {
+// NewStatPeriod создает новый период с валидацией длины для размера бакета
+func NewStatPeriod(from, to time.Time, granularity Granularity) (*StatPeriod, error) {
+	return newStatPeriod(from, to, MaxPeriod(granularity))
+}
+
+// NewExportPeriod создает период потоковой выгрузки с валидацией длины для размера бакета
+func NewExportPeriod(from, to time.Time, granularity Granularity) (*StatPeriod, error) {
+	return newStatPeriod(from, to, MaxExportPeriod(granularity))
+}
+
+// newStatPeriod создает период не длиннее maxPeriod
+func newStatPeriod(from, to time.Time, maxPeriod time.Duration) (*StatPeriod, error) {
 	if from.IsZero() || to.IsZero() {
 		return nil, ErrInvalidTimestamp
 	}
@@ -85,7 +179,7 @@ func NewStatPeriod(from, to time.Time) (*StatPeriod, error) {
 	}
 
 	// Проверяем, что период не слишком большой
-	if to.Sub(from) > time.Duration(MaxPeriodDays)*24*time.Hour {
+	if to.Sub(from) > maxPeriod {
 		return nil, ErrPeriodTooLarge
 	}
 
@@ -95,14 +189,52 @@ func NewStatPeriod(from, to time.Time) (*StatPeriod, error) {
 	}, nil
 }
 
-// NewMinuteStat создает статистику за минуту
-func NewMinuteStat(timestamp time.Time, value int64) *MinuteStat {
-	return &MinuteStat{
-		Timestamp: timestamp.Truncate(time.Minute),
+// NormalizeBannerIDs возвращает упорядоченные ID без повторов: одинаковые наборы
+// баннеров в разном порядке читаются одним запросом и попадают в один ключ кэша
+func NormalizeBannerIDs(bannerIDs []int64) ([]int64, error) {
+	if len(bannerIDs) == 0 {
+		return nil, ErrNoBanners
+	}
+
+	normalized := make([]int64, 0, len(bannerIDs))
+	seen := make(map[int64]struct{}, len(bannerIDs))
+	for _, id := range bannerIDs {
+		if id <= 0 {
+			return nil, ErrInvalidBannerID
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		normalized = append(normalized, id)
+	}
+
+	if len(normalized) > MaxBannersPerQuery {
+		return nil, ErrTooManyBanners
+	}
+
+	sort.Slice(normalized, func(i, j int) bool { return normalized[i] < normalized[j] })
+	return normalized, nil
+}
+
+// NewBucketStat создает статистику за бакет, содержащий timestamp
+func NewBucketStat(granularity Granularity, timestamp time.Time, value int64) *BucketStat {
+	return &BucketStat{
+		Timestamp: granularity.Truncate(timestamp),
 		Value:     value,
 	}
 }
 
+// SetVisitors задает скетч посетителей бакета и оценку уникальных посетителей по нему
+func (bs *BucketStat) SetVisitors(sketch *hll.Sketch) {
+	bs.Visitors = sketch
+	uniques := int64(0)
+	if sketch != nil {
+		uniques = sketch.Estimate()
+	}
+	bs.Uniques = &uniques
+}
+
 // IsValid проверяет валидность статистики
 func (s *Stat) IsValid() error {
 	if s.BannerID <= 0 {
@@ -129,15 +261,25 @@ func (sr *StatsResponse) CalculateTotal() {
 	sr.Total = total
 }
 
-// SortByTimestamp сортирует статистику по времени
-func (sr *StatsResponse) SortByTimestamp() {
-	// Простая сортировка пузырьком (для небольших массивов)
-	n := len(sr.Stats)
-	for i := 0; i < n-1; i++ {
-		for j := 0; j < n-i-1; j++ {
-			if sr.Stats[j].Timestamp.After(sr.Stats[j+1].Timestamp) {
-				sr.Stats[j], sr.Stats[j+1] = sr.Stats[j+1], sr.Stats[j]
-			}
+// CalculateUniques оценивает уникальных посетителей за период слиянием скетчей бакетов.
+// Сумма оценок по бакетам завысила бы результат для посетителей, кликавших в разные минуты
+func (sr *StatsResponse) CalculateUniques() error {
+	merged := hll.NewDefault()
+	for _, stat := range sr.Stats {
+		if err := merged.Merge(stat.Visitors); err != nil {
+			return err
 		}
 	}
+
+	uniques := merged.Estimate()
+	sr.Uniques = &uniques
+	return nil
+}
+
+// SortByTimestamp сортирует статистику по времени. Ответ по набору баннеров содержит
+// тысячи бакетов на баннер, поэтому квадратичная сортировка не подходит
+func (sr *StatsResponse) SortByTimestamp() {
+	sort.SliceStable(sr.Stats, func(i, j int) bool {
+		return sr.Stats[i].Timestamp.Before(sr.Stats[j].Timestamp)
+	})
 }
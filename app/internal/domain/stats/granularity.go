@@ -0,0 +1,66 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Granularity размер бакета, по которому группируется статистика
+type Granularity string
+
+// Поддерживаемые размеры бакетов
+const (
+	GranularityMinute     Granularity = "minute"
+	GranularityFiveMinute Granularity = "5m"
+	GranularityHour       Granularity = "hour"
+	GranularityDay        Granularity = "day"
+	GranularityWeek       Granularity = "week"
+)
+
+// ErrInvalidGranularity возвращается при неизвестном размере бакета
+var ErrInvalidGranularity = errors.New("invalid granularity")
+
+// ParseGranularity разбирает размер бакета. Пустая строка - поминутная статистика
+func ParseGranularity(value string) (Granularity, error) {
+	switch g := Granularity(value); g {
+	case "":
+		return GranularityMinute, nil
+	case GranularityMinute, GranularityFiveMinute, GranularityHour, GranularityDay, GranularityWeek:
+		return g, nil
+	default:
+		return "", fmt.Errorf("%w: %q (expected minute, 5m, hour, day or week)", ErrInvalidGranularity, value)
+	}
+}
+
+// Duration возвращает длительность бакета
+func (g Granularity) Duration() time.Duration {
+	switch g {
+	case GranularityFiveMinute:
+		return 5 * time.Minute
+	case GranularityHour:
+		return time.Hour
+	case GranularityDay:
+		return 24 * time.Hour
+	case GranularityWeek:
+		return 7 * 24 * time.Hour
+	default:
+		return time.Minute
+	}
+}
+
+// Truncate возвращает начало бакета, содержащего t (в UTC; недели начинаются с понедельника,
+// как date_trunc('week') в PostgreSQL)
+func (g Granularity) Truncate(t time.Time) time.Time {
+	t = t.UTC()
+	switch g {
+	case GranularityDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case GranularityWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	default:
+		return t.Truncate(g.Duration())
+	}
+}
@@ -7,24 +7,71 @@ import (
 
 // Repository определяет интерфейс для работы со статистикой
 type Repository interface {
-	// GetAggregatedStats возвращает агрегированную статистику по минутам
-	GetAggregatedStats(ctx context.Context, bannerID int64, from, to time.Time) ([]*MinuteStat, error)
+	// GetAggregatedStats возвращает статистику, сгруппированную по бакетам opts.Granularity
+	GetAggregatedStats(ctx context.Context, bannerID int64, from, to time.Time, opts QueryOptions) ([]*BucketStat, error)
 
-	// IncrementCount увеличивает счетчик для определенной минуты
-	IncrementCount(ctx context.Context, bannerID int64, timestamp time.Time, delta int64) error
+	// GetAggregatedStatsForBanners возвращает статистику нескольких баннеров одним запросом,
+	// сгруппированную по баннерам. Баннеры без кликов за период в результат не попадают
+	GetAggregatedStatsForBanners(ctx context.Context, bannerIDs []int64, from, to time.Time, opts QueryOptions) (map[int64][]*BucketStat, error)
+
+	// StreamAggregatedStats передает статистику баннера в fn по бакетам в порядке времени
+	// по мере чтения из БД, не накапливая ее в памяти. Ошибка fn прерывает выгрузку
+	StreamAggregatedStats(ctx context.Context, bannerID int64, from, to time.Time, opts QueryOptions, fn func(*BucketStat) error) error
 }
 
 // AggregationRepository определяет интерфейс для агрегации данных из кликов
 type AggregationRepository interface {
-	// AggregateClicksForBanner агрегирует клики для конкретного баннера
+	// AggregateClicksForBanner агрегирует клики для конкретного баннера.
+	// Реализация должна быть идемпотентной: повторный вызов по тому же окну не меняет статистику
 	AggregateClicksForBanner(ctx context.Context, bannerID int64, from, to time.Time) error
 }
 
+// IncrementalAggregationRepository определяет интерфейс для фоновой агрегации новых кликов
+type IncrementalAggregationRepository interface {
+	// AggregatePendingClicks переносит в статистику клики с ID больше сохраненного high-water mark.
+	// limit ограничивает количество ID, обрабатываемых за один проход (0 - без ограничения)
+	AggregatePendingClicks(ctx context.Context, limit int64) (*AggregationResult, error)
+
+	// GetAggregatedClickID возвращает high-water mark: клики с ID не больше него учтены в статистике
+	GetAggregatedClickID(ctx context.Context) (int64, error)
+}
+
+// RollupRepository определяет интерфейс для сворачивания статистики в часовые и дневные бакеты
+type RollupRepository interface {
+	// RollupStats пересчитывает бакеты уровня, исходные данные которых изменились с прошлого прохода.
+	// Изменения за последние settleDelay откладываются до следующего прохода: транзакции,
+	// начатые раньше границы, могут быть еще не закоммичены, поэтому settleDelay должен
+	// превышать самую долгую транзакцию записи статистики. Бакеты, начинающиеся раньше
+	// sourceRetention (срок хранения исходной таблицы, 0 - бессрочно), не пересчитываются:
+	// часть их исходных строк уже удалена. batchSize ограничивает количество бакетов,
+	// пересчитываемых одним запросом
+	RollupStats(ctx context.Context, level RollupLevel, settleDelay, sourceRetention time.Duration, batchSize int) (*RollupResult, error)
+
+	// GetRollupBoundaries возвращает по уровням границу, раньше которой бакеты свернуты
+	GetRollupBoundaries(ctx context.Context) (map[RollupLevel]time.Time, error)
+}
+
+// RetentionRepository определяет интерфейс удаления устаревшей статистики.
+// Методы удаляют не более limit строк за вызов, чтобы не удерживать блокировки долго
+type RetentionRepository interface {
+	// DeleteOldStats удаляет минутную статистику старше before
+	DeleteOldStats(ctx context.Context, before time.Time, limit int) (int64, error)
+
+	// DeleteOldRollups удаляет свернутую статистику уровня старше before
+	DeleteOldRollups(ctx context.Context, level RollupLevel, before time.Time, limit int) (int64, error)
+}
+
 // CacheRepository определяет интерфейс для кэширования статистики
 type CacheRepository interface {
 	// GetStats возвращает статистику из кэша
-	GetStats(ctx context.Context, bannerID int64, from, to time.Time) (*StatsResponse, error)
+	GetStats(ctx context.Context, bannerID int64, from, to time.Time, opts QueryOptions) (*StatsResponse, error)
 
 	// SetStats сохраняет статистику в кэш
-	SetStats(ctx context.Context, bannerID int64, from, to time.Time, stats *StatsResponse) error
+	SetStats(ctx context.Context, bannerID int64, from, to time.Time, opts QueryOptions, stats *StatsResponse) error
+
+	// GetMultiStats возвращает из кэша статистику набора баннеров (bannerIDs упорядочены)
+	GetMultiStats(ctx context.Context, bannerIDs []int64, from, to time.Time, opts QueryOptions) (*MultiStatsResponse, error)
+
+	// SetMultiStats сохраняет в кэш статистику набора баннеров (bannerIDs упорядочены)
+	SetMultiStats(ctx context.Context, bannerIDs []int64, from, to time.Time, opts QueryOptions, stats *MultiStatsResponse) error
 }
@@ -0,0 +1,31 @@
+package stats
+
+import "time"
+
+// RollupLevel уровень свернутой статистики
+type RollupLevel string
+
+// Уровни свертки. Часовая статистика сворачивается из минутной, дневная - из часовой
+const (
+	RollupHourly RollupLevel = "hourly"
+	RollupDaily  RollupLevel = "daily"
+)
+
+// RollupLevels уровни в порядке свертки
+var RollupLevels = []RollupLevel{RollupHourly, RollupDaily}
+
+// BucketSize возвращает длительность бакета уровня
+func (l RollupLevel) BucketSize() time.Duration {
+	if l == RollupDaily {
+		return 24 * time.Hour
+	}
+	return time.Hour
+}
+
+// RollupResult представляет результат прохода свертки одного уровня
+type RollupResult struct {
+	Level          RollupLevel `json:"level"`
+	Buckets        int64       `json:"buckets"`         // количество пересчитанных бакетов
+	CompleteBefore time.Time   `json:"complete_before"` // бакеты раньше этой границы свернуты
+	Skipped        bool        `json:"skipped"`         // уровень сворачивается другим экземпляром
+}
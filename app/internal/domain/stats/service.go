@@ -9,83 +9,139 @@ import (
 // Service представляет доменный сервис для работы со статистикой
 type Service struct {
 	repo      Repository
-	aggRepo   AggregationRepository
 	cacheRepo CacheRepository
 	cacheTTL  time.Duration
 }
 
 // NewService создает новый экземпляр сервиса статистики
-func NewService(repo Repository, aggRepo AggregationRepository, cacheRepo CacheRepository) *Service {
+func NewService(repo Repository, cacheRepo CacheRepository) *Service {
 	return &Service{
 		repo:      repo,
-		aggRepo:   aggRepo,
 		cacheRepo: cacheRepo,
 		cacheTTL:  5 * time.Minute, // TTL кэша по умолчанию
 	}
 }
 
 // GetStats возвращает статистику кликов по баннеру за период
-func (s *Service) GetStats(ctx context.Context, bannerID int64, from, to time.Time) (*StatsResponse, error) {
+func (s *Service) GetStats(ctx context.Context, bannerID int64, from, to time.Time, opts QueryOptions) (*StatsResponse, error) {
 	if bannerID <= 0 {
 		return nil, ErrInvalidBannerID
 	}
 
-	period, err := NewStatPeriod(from, to)
+	period, err := NewStatPeriod(from, to, opts.BucketGranularity())
 	if err != nil {
 		return nil, fmt.Errorf("invalid period: %w", err)
 	}
 
 	// Пытаемся получить из кэша
 	if s.cacheRepo != nil {
-		if cached, err := s.cacheRepo.GetStats(ctx, bannerID, from, to); err == nil && cached != nil {
+		if cached, err := s.cacheRepo.GetStats(ctx, bannerID, from, to, opts); err == nil && cached != nil {
 			return cached, nil
 		}
 	}
 
-	// Получаем агрегированную статистику
-	minuteStats, err := s.repo.GetAggregatedStats(ctx, bannerID, from, to)
+	// Получаем статистику, сгруппированную по бакетам
+	bucketStats, err := s.repo.GetAggregatedStats(ctx, bannerID, from, to, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get aggregated stats: %w", err)
 	}
 
 	// Формируем ответ
 	response := &StatsResponse{
-		BannerID: bannerID,
-		Period:   *period,
-		Stats:    minuteStats,
+		BannerID:    bannerID,
+		Period:      *period,
+		Granularity: opts.BucketGranularity(),
+		Stats:       bucketStats,
 	}
 
 	// Вычисляем общую сумму
 	response.CalculateTotal()
 
+	if opts.Uniques {
+		if err := response.CalculateUniques(); err != nil {
+			return nil, fmt.Errorf("failed to merge visitors sketches: %w", err)
+		}
+	}
+
 	// Сортируем по времени
 	response.SortByTimestamp()
 
 	// Сохраняем в кэш
 	if s.cacheRepo != nil {
-		_ = s.cacheRepo.SetStats(ctx, bannerID, from, to, response)
+		_ = s.cacheRepo.SetStats(ctx, bannerID, from, to, opts, response)
 	}
 
 	return response, nil
 }
 
-// GetStatsWithFallback возвращает статистику с fallback на агрегацию кликов
-func (s *Service) GetStatsWithFallback(ctx context.Context, bannerID int64, from, to time.Time) (*StatsResponse, error) {
-	// Сначала пытаемся получить готовую статистику
-	response, err := s.GetStats(ctx, bannerID, from, to)
-	if err == nil && len(response.Stats) > 0 {
-		return response, nil
+// ExportStats передает статистику баннера в fn по бакетам в порядке времени, читая ее из БД
+// потоком. Кэш не используется: выгрузки редки и велики. Существование баннера проверяет
+// вызывающий код
+func (s *Service) ExportStats(ctx context.Context, bannerID int64, from, to time.Time, opts QueryOptions, fn func(*BucketStat) error) error {
+	if bannerID <= 0 {
+		return ErrInvalidBannerID
+	}
+
+	if _, err := NewExportPeriod(from, to, opts.BucketGranularity()); err != nil {
+		return fmt.Errorf("invalid period: %w", err)
+	}
+
+	return s.repo.StreamAggregatedStats(ctx, bannerID, from, to, opts, fn)
+}
+
+// GetStatsForBanners возвращает статистику набора баннеров за период одним запросом к БД.
+// Существование баннеров проверяет вызывающий код
+func (s *Service) GetStatsForBanners(ctx context.Context, bannerIDs []int64, from, to time.Time, opts QueryOptions) (*MultiStatsResponse, error) {
+	bannerIDs, err := NormalizeBannerIDs(bannerIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	period, err := NewStatPeriod(from, to, opts.BucketGranularity())
+	if err != nil {
+		return nil, fmt.Errorf("invalid period: %w", err)
 	}
 
-	// Если статистики нет, агрегируем из кликов
-	if s.aggRepo != nil {
-		if err := s.aggRepo.AggregateClicksForBanner(ctx, bannerID, from, to); err != nil {
-			return nil, fmt.Errorf("failed to aggregate clicks: %w", err)
+	// Кэш хранит ответ для всего набора баннеров
+	if s.cacheRepo != nil {
+		if cached, err := s.cacheRepo.GetMultiStats(ctx, bannerIDs, from, to, opts); err == nil && cached != nil {
+			return cached, nil
 		}
+	}
+
+	byBanner, err := s.repo.GetAggregatedStatsForBanners(ctx, bannerIDs, from, to, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get aggregated stats: %w", err)
+	}
 
-		// Повторно получаем статистику
-		return s.GetStats(ctx, bannerID, from, to)
+	response := &MultiStatsResponse{
+		Period:      *period,
+		Granularity: opts.BucketGranularity(),
+		Banners:     make([]*StatsResponse, 0, len(bannerIDs)),
 	}
+	for _, bannerID := range bannerIDs {
+		bannerStats := &StatsResponse{
+			BannerID:    bannerID,
+			Period:      *period,
+			Granularity: opts.BucketGranularity(),
+			Stats:       byBanner[bannerID],
+		}
+		bannerStats.CalculateTotal()
 
-	return response, err
+		if opts.Uniques {
+			if err := bannerStats.CalculateUniques(); err != nil {
+				return nil, fmt.Errorf("failed to merge visitors sketches: %w", err)
+			}
+		}
+
+		bannerStats.SortByTimestamp()
+		response.Banners = append(response.Banners, bannerStats)
+		response.Total += bannerStats.Total
+	}
+
+	if s.cacheRepo != nil {
+		_ = s.cacheRepo.SetMultiStats(ctx, bannerIDs, from, to, opts, response)
+	}
+
+	return response, nil
 }
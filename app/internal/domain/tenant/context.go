@@ -0,0 +1,42 @@
+package tenant
+
+import "context"
+
+// contextKey ключ значения контекста с ID арендатора запроса
+type contextKey struct{}
+
+// systemKey ключ значения контекста системной операции
+type systemKey struct{}
+
+// NewContext возвращает контекст с ID арендатора, которым ограничиваются запросы к данным
+func NewContext(ctx context.Context, id int64) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// WithSystem возвращает контекст системной операции, работающей с данными всех арендаторов
+// (публичный /counter, сброс кликов, фоновые задачи). Без арендатора и без этой отметки
+// запросы к данным арендаторов ничего не находят
+func WithSystem(ctx context.Context) context.Context {
+	return context.WithValue(ctx, systemKey{}, true)
+}
+
+// IsSystem сообщает, отмечен ли контекст как системный (см. WithSystem)
+func IsSystem(ctx context.Context) bool {
+	system, _ := ctx.Value(systemKey{}).(bool)
+	return system
+}
+
+// FromContext возвращает ID арендатора запроса; ok = false, если арендатор не задан
+func FromContext(ctx context.Context) (id int64, ok bool) {
+	id, ok = ctx.Value(contextKey{}).(int64)
+	return id, ok && id > 0
+}
+
+// IDOrDefault возвращает ID арендатора запроса или DefaultID, если арендатор не задан.
+// Используется при создании данных, которые всегда принадлежат какому-то арендатору
+func IDOrDefault(ctx context.Context) int64 {
+	if id, ok := FromContext(ctx); ok {
+		return id
+	}
+	return DefaultID
+}
@@ -0,0 +1,70 @@
+package tenant
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultID ID арендатора по умолчанию (migrations/013): ему принадлежат данные,
+// созданные до разделения по арендаторам, и запросы без указания арендатора
+const DefaultID int64 = 1
+
+// MaxNameLength максимальная длина названия арендатора (tenants.name VARCHAR(255))
+const MaxNameLength = 255
+
+// Доменные ошибки
+var (
+	ErrTenantNotFound  = errors.New("tenant not found")
+	ErrInvalidTenantID = errors.New("invalid tenant ID")
+	ErrEmptyName       = errors.New("tenant name is required")
+	ErrNameTooLong     = errors.New("tenant name is too long")
+	ErrDuplicateName   = errors.New("tenant with this name already exists")
+)
+
+// Tenant представляет арендатора: бизнес-подразделение со своими баннерами,
+// кликами, статистикой и API ключами
+type Tenant struct {
+	ID        int64     `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// NewTenant создает арендатора с валидацией
+func NewTenant(name string) (*Tenant, error) {
+	t := &Tenant{Name: strings.TrimSpace(name)}
+
+	if err := t.IsValid(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// ParseID разбирает ID арендатора (заголовок X-Tenant-ID, аргументы команд)
+func ParseID(value string) (int64, error) {
+	id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidTenantID, value)
+	}
+	return id, nil
+}
+
+// IsValid проверяет валидность арендатора
+func (t *Tenant) IsValid() error {
+	if t.ID < 0 {
+		return ErrInvalidTenantID
+	}
+
+	if t.Name == "" {
+		return ErrEmptyName
+	}
+	if utf8.RuneCountInString(t.Name) > MaxNameLength {
+		return ErrNameTooLong
+	}
+
+	return nil
+}
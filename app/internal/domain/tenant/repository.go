@@ -0,0 +1,17 @@
+package tenant
+
+import (
+	"context"
+)
+
+// Repository определяет интерфейс для работы с арендаторами
+type Repository interface {
+	// GetByID возвращает арендатора по ID
+	GetByID(ctx context.Context, id int64) (*Tenant, error)
+
+	// List возвращает всех арендаторов
+	List(ctx context.Context) ([]*Tenant, error)
+
+	// Create создает арендатора, заполняя ID и CreatedAt
+	Create(ctx context.Context, tenant *Tenant) error
+}
@@ -0,0 +1,46 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+)
+
+// Service представляет доменный сервис для работы с арендаторами
+type Service struct {
+	repo Repository
+}
+
+// NewService создает новый экземпляр сервиса арендаторов
+func NewService(repo Repository) *Service {
+	return &Service{
+		repo: repo,
+	}
+}
+
+// Get возвращает арендатора по ID
+func (s *Service) Get(ctx context.Context, id int64) (*Tenant, error) {
+	if id <= 0 {
+		return nil, ErrInvalidTenantID
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
+
+// List возвращает всех арендаторов
+func (s *Service) List(ctx context.Context) ([]*Tenant, error) {
+	return s.repo.List(ctx)
+}
+
+// Create создает арендатора
+func (s *Service) Create(ctx context.Context, name string) (*Tenant, error) {
+	tenant, err := NewTenant(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, tenant); err != nil {
+		return nil, fmt.Errorf("failed to create tenant: %w", err)
+	}
+
+	return tenant, nil
+}
@@ -0,0 +1,153 @@
+package botfilter
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Filter реализует интерфейс click.BotDetector. Правила загружаются из YAML файла
+// и перечитываются при его изменении; при ошибке в новом файле продолжают
+// действовать предыдущие правила
+type Filter struct {
+	path   string
+	logger *logrus.Logger
+
+	rules atomic.Pointer[ruleSet]
+
+	// Отметка загруженной версии файла
+	reloadMutex sync.Mutex
+	modTime     time.Time
+	size        int64
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewFilter создает фильтр ботов. Пустой path - встроенные правила по умолчанию;
+// reloadInterval > 0 включает проверку изменений файла с этим интервалом
+func NewFilter(path string, reloadInterval time.Duration, logger *logrus.Logger) (*Filter, error) {
+	if logger == nil {
+		logger = logrus.New()
+	}
+
+	f := &Filter{
+		path:   path,
+		logger: logger,
+		done:   make(chan struct{}),
+	}
+
+	if path == "" {
+		rules, err := DefaultRules().compile()
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile default bot filter rules: %w", err)
+		}
+		f.rules.Store(rules)
+		return f, nil
+	}
+
+	if _, err := f.Reload(); err != nil {
+		return nil, err
+	}
+
+	if reloadInterval > 0 {
+		f.wg.Add(1)
+		go f.watch(reloadInterval)
+	}
+
+	return f, nil
+}
+
+// IsBot сообщает, отправлен ли клик ботом
+func (f *Filter) IsBot(userIP, userAgent string) bool {
+	reason, matched := f.rules.Load().match(userIP, userAgent)
+	if matched {
+		f.logger.WithFields(logrus.Fields{
+			"user_ip":    userIP,
+			"user_agent": userAgent,
+			"rule":       reason,
+		}).Debug("Click matched bot filter")
+	}
+	return matched
+}
+
+// Reload перечитывает файл правил, если он изменился с прошлой загрузки.
+// Возвращает true, если правила были заменены
+func (f *Filter) Reload() (bool, error) {
+	f.reloadMutex.Lock()
+	defer f.reloadMutex.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat bot filter rules: %w", err)
+	}
+
+	if f.rules.Load() != nil && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return false, nil
+	}
+
+	rules, err := LoadRules(f.path)
+	if err != nil {
+		f.markLoaded(info)
+		return false, err
+	}
+
+	compiled, err := rules.compile()
+	if err != nil {
+		f.markLoaded(info)
+		return false, fmt.Errorf("failed to compile bot filter rules: %w", err)
+	}
+
+	f.rules.Store(compiled)
+	f.markLoaded(info)
+
+	f.logger.WithFields(logrus.Fields{
+		"file":        f.path,
+		"ua_patterns": len(rules.UserAgentPatterns),
+		"deny_cidrs":  len(compiled.prefixes),
+	}).Info("Bot filter rules loaded")
+
+	return true, nil
+}
+
+// markLoaded запоминает версию файла, чтобы не перечитывать ее повторно.
+// Ошибочная версия тоже запоминается: ошибка логируется один раз до следующего изменения
+func (f *Filter) markLoaded(info os.FileInfo) {
+	if f.rules.Load() == nil {
+		return
+	}
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+}
+
+// watch периодически проверяет изменения файла правил
+func (f *Filter) watch(interval time.Duration) {
+	defer f.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := f.Reload(); err != nil {
+				f.logger.WithError(err).WithField("file", f.path).Error("Failed to reload bot filter rules, keeping previous rules")
+			}
+		case <-f.done:
+			return
+		}
+	}
+}
+
+// Close останавливает отслеживание файла правил
+func (f *Filter) Close() {
+	f.closeOnce.Do(func() {
+		close(f.done)
+	})
+	f.wg.Wait()
+}
@@ -0,0 +1,196 @@
+package botfilter
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// builtinUserAgentPatterns шаблоны User-Agent известных ботов: поисковые краулеры,
+// мониторинг доступности, превью ссылок в мессенджерах и HTTP клиенты библиотек
+var builtinUserAgentPatterns = []string{
+	`bot\b`, `crawl`, `spider`, `slurp`, `archiver`, `scraper`,
+	`facebookexternalhit`, `facebookcatalog`, `embedly`, `quora link preview`,
+	`skypeuripreview`, `whatsapp`, `vkshare`, `bingpreview`,
+	`pingdom`, `uptimerobot`, `statuscake`, `site24x7`, `newrelicpinger`,
+	`datadog`, `zabbix`, `nagios`, `monitis`, `lighthouse`, `gtmetrix`,
+	`headlesschrome`, `phantomjs`, `puppeteer`, `playwright`, `selenium`,
+	`^curl/`, `^wget/`, `^python-requests/`, `^python-urllib/`, `^go-http-client/`,
+	`^java/`, `^apache-httpclient/`, `^okhttp/`, `^node-fetch/`, `^axios/`,
+	`^libwww-perl/`, `^scrapy/`, `^httpie/`, `^postmanruntime/`,
+}
+
+// Rules описание правил фильтрации в файле
+type Rules struct {
+	// BuiltinPatterns включает встроенные шаблоны известных ботов
+	BuiltinPatterns *bool `yaml:"builtin_patterns"`
+
+	// UserAgentPatterns дополнительные регулярные выражения (без учета регистра)
+	UserAgentPatterns []string `yaml:"user_agent_patterns"`
+
+	// AllowUserAgentPatterns исключения из шаблонов: совпавший User-Agent не считается ботом
+	AllowUserAgentPatterns []string `yaml:"allow_user_agent_patterns"`
+
+	// DenyCIDRs подсети, клики из которых считаются ботами
+	DenyCIDRs []string `yaml:"deny_cidrs"`
+
+	// BlockEmptyUserAgent считает ботом клик без User-Agent
+	BlockEmptyUserAgent bool `yaml:"block_empty_user_agent"`
+
+	// BlockMalformedUserAgent считает ботом клик с некорректным User-Agent:
+	// невалидный UTF-8, управляющие символы, нет букв или длина вне допустимых границ
+	BlockMalformedUserAgent bool `yaml:"block_malformed_user_agent"`
+	MinUserAgentLength      int  `yaml:"min_user_agent_length"`
+	MaxUserAgentLength      int  `yaml:"max_user_agent_length"`
+}
+
+// DefaultRules правила по умолчанию, если файл не задан
+func DefaultRules() Rules {
+	return Rules{
+		BlockEmptyUserAgent:     true,
+		BlockMalformedUserAgent: true,
+	}
+}
+
+// LoadRules читает правила из YAML файла
+func LoadRules(path string) (Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Rules{}, fmt.Errorf("failed to read bot filter rules: %w", err)
+	}
+
+	var rules Rules
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return Rules{}, fmt.Errorf("failed to parse bot filter rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// ruleSet скомпилированные правила
+type ruleSet struct {
+	deny           *regexp.Regexp // nil - шаблонов нет
+	allow          *regexp.Regexp
+	prefixes       []netip.Prefix
+	blockEmpty     bool
+	blockMalformed bool
+	minLength      int
+	maxLength      int
+}
+
+// compile проверяет и компилирует правила
+func (r Rules) compile() (*ruleSet, error) {
+	set := &ruleSet{
+		blockEmpty:     r.BlockEmptyUserAgent,
+		blockMalformed: r.BlockMalformedUserAgent,
+		minLength:      r.MinUserAgentLength,
+		maxLength:      r.MaxUserAgentLength,
+	}
+	if set.minLength <= 0 {
+		set.minLength = 8
+	}
+	if set.maxLength <= 0 {
+		set.maxLength = 1024
+	}
+
+	patterns := r.UserAgentPatterns
+	if r.BuiltinPatterns == nil || *r.BuiltinPatterns {
+		patterns = append(append([]string{}, builtinUserAgentPatterns...), patterns...)
+	}
+
+	var err error
+	if set.deny, err = compilePatterns(patterns); err != nil {
+		return nil, err
+	}
+	if set.allow, err = compilePatterns(r.AllowUserAgentPatterns); err != nil {
+		return nil, err
+	}
+
+	for _, cidr := range r.DenyCIDRs {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			// Одиночный адрес без маски
+			addr, addrErr := netip.ParseAddr(strings.TrimSpace(cidr))
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid deny CIDR %q: %w", cidr, err)
+			}
+			addr = addr.Unmap()
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		set.prefixes = append(set.prefixes, prefix.Masked())
+	}
+
+	return set, nil
+}
+
+// compilePatterns объединяет шаблоны в одно регулярное выражение без учета регистра
+func compilePatterns(patterns []string) (*regexp.Regexp, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	for _, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid user agent pattern %q: %w", pattern, err)
+		}
+	}
+
+	return regexp.Compile(`(?i)(?:` + strings.Join(patterns, `)|(?:`) + `)`)
+}
+
+// match проверяет клик по правилам и возвращает сработавшее правило
+func (s *ruleSet) match(userIP, userAgent string) (string, bool) {
+	if len(s.prefixes) > 0 {
+		if addr, err := netip.ParseAddr(userIP); err == nil {
+			addr = addr.Unmap()
+			for _, prefix := range s.prefixes {
+				if prefix.Contains(addr) {
+					return "deny_cidr", true
+				}
+			}
+		}
+	}
+
+	userAgent = strings.TrimSpace(userAgent)
+	if userAgent == "" {
+		return "empty_user_agent", s.blockEmpty
+	}
+
+	if s.blockMalformed && s.malformed(userAgent) {
+		return "malformed_user_agent", true
+	}
+
+	if s.deny != nil && s.deny.MatchString(userAgent) {
+		if s.allow != nil && s.allow.MatchString(userAgent) {
+			return "", false
+		}
+		return "user_agent_pattern", true
+	}
+
+	return "", false
+}
+
+// malformed проверяет, похож ли User-Agent на заголовок реального клиента
+func (s *ruleSet) malformed(userAgent string) bool {
+	if len(userAgent) < s.minLength || len(userAgent) > s.maxLength || !utf8.ValidString(userAgent) {
+		return true
+	}
+
+	hasLetter := false
+	for _, r := range userAgent {
+		if unicode.IsControl(r) {
+			return true
+		}
+		if unicode.IsLetter(r) {
+			hasLetter = true
+		}
+	}
+
+	return !hasLetter
+}
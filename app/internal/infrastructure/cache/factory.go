@@ -5,7 +5,9 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/clickcounter/app/internal/domain/apikey"
 	"github.com/clickcounter/app/internal/domain/banner"
+	"github.com/clickcounter/app/internal/domain/click"
 	"github.com/clickcounter/app/internal/domain/stats"
 	"github.com/clickcounter/app/internal/infrastructure/cache/memory"
 	"github.com/clickcounter/app/internal/infrastructure/config"
@@ -15,13 +17,17 @@ import (
 type CacheFactory struct {
 	config *config.Config
 	logger *logrus.Logger
+
+	// Созданные хранилища по имени кэша (для метрик)
+	memoryCaches map[string]*memory.MemoryCache
 }
 
 // NewCacheFactory создает новую фабрику кэшей
 func NewCacheFactory(config *config.Config, logger *logrus.Logger) *CacheFactory {
 	return &CacheFactory{
-		config: config,
-		logger: logger,
+		config:       config,
+		logger:       logger,
+		memoryCaches: make(map[string]*memory.MemoryCache),
 	}
 }
 
@@ -41,6 +47,7 @@ func (f *CacheFactory) createMemoryBannerCache() (banner.CacheRepository, error)
 	bannerTTL := time.Duration(f.config.Cache.Memory.BannerTTL) * time.Second
 
 	memCache := memory.NewMemoryCache(cleanupInterval)
+	f.memoryCaches["banner"] = memCache
 	bannerCache := memory.NewBannerCache(memCache, f.logger, bannerTTL)
 
 	f.logger.WithFields(logrus.Fields{
@@ -58,6 +65,7 @@ func (f *CacheFactory) createMemoryStatsCache() (stats.CacheRepository, error) {
 	statsTTL := time.Duration(f.config.Cache.Memory.StatsTTL) * time.Second
 
 	memCache := memory.NewMemoryCache(cleanupInterval)
+	f.memoryCaches["stats"] = memCache
 	statsCache := memory.NewStatsCache(memCache, f.logger, statsTTL)
 
 	f.logger.WithFields(logrus.Fields{
@@ -69,6 +77,48 @@ func (f *CacheFactory) createMemoryStatsCache() (stats.CacheRepository, error) {
 	return statsCache, nil
 }
 
+// CreateAPIKeyCache создает кэш API ключей в памяти. TTL ограничивает время,
+// в течение которого отозванный на другом экземпляре сервиса ключ продолжает действовать
+func (f *CacheFactory) CreateAPIKeyCache() apikey.CacheRepository {
+	cleanupInterval := time.Duration(f.config.Cache.Memory.CleanupInterval) * time.Second
+	apiKeyTTL := time.Duration(f.config.Auth.CacheTTL) * time.Second
+
+	memCache := memory.NewMemoryCache(cleanupInterval)
+	f.memoryCaches["api_key"] = memCache
+	apiKeyCache := memory.NewAPIKeyCache(memCache, f.logger, apiKeyTTL)
+
+	f.logger.WithFields(logrus.Fields{
+		"cache_type":       "memory",
+		"cleanup_interval": cleanupInterval,
+		"api_key_ttl":      apiKeyTTL,
+	}).Info("Memory api key cache created")
+
+	return apiKeyCache
+}
+
+// CreateClickDedupCache создает хранилище отпечатков кликов для дедупликации
+func (f *CacheFactory) CreateClickDedupCache() click.DedupCache {
+	window := time.Duration(f.config.ClickDedup.Window) * time.Second
+	dedupCache := memory.NewClickDedupCache(window, f.config.ClickDedup.MaxEntries)
+
+	f.logger.WithFields(logrus.Fields{
+		"cache_type":  "memory",
+		"window":      window,
+		"max_entries": f.config.ClickDedup.MaxEntries,
+	}).Info("Memory click dedup cache created")
+
+	return dedupCache
+}
+
+// MemoryCaches возвращает созданные хранилища кэшей по имени ("banner", "stats", "api_key")
+func (f *CacheFactory) MemoryCaches() map[string]*memory.MemoryCache {
+	caches := make(map[string]*memory.MemoryCache, len(f.memoryCaches))
+	for name, c := range f.memoryCaches {
+		caches[name] = c
+	}
+	return caches
+}
+
 // GetCacheType возвращает тип используемого кэша
 func (f *CacheFactory) GetCacheType() string {
 	return "memory"
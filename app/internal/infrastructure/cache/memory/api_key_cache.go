@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/domain/apikey"
+)
+
+// APIKeyCache реализует кэширование API ключей в памяти по SHA-256 ключа
+type APIKeyCache struct {
+	cache  *MemoryCache
+	logger *logrus.Logger
+	ttl    time.Duration
+}
+
+// NewAPIKeyCache создает новый экземпляр кэша API ключей
+func NewAPIKeyCache(cache *MemoryCache, logger *logrus.Logger, ttl time.Duration) *APIKeyCache {
+	return &APIKeyCache{
+		cache:  cache,
+		logger: logger,
+		ttl:    ttl,
+	}
+}
+
+// Get получает ключ из кэша
+func (c *APIKeyCache) Get(ctx context.Context, hash string) (*apikey.Key, error) {
+	data, err := c.cache.Get(ctx, c.apiKeyKey(hash))
+	if err != nil {
+		if err == ErrKeyNotFound {
+			return nil, apikey.ErrKeyNotFound
+		}
+		c.logger.WithError(err).Error("Failed to get api key from cache")
+		return nil, err
+	}
+
+	// Десериализуем JSON
+	jsonData, ok := data.(string)
+	if !ok {
+		c.logger.Error("Invalid data type in cache")
+		return nil, ErrInvalidType
+	}
+
+	var key apikey.Key
+	if err := json.Unmarshal([]byte(jsonData), &key); err != nil {
+		c.logger.WithError(err).Error("Failed to unmarshal api key from cache")
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// Set сохраняет ключ в кэш
+func (c *APIKeyCache) Set(ctx context.Context, key *apikey.Key) error {
+	// Сериализуем в JSON
+	data, err := json.Marshal(key)
+	if err != nil {
+		c.logger.WithError(err).WithField("api_key_id", key.ID).Error("Failed to marshal api key for cache")
+		return err
+	}
+
+	if err := c.cache.Set(ctx, c.apiKeyKey(key.Hash), string(data), c.ttl); err != nil {
+		c.logger.WithError(err).WithField("api_key_id", key.ID).Error("Failed to set api key in cache")
+		return err
+	}
+
+	return nil
+}
+
+// Delete удаляет ключ из кэша
+func (c *APIKeyCache) Delete(ctx context.Context, hash string) error {
+	if err := c.cache.Delete(ctx, c.apiKeyKey(hash)); err != nil {
+		c.logger.WithError(err).Error("Failed to delete api key from cache")
+		return err
+	}
+
+	return nil
+}
+
+// apiKeyKey генерирует ключ кэша для API ключа
+func (c *APIKeyCache) apiKeyKey(hash string) string {
+	return "api_key:" + hash
+}
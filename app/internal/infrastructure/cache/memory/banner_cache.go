@@ -9,6 +9,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/clickcounter/app/internal/domain/banner"
+	"github.com/clickcounter/app/internal/domain/tenant"
 )
 
 // BannerCache реализует кэширование баннеров в памяти
@@ -29,7 +30,7 @@ func NewBannerCache(cache *MemoryCache, logger *logrus.Logger, ttl time.Duration
 
 // Get получает баннер из кэша
 func (c *BannerCache) Get(ctx context.Context, id int64) (*banner.Banner, error) {
-	key := c.bannerKey(id)
+	key := c.bannerKey(ctx, id)
 
 	data, err := c.cache.Get(ctx, key)
 	if err != nil {
@@ -58,7 +59,7 @@ func (c *BannerCache) Get(ctx context.Context, id int64) (*banner.Banner, error)
 
 // Set сохраняет баннер в кэш
 func (c *BannerCache) Set(ctx context.Context, b *banner.Banner) error {
-	key := c.bannerKey(b.ID)
+	key := c.bannerKey(ctx, b.ID)
 
 	// Сериализуем в JSON
 	data, err := json.Marshal(b)
@@ -67,7 +68,7 @@ func (c *BannerCache) Set(ctx context.Context, b *banner.Banner) error {
 		return err
 	}
 
-	if err := c.cache.Set(ctx, key, string(data), c.ttl); err != nil {
+	if err := c.cache.Set(ctx, key, string(data), c.entryTTL(b, time.Now())); err != nil {
 		c.logger.WithError(err).WithField("banner_id", b.ID).Error("Failed to set banner in cache")
 		return err
 	}
@@ -76,13 +77,19 @@ func (c *BannerCache) Set(ctx context.Context, b *banner.Banner) error {
 	return nil
 }
 
-// Delete удаляет баннер из кэша
+// Delete удаляет баннер из кэша: запись арендатора запроса и запись публичного /counter,
+// прочитанную системной операцией
 func (c *BannerCache) Delete(ctx context.Context, id int64) error {
-	key := c.bannerKey(id)
+	keys := []string{c.bannerKey(ctx, id)}
+	if unscoped := c.bannerKey(tenant.WithSystem(context.Background()), id); unscoped != keys[0] {
+		keys = append(keys, unscoped)
+	}
 
-	if err := c.cache.Delete(ctx, key); err != nil {
-		c.logger.WithError(err).WithField("banner_id", id).Error("Failed to delete banner from cache")
-		return err
+	for _, key := range keys {
+		if err := c.cache.Delete(ctx, key); err != nil {
+			c.logger.WithError(err).WithField("banner_id", id).Error("Failed to delete banner from cache")
+			return err
+		}
 	}
 
 	c.logger.WithField("banner_id", id).Debug("Banner deleted from cache")
@@ -100,7 +107,7 @@ func (c *BannerCache) Clear(ctx context.Context) error {
 
 	bannerKeyPrefix := "banner:"
 	for _, key := range keys {
-		if len(key) > len(bannerKeyPrefix) && key[:len(bannerKeyPrefix)] == bannerKeyPrefix {
+		if unscoped := stripTenantKey(key); len(unscoped) > len(bannerKeyPrefix) && unscoped[:len(bannerKeyPrefix)] == bannerKeyPrefix {
 			if err := c.cache.Delete(ctx, key); err != nil {
 				c.logger.WithError(err).WithField("key", key).Error("Failed to delete banner key from cache")
 			}
@@ -111,7 +118,26 @@ func (c *BannerCache) Clear(ctx context.Context) error {
 	return nil
 }
 
-// bannerKey генерирует ключ для баннера
-func (c *BannerCache) bannerKey(id int64) string {
-	return fmt.Sprintf("banner:%d", id)
+// entryTTL возвращает время жизни записи: не дольше banner_ttl и не дольше
+// ближайшей границы окна показа, чтобы запись обновлялась к моменту ее смены
+func (c *BannerCache) entryTTL(b *banner.Banner, now time.Time) time.Duration {
+	ttl := c.ttl
+
+	if next, ok := b.NextTransition(now); ok {
+		if untilNext := next.Sub(now); untilNext < ttl {
+			ttl = untilNext
+		}
+	}
+
+	// Граница совпадает с текущим моментом - не кэшируем дольше минимального срока
+	if ttl < time.Millisecond {
+		ttl = time.Millisecond
+	}
+
+	return ttl
+}
+
+// bannerKey генерирует ключ для баннера с префиксом арендатора запроса
+func (c *BannerCache) bannerKey(ctx context.Context, id int64) string {
+	return tenantKey(ctx, fmt.Sprintf("banner:%d", id))
 }
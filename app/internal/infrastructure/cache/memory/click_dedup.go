@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// dedupShards количество независимо блокируемых частей хранилища отпечатков
+const dedupShards = 32
+
+// dedupEntry запись очереди отпечатков в порядке добавления
+type dedupEntry struct {
+	fingerprint uint64
+	expiresAt   int64
+}
+
+// dedupShard часть хранилища отпечатков. Окно одинаково для всех записей,
+// поэтому порядок добавления совпадает с порядком истечения: устаревшие и
+// вытесняемые при переполнении записи всегда находятся в начале кольцевой очереди
+type dedupShard struct {
+	mutex   sync.Mutex
+	entries map[uint64]int64 // отпечаток -> время истечения (UnixNano)
+	ring    []dedupEntry
+	head    int
+	size    int
+}
+
+// ClickDedupCache реализует интерфейс click.DedupCache: хранит отпечатки кликов
+// в течение окна дедупликации и занимает не более maxEntries записей
+type ClickDedupCache struct {
+	window time.Duration
+	shards [dedupShards]dedupShard
+}
+
+// NewClickDedupCache создает хранилище отпечатков с окном window и ограничением maxEntries
+func NewClickDedupCache(window time.Duration, maxEntries int) *ClickDedupCache {
+	perShard := maxEntries / dedupShards
+	if perShard < 1 {
+		perShard = 1
+	}
+
+	c := &ClickDedupCache{window: window}
+	for i := range c.shards {
+		c.shards[i].entries = make(map[uint64]int64)
+		c.shards[i].ring = make([]dedupEntry, perShard)
+	}
+
+	return c
+}
+
+// Seen запоминает отпечаток и сообщает, встречался ли он в течение окна.
+// Окно отсчитывается от первого учтенного клика и не продлевается повторами
+func (c *ClickDedupCache) Seen(ctx context.Context, fingerprint uint64) (bool, error) {
+	now := time.Now().UnixNano()
+	shard := &c.shards[fingerprint%dedupShards]
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	shard.evictExpired(now)
+
+	if expiresAt, ok := shard.entries[fingerprint]; ok && expiresAt > now {
+		return true, nil
+	}
+
+	// При переполнении вытесняем самый старый отпечаток
+	if shard.size == len(shard.ring) {
+		shard.pop()
+	}
+
+	expiresAt := now + int64(c.window)
+	shard.entries[fingerprint] = expiresAt
+	shard.ring[(shard.head+shard.size)%len(shard.ring)] = dedupEntry{fingerprint: fingerprint, expiresAt: expiresAt}
+	shard.size++
+
+	return false, nil
+}
+
+// Forget удаляет отпечаток. Запись кольцевой очереди остается до истечения окна:
+// pop удаляет из entries только отпечаток с совпадающим временем истечения
+func (c *ClickDedupCache) Forget(ctx context.Context, fingerprint uint64) error {
+	shard := &c.shards[fingerprint%dedupShards]
+
+	shard.mutex.Lock()
+	delete(shard.entries, fingerprint)
+	shard.mutex.Unlock()
+
+	return nil
+}
+
+// Len возвращает количество хранимых отпечатков
+func (c *ClickDedupCache) Len() int {
+	total := 0
+	for i := range c.shards {
+		c.shards[i].mutex.Lock()
+		total += len(c.shards[i].entries)
+		c.shards[i].mutex.Unlock()
+	}
+	return total
+}
+
+// evictExpired удаляет истекшие отпечатки из начала очереди
+func (s *dedupShard) evictExpired(now int64) {
+	for s.size > 0 && s.ring[s.head].expiresAt <= now {
+		s.pop()
+	}
+}
+
+// pop удаляет самую старую запись очереди
+func (s *dedupShard) pop() {
+	entry := s.ring[s.head]
+	// Отпечаток мог быть добавлен повторно после истечения - удаляем только свою запись
+	if expiresAt, ok := s.entries[entry.fingerprint]; ok && expiresAt == entry.expiresAt {
+		delete(s.entries, entry.fingerprint)
+	}
+	s.head = (s.head + 1) % len(s.ring)
+	s.size--
+}
@@ -3,6 +3,7 @@ package memory
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,12 +18,23 @@ func (item *CacheItem) IsExpired() bool {
 	return time.Now().After(item.ExpiresAt)
 }
 
+// CacheStats счетчики обращений к кэшу
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+	Items  int
+}
+
 // MemoryCache представляет кэш в памяти
 type MemoryCache struct {
 	data    map[string]*CacheItem
 	mutex   sync.RWMutex
 	cleaner *time.Ticker
 	done    chan bool
+
+	// Счетчики попаданий и промахов Get/MGet
+	hits   int64
+	misses int64
 }
 
 // NewMemoryCache создает новый экземпляр кэша в памяти
@@ -84,6 +96,7 @@ func (c *MemoryCache) Get(ctx context.Context, key string) (interface{}, error)
 
 	item, exists := c.data[key]
 	if !exists {
+		atomic.AddInt64(&c.misses, 1)
 		return nil, ErrKeyNotFound
 	}
 
@@ -94,12 +107,23 @@ func (c *MemoryCache) Get(ctx context.Context, key string) (interface{}, error)
 		delete(c.data, key)
 		c.mutex.Unlock()
 		c.mutex.RLock()
+		atomic.AddInt64(&c.misses, 1)
 		return nil, ErrKeyNotFound
 	}
 
+	atomic.AddInt64(&c.hits, 1)
 	return item.Value, nil
 }
 
+// Stats возвращает счетчики попаданий и промахов и текущее количество элементов
+func (c *MemoryCache) Stats() CacheStats {
+	return CacheStats{
+		Hits:   atomic.LoadInt64(&c.hits),
+		Misses: atomic.LoadInt64(&c.misses),
+		Items:  c.Size(),
+	}
+}
+
 // Delete удаляет значение из кэша
 func (c *MemoryCache) Delete(ctx context.Context, key string) error {
 	c.mutex.Lock()
@@ -172,6 +196,9 @@ func (c *MemoryCache) MGet(ctx context.Context, keys []string) (map[string]inter
 		}
 	}
 
+	atomic.AddInt64(&c.hits, int64(len(result)))
+	atomic.AddInt64(&c.misses, int64(len(keys)-len(result)))
+
 	return result, nil
 }
 
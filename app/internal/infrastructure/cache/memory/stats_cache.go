@@ -2,6 +2,8 @@ package memory
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -28,8 +30,8 @@ func NewStatsCache(cache *MemoryCache, logger *logrus.Logger, ttl time.Duration)
 }
 
 // GetStats получает статистику из кэша
-func (c *StatsCache) GetStats(ctx context.Context, bannerID int64, from, to time.Time) (*stats.StatsResponse, error) {
-	key := c.statsKey(bannerID, from, to)
+func (c *StatsCache) GetStats(ctx context.Context, bannerID int64, from, to time.Time, opts stats.QueryOptions) (*stats.StatsResponse, error) {
+	key := c.statsKey(ctx, bannerID, from, to, opts)
 
 	data, err := c.cache.Get(ctx, key)
 	if err != nil {
@@ -69,8 +71,8 @@ func (c *StatsCache) GetStats(ctx context.Context, bannerID int64, from, to time
 }
 
 // SetStats сохраняет статистику в кэш
-func (c *StatsCache) SetStats(ctx context.Context, bannerID int64, from, to time.Time, statsResp *stats.StatsResponse) error {
-	key := c.statsKey(bannerID, from, to)
+func (c *StatsCache) SetStats(ctx context.Context, bannerID int64, from, to time.Time, opts stats.QueryOptions, statsResp *stats.StatsResponse) error {
+	key := c.statsKey(ctx, bannerID, from, to, opts)
 
 	// Сериализуем в JSON
 	data, err := json.Marshal(statsResp)
@@ -101,8 +103,8 @@ func (c *StatsCache) SetStats(ctx context.Context, bannerID int64, from, to time
 }
 
 // DeleteStats удаляет статистику из кэша
-func (c *StatsCache) DeleteStats(ctx context.Context, bannerID int64, from, to time.Time) error {
-	key := c.statsKey(bannerID, from, to)
+func (c *StatsCache) DeleteStats(ctx context.Context, bannerID int64, from, to time.Time, opts stats.QueryOptions) error {
+	key := c.statsKey(ctx, bannerID, from, to, opts)
 
 	if err := c.cache.Delete(ctx, key); err != nil {
 		c.logger.WithError(err).WithFields(logrus.Fields{
@@ -121,7 +123,72 @@ func (c *StatsCache) DeleteStats(ctx context.Context, bannerID int64, from, to t
 	return nil
 }
 
-// statsKey генерирует ключ для статистики
-func (c *StatsCache) statsKey(bannerID int64, from, to time.Time) string {
-	return fmt.Sprintf("stats:%d:%d:%d", bannerID, from.Unix(), to.Unix())
+// GetMultiStats получает из кэша статистику набора баннеров
+func (c *StatsCache) GetMultiStats(ctx context.Context, bannerIDs []int64, from, to time.Time, opts stats.QueryOptions) (*stats.MultiStatsResponse, error) {
+	key := c.multiStatsKey(ctx, bannerIDs, from, to, opts)
+
+	data, err := c.cache.Get(ctx, key)
+	if err != nil {
+		if err == ErrKeyNotFound {
+			return nil, stats.ErrStatNotFound
+		}
+		c.logger.WithError(err).WithField("banners", len(bannerIDs)).Error("Failed to get banner set stats from cache")
+		return nil, err
+	}
+
+	jsonData, ok := data.(string)
+	if !ok {
+		c.logger.WithField("banners", len(bannerIDs)).Error("Invalid data type in cache")
+		return nil, ErrInvalidType
+	}
+
+	var statsResp stats.MultiStatsResponse
+	if err := json.Unmarshal([]byte(jsonData), &statsResp); err != nil {
+		c.logger.WithError(err).WithField("banners", len(bannerIDs)).Error("Failed to unmarshal banner set stats from cache")
+		return nil, err
+	}
+
+	return &statsResp, nil
+}
+
+// SetMultiStats сохраняет в кэш статистику набора баннеров
+func (c *StatsCache) SetMultiStats(ctx context.Context, bannerIDs []int64, from, to time.Time, opts stats.QueryOptions, statsResp *stats.MultiStatsResponse) error {
+	key := c.multiStatsKey(ctx, bannerIDs, from, to, opts)
+
+	data, err := json.Marshal(statsResp)
+	if err != nil {
+		c.logger.WithError(err).WithField("banners", len(bannerIDs)).Error("Failed to marshal banner set stats for cache")
+		return err
+	}
+
+	if err := c.cache.Set(ctx, key, string(data), c.ttl); err != nil {
+		c.logger.WithError(err).WithField("banners", len(bannerIDs)).Error("Failed to set banner set stats in cache")
+		return err
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"banners": len(bannerIDs),
+		"from":    from,
+		"to":      to,
+	}).Debug("Banner set stats cached successfully")
+	return nil
+}
+
+// multiStatsKey генерирует ключ для статистики набора баннеров с префиксом арендатора
+// запроса. Набор до 500 ID сворачивается в хэш, чтобы ключ оставался коротким
+func (c *StatsCache) multiStatsKey(ctx context.Context, bannerIDs []int64, from, to time.Time, opts stats.QueryOptions) string {
+	hash := sha256.New()
+	buf := make([]byte, 8)
+	for _, id := range bannerIDs {
+		binary.BigEndian.PutUint64(buf, uint64(id))
+		hash.Write(buf)
+	}
+
+	return tenantKey(ctx, fmt.Sprintf("stats:set:%x:%d:%d:%d:%s:%s",
+		hash.Sum(nil)[:16], len(bannerIDs), from.Unix(), to.Unix(), opts.BucketGranularity(), opts.CacheKey()))
+}
+
+// statsKey генерирует ключ для статистики с префиксом арендатора запроса
+func (c *StatsCache) statsKey(ctx context.Context, bannerID int64, from, to time.Time, opts stats.QueryOptions) string {
+	return tenantKey(ctx, fmt.Sprintf("stats:%d:%d:%d:%s:%s", bannerID, from.Unix(), to.Unix(), opts.BucketGranularity(), opts.CacheKey()))
 }
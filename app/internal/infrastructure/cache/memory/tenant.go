@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/clickcounter/app/internal/domain/tenant"
+)
+
+// tenantKeyPrefix префикс ключей кэша, относящихся к арендатору
+const tenantKeyPrefix = "tenant:"
+
+// tenantKey добавляет к ключу префикс арендатора запроса, чтобы данные, прочитанные
+// с ограничением арендатором, не отдавались другому арендатору. Для системной операции
+// (публичный /counter) ключ не меняется; контекст без арендатора получает отдельный
+// префикс, чтобы не читать записи системных операций в обход ограничения в БД
+func tenantKey(ctx context.Context, key string) string {
+	id, ok := tenant.FromContext(ctx)
+	if !ok {
+		if tenant.IsSystem(ctx) {
+			return key
+		}
+		id = 0
+	}
+	return fmt.Sprintf("%s%d:%s", tenantKeyPrefix, id, key)
+}
+
+// stripTenantKey возвращает ключ без префикса арендатора
+func stripTenantKey(key string) string {
+	rest, ok := strings.CutPrefix(key, tenantKeyPrefix)
+	if !ok {
+		return key
+	}
+	if _, unscoped, found := strings.Cut(rest, ":"); found {
+		return unscoped
+	}
+	return key
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 )
@@ -16,7 +17,17 @@ type Config struct {
 	Cache           CacheConfig           `mapstructure:"cache"`
 	Logger          LoggerConfig          `mapstructure:"logger"`
 	ClickFlusher    ClickFlusherConfig    `mapstructure:"click_flusher"`
+	ClickJournal    ClickJournalConfig    `mapstructure:"click_journal"`
+	ClickDedup      ClickDedupConfig      `mapstructure:"click_dedup"`
+	BotFilter       BotFilterConfig       `mapstructure:"bot_filter"`
 	StatsAggregator StatsAggregatorConfig `mapstructure:"stats_aggregator"`
+	StatsRollup     StatsRollupConfig     `mapstructure:"stats_rollup"`
+	ClickPartitions ClickPartitionsConfig `mapstructure:"click_partitions"`
+	Retention       RetentionConfig       `mapstructure:"retention"`
+	LiveStats       LiveStatsConfig       `mapstructure:"live_stats"`
+	Auth            AuthConfig            `mapstructure:"auth"`
+	Metrics         MetricsConfig         `mapstructure:"metrics"`
+	RateLimiting    RateLimitingConfig    `mapstructure:"rate_limiting"`
 }
 
 // ServerConfig конфигурация HTTP сервера
@@ -39,6 +50,7 @@ type DatabaseConfig struct {
 	MaxOpenConns    int    `mapstructure:"max_open_conns"`
 	MaxIdleConns    int    `mapstructure:"max_idle_conns"`
 	ConnMaxLifetime int    `mapstructure:"conn_max_lifetime"`
+	AutoMigrate     bool   `mapstructure:"auto_migrate"` // применять миграции при старте
 }
 
 // CacheConfig конфигурация кэша
@@ -54,6 +66,29 @@ type MemoryConfig struct {
 	StatsTTL        int `mapstructure:"stats_ttl" yaml:"stats_ttl"`               // в секундах
 }
 
+// MetricsConfig конфигурация экспорта метрик Prometheus
+type MetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Path    string `mapstructure:"path"`
+}
+
+// RateLimitingConfig конфигурация ограничения частоты запросов
+type RateLimitingConfig struct {
+	Enabled bool              `mapstructure:"enabled"`
+	MaxKeys int               `mapstructure:"max_keys"` // максимум bucket'ов на правило
+	IdleTTL int               `mapstructure:"idle_ttl"` // в секундах
+	Rules   []RateLimitConfig `mapstructure:"rules"`
+}
+
+// RateLimitConfig правило ограничения частоты запросов
+type RateLimitConfig struct {
+	Name   string   `mapstructure:"name"`
+	Key    string   `mapstructure:"key"`    // global, ip, banner, api_key или комбинация через '+'
+	Routes []string `mapstructure:"routes"` // шаблоны маршрутов; пусто - все запросы
+	RPS    float64  `mapstructure:"rps"`
+	Burst  int      `mapstructure:"burst"`
+}
+
 // LoggerConfig конфигурация логгера
 type LoggerConfig struct {
 	Level  string `mapstructure:"level"`
@@ -62,13 +97,97 @@ type LoggerConfig struct {
 
 // ClickFlusherConfig конфигурация сброса кликов
 type ClickFlusherConfig struct {
-	Interval  int `mapstructure:"interval"`
-	BatchSize int `mapstructure:"batch_size"`
+	Interval       int    `mapstructure:"interval"`
+	BatchSize      int    `mapstructure:"batch_size"`
+	QueueSize      int    `mapstructure:"queue_size"`       // емкость очереди кликов
+	Workers        int    `mapstructure:"workers"`          // количество воркеров сброса
+	OverflowPolicy string `mapstructure:"overflow_policy"`  // block, drop_newest или reject
+	FlushTimeout   int    `mapstructure:"flush_timeout"`    // в секундах
+	InsertMode     string `mapstructure:"insert_mode"`      // copy или batch
+	MaxRetries     int    `mapstructure:"max_retries"`      // повторы записи батча
+	RetryBaseDelay int    `mapstructure:"retry_base_delay"` // в миллисекундах
+	RetryMaxDelay  int    `mapstructure:"retry_max_delay"`  // в миллисекундах
+	DeadLetterDir  string `mapstructure:"dead_letter_dir"`  // пустая строка отключает dead-letter
+}
+
+// ClickJournalConfig конфигурация журнала упреждающей записи кликов
+type ClickJournalConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	Dir          string `mapstructure:"dir"`
+	SyncPolicy   string `mapstructure:"sync_policy"`   // always, interval или none
+	SyncInterval int    `mapstructure:"sync_interval"` // в миллисекундах (для политики interval)
+}
+
+// ClickDedupConfig конфигурация дедупликации повторных кликов
+type ClickDedupConfig struct {
+	Mode       string `mapstructure:"mode"`        // off, suppress или flag
+	Window     int    `mapstructure:"window"`      // в секундах
+	MaxEntries int    `mapstructure:"max_entries"` // максимум хранимых отпечатков
+}
+
+// BotFilterConfig конфигурация фильтра ботов и краулеров
+type BotFilterConfig struct {
+	Enabled        bool   `mapstructure:"enabled"`
+	Action         string `mapstructure:"action"`          // flag или discard
+	RulesFile      string `mapstructure:"rules_file"`      // пустая строка - встроенные правила
+	ReloadInterval int    `mapstructure:"reload_interval"` // в секундах, 0 - без перечитывания
 }
 
 // StatsAggregatorConfig конфигурация агрегации статистики
 type StatsAggregatorConfig struct {
-	Interval int `mapstructure:"interval"`
+	Enabled   bool  `mapstructure:"enabled"` // сверка stats с сырыми кликами
+	Interval  int   `mapstructure:"interval"`
+	BatchSize int64 `mapstructure:"batch_size"` // максимум ID кликов за один проход
+}
+
+// StatsRollupConfig конфигурация свертки статистики в часовые и дневные таблицы
+type StatsRollupConfig struct {
+	Enabled     bool `mapstructure:"enabled"`
+	Interval    int  `mapstructure:"interval"`     // в секундах
+	SettleDelay int  `mapstructure:"settle_delay"` // в секундах, задержка учета свежих изменений
+	BatchSize   int  `mapstructure:"batch_size"`   // максимум бакетов за один запрос
+}
+
+// ClickPartitionsConfig конфигурация секционирования таблицы кликов
+type ClickPartitionsConfig struct {
+	Interval      string `mapstructure:"interval"`       // day или month
+	Premake       int    `mapstructure:"premake"`        // количество будущих секций, создаваемых заранее
+	CheckInterval int    `mapstructure:"check_interval"` // в секундах
+	RetentionMode string `mapstructure:"retention_mode"` // drop или detach устаревших секций
+}
+
+// RetentionConfig конфигурация удаления устаревших данных. Срок 0 - хранить бессрочно
+type RetentionConfig struct {
+	Enabled    bool `mapstructure:"enabled"`
+	Interval   int  `mapstructure:"interval"`    // в секундах
+	ClicksDays int  `mapstructure:"clicks_days"` // сырые клики
+	StatsDays  int  `mapstructure:"stats_days"`  // минутная статистика
+	HourlyDays int  `mapstructure:"hourly_days"` // stats_hourly
+	DailyDays  int  `mapstructure:"daily_days"`  // stats_daily
+	ChunkSize  int  `mapstructure:"chunk_size"`  // максимум строк статистики за один DELETE
+	ChunkPause int  `mapstructure:"chunk_pause"` // в миллисекундах, пауза между порциями
+}
+
+// LiveStatsConfig конфигурация потока live-статистики через Server-Sent Events
+type LiveStatsConfig struct {
+	Enabled        bool `mapstructure:"enabled"`
+	BufferSize     int  `mapstructure:"buffer_size"`     // обновлений в буфере одного клиента
+	TickInterval   int  `mapstructure:"tick_interval"`   // в миллисекундах, период рассылки текущей минуты
+	Heartbeat      int  `mapstructure:"heartbeat"`       // в секундах
+	MaxSubscribers int  `mapstructure:"max_subscribers"` // 0 - без ограничения
+}
+
+// minAPIKeyLength минимальная длина API ключа
+const minAPIKeyLength = 16
+
+// minStatsRollupSettleDelay минимальная задержка свертки в секундах
+const minStatsRollupSettleDelay = 30
+
+// AuthConfig конфигурация доступа к /api/v1 по API ключам
+type AuthConfig struct {
+	Enabled  bool     `mapstructure:"enabled"`   // false - /api/v1 доступен без ключа (только для разработки)
+	APIKeys  []string `mapstructure:"api_keys"`  // bootstrap ключи с правами admin (помимо ключей в БД)
+	CacheTTL int      `mapstructure:"cache_ttl"` // в секундах, кэш ключей из БД
 }
 
 // Load загружает конфигурацию из файла и переменных окружения
@@ -148,6 +267,7 @@ func setDefaults() {
 	viper.SetDefault("database.max_open_conns", 100)
 	viper.SetDefault("database.max_idle_conns", 10)
 	viper.SetDefault("database.conn_max_lifetime", 3600)
+	viper.SetDefault("database.auto_migrate", false)
 
 	// Кэш
 	viper.SetDefault("cache.type", "memory")
@@ -161,12 +281,86 @@ func setDefaults() {
 	viper.SetDefault("logger.level", "info")
 	viper.SetDefault("logger.format", "json")
 
+	// Метрики
+	viper.SetDefault("metrics.enabled", true)
+	viper.SetDefault("metrics.path", "/metrics")
+
+	// Ограничение частоты запросов
+	viper.SetDefault("rate_limiting.enabled", true)
+	viper.SetDefault("rate_limiting.max_keys", 100000)
+	viper.SetDefault("rate_limiting.idle_ttl", 600)
+	viper.SetDefault("rate_limiting.rules", []map[string]interface{}{
+		{"name": "global", "key": "global", "rps": 5000, "burst": 10000},
+	})
+
 	// Сброс кликов
 	viper.SetDefault("click_flusher.interval", 5)
 	viper.SetDefault("click_flusher.batch_size", 1000)
+	viper.SetDefault("click_flusher.queue_size", 20000)
+	viper.SetDefault("click_flusher.workers", 4)
+	viper.SetDefault("click_flusher.overflow_policy", "block")
+	viper.SetDefault("click_flusher.flush_timeout", 30)
+	viper.SetDefault("click_flusher.insert_mode", "copy")
+	viper.SetDefault("click_flusher.max_retries", 5)
+	viper.SetDefault("click_flusher.retry_base_delay", 200)
+	viper.SetDefault("click_flusher.retry_max_delay", 10000)
+	viper.SetDefault("click_flusher.dead_letter_dir", "./data/deadletter")
+
+	// Журнал кликов
+	viper.SetDefault("click_journal.enabled", false)
+	viper.SetDefault("click_journal.dir", "./data/wal")
+	viper.SetDefault("click_journal.sync_policy", "interval")
+	viper.SetDefault("click_journal.sync_interval", 100)
+
+	// Дедупликация кликов
+	viper.SetDefault("click_dedup.mode", "off")
+	viper.SetDefault("click_dedup.window", 10)
+	viper.SetDefault("click_dedup.max_entries", 500000)
+
+	// Фильтр ботов
+	viper.SetDefault("bot_filter.enabled", true)
+	viper.SetDefault("bot_filter.action", "flag")
+	viper.SetDefault("bot_filter.rules_file", "")
+	viper.SetDefault("bot_filter.reload_interval", 10)
 
 	// Агрегация статистики
+	viper.SetDefault("stats_aggregator.enabled", false)
 	viper.SetDefault("stats_aggregator.interval", 60)
+	viper.SetDefault("stats_aggregator.batch_size", 100000)
+
+	// Свертка статистики
+	viper.SetDefault("stats_rollup.enabled", true)
+	viper.SetDefault("stats_rollup.interval", 60)
+	viper.SetDefault("stats_rollup.settle_delay", 120)
+	viper.SetDefault("stats_rollup.batch_size", 5000)
+
+	// Секционирование кликов
+	viper.SetDefault("click_partitions.interval", "day")
+	viper.SetDefault("click_partitions.premake", 7)
+	viper.SetDefault("click_partitions.check_interval", 3600)
+	viper.SetDefault("click_partitions.retention_mode", "drop")
+
+	// Удаление устаревших данных
+	viper.SetDefault("retention.enabled", false)
+	viper.SetDefault("retention.interval", 3600)
+	viper.SetDefault("retention.clicks_days", 30)
+	viper.SetDefault("retention.stats_days", 90)
+	viper.SetDefault("retention.hourly_days", 730)
+	viper.SetDefault("retention.daily_days", 0)
+	viper.SetDefault("retention.chunk_size", 10000)
+	viper.SetDefault("retention.chunk_pause", 100)
+
+	// Поток live-статистики
+	viper.SetDefault("live_stats.enabled", true)
+	viper.SetDefault("live_stats.buffer_size", 64)
+	viper.SetDefault("live_stats.tick_interval", 1000)
+	viper.SetDefault("live_stats.heartbeat", 15)
+	viper.SetDefault("live_stats.max_subscribers", 1000)
+
+	// Доступ к /api/v1 по API ключам
+	viper.SetDefault("auth.enabled", true)
+	viper.SetDefault("auth.api_keys", []string{})
+	viper.SetDefault("auth.cache_ttl", 60)
 }
 
 // validateConfig валидирует конфигурацию
@@ -207,10 +401,201 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("click flusher interval must be positive")
 	}
 
-	if config.StatsAggregator.Interval <= 0 {
+	if config.ClickFlusher.QueueSize <= 0 {
+		return fmt.Errorf("click flusher queue size must be positive")
+	}
+
+	if config.ClickFlusher.Workers <= 0 {
+		return fmt.Errorf("click flusher workers must be positive")
+	}
+
+	switch config.ClickFlusher.OverflowPolicy {
+	case "block", "drop_newest", "reject":
+	default:
+		return fmt.Errorf("invalid click flusher overflow policy: %s (must be 'block', 'drop_newest' or 'reject')", config.ClickFlusher.OverflowPolicy)
+	}
+
+	switch config.ClickFlusher.InsertMode {
+	case "copy", "batch":
+	default:
+		return fmt.Errorf("invalid click flusher insert mode: %s (must be 'copy' or 'batch')", config.ClickFlusher.InsertMode)
+	}
+
+	if config.ClickFlusher.MaxRetries < 0 {
+		return fmt.Errorf("click flusher max retries must be non-negative")
+	}
+
+	if config.ClickFlusher.RetryBaseDelay <= 0 {
+		return fmt.Errorf("click flusher retry base delay must be positive")
+	}
+
+	if config.ClickFlusher.RetryMaxDelay < config.ClickFlusher.RetryBaseDelay {
+		return fmt.Errorf("click flusher retry max delay must not be less than retry base delay")
+	}
+
+	if config.ClickJournal.Enabled {
+		if config.ClickJournal.Dir == "" {
+			return fmt.Errorf("click journal dir is required")
+		}
+
+		switch config.ClickJournal.SyncPolicy {
+		case "always", "interval", "none":
+		default:
+			return fmt.Errorf("invalid click journal sync policy: %s (must be 'always', 'interval' or 'none')", config.ClickJournal.SyncPolicy)
+		}
+
+		if config.ClickJournal.SyncPolicy == "interval" && config.ClickJournal.SyncInterval <= 0 {
+			return fmt.Errorf("click journal sync interval must be positive")
+		}
+	}
+
+	switch config.ClickDedup.Mode {
+	case "off":
+	case "suppress", "flag":
+		if config.ClickDedup.Window <= 0 {
+			return fmt.Errorf("click dedup window must be positive")
+		}
+		if config.ClickDedup.MaxEntries <= 0 {
+			return fmt.Errorf("click dedup max entries must be positive")
+		}
+	default:
+		return fmt.Errorf("invalid click dedup mode: %s (must be 'off', 'suppress' or 'flag')", config.ClickDedup.Mode)
+	}
+
+	if config.BotFilter.Enabled {
+		switch config.BotFilter.Action {
+		case "flag", "discard":
+		default:
+			return fmt.Errorf("invalid bot filter action: %s (must be 'flag' or 'discard')", config.BotFilter.Action)
+		}
+
+		if config.BotFilter.ReloadInterval < 0 {
+			return fmt.Errorf("bot filter reload interval cannot be negative")
+		}
+	}
+
+	if config.Metrics.Enabled && (config.Metrics.Path == "" || config.Metrics.Path[0] != '/') {
+		return fmt.Errorf("metrics path must start with '/'")
+	}
+
+	if config.RateLimiting.Enabled {
+		if err := validateRateLimiting(&config.RateLimiting); err != nil {
+			return err
+		}
+	}
+
+	if config.StatsAggregator.Enabled && config.StatsAggregator.Interval <= 0 {
 		return fmt.Errorf("stats aggregator interval must be positive")
 	}
 
+	if config.StatsAggregator.BatchSize < 0 {
+		return fmt.Errorf("stats aggregator batch size cannot be negative")
+	}
+
+	if config.StatsRollup.Enabled {
+		if config.StatsRollup.Interval <= 0 {
+			return fmt.Errorf("stats rollup interval must be positive")
+		}
+		// Свертка пропускает строки, транзакция записи которых длилась дольше задержки;
+		// запись батча кликов ограничена flush_timeout
+		if config.StatsRollup.SettleDelay < minStatsRollupSettleDelay {
+			return fmt.Errorf("stats rollup settle delay must be at least %d seconds", minStatsRollupSettleDelay)
+		}
+		if config.StatsRollup.SettleDelay <= config.ClickFlusher.FlushTimeout {
+			return fmt.Errorf("stats rollup settle delay must be greater than click flusher flush timeout")
+		}
+		if config.StatsRollup.BatchSize <= 0 {
+			return fmt.Errorf("stats rollup batch size must be positive")
+		}
+	}
+
+	if err := validateClickPartitions(&config.ClickPartitions); err != nil {
+		return err
+	}
+
+	if config.Retention.Enabled {
+		if err := validateRetention(&config.Retention); err != nil {
+			return err
+		}
+	}
+
+	if config.LiveStats.Enabled {
+		if err := validateLiveStats(&config.LiveStats); err != nil {
+			return err
+		}
+	}
+
+	if err := validateAuth(&config.Auth); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateAuth проверяет параметры доступа: короткий ключ легко подобрать
+func validateAuth(config *AuthConfig) error {
+	if config.CacheTTL <= 0 {
+		return fmt.Errorf("auth cache ttl must be positive")
+	}
+
+	for i, key := range config.APIKeys {
+		if len(key) < minAPIKeyLength {
+			return fmt.Errorf("auth api key #%d is too short (minimum %d characters)", i+1, minAPIKeyLength)
+		}
+	}
+	return nil
+}
+
+// validateLiveStats проверяет параметры потока live-статистики
+func validateLiveStats(config *LiveStatsConfig) error {
+	if config.BufferSize <= 0 {
+		return fmt.Errorf("live stats buffer size must be positive")
+	}
+	if config.TickInterval <= 0 {
+		return fmt.Errorf("live stats tick interval must be positive")
+	}
+	if config.Heartbeat <= 0 {
+		return fmt.Errorf("live stats heartbeat must be positive")
+	}
+	if config.MaxSubscribers < 0 {
+		return fmt.Errorf("live stats max subscribers cannot be negative")
+	}
+	return nil
+}
+
+// validateRateLimiting валидирует правила ограничения частоты запросов
+func validateRateLimiting(config *RateLimitingConfig) error {
+	if config.MaxKeys < 0 {
+		return fmt.Errorf("rate limiting max keys cannot be negative")
+	}
+
+	if config.IdleTTL < 0 {
+		return fmt.Errorf("rate limiting idle TTL cannot be negative")
+	}
+
+	names := make(map[string]bool, len(config.Rules))
+	for i, rule := range config.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("rate limit rule #%d: name is required", i+1)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("rate limit rule %q: duplicate name", rule.Name)
+		}
+		names[rule.Name] = true
+
+		for _, part := range strings.Split(rule.Key, "+") {
+			switch strings.TrimSpace(part) {
+			case "global", "ip", "banner", "api_key":
+			default:
+				return fmt.Errorf("rate limit rule %q: invalid key %q (must be 'global', 'ip', 'banner', 'api_key' or a '+' combination)", rule.Name, rule.Key)
+			}
+		}
+
+		if rule.RPS <= 0 || rule.Burst <= 0 {
+			return fmt.Errorf("rate limit rule %q: rps and burst must be positive", rule.Name)
+		}
+	}
+
 	return nil
 }
 
@@ -221,3 +606,62 @@ func (c *DatabaseConfig) GetDSN() string {
 		c.Host, c.Port, c.User, c.Password, c.Database, c.SSLMode,
 	)
 }
+
+// validateClickPartitions проверяет параметры секционирования кликов
+func validateClickPartitions(config *ClickPartitionsConfig) error {
+	if config.Interval != "day" && config.Interval != "month" {
+		return fmt.Errorf("invalid click partitions interval: %s (expected day or month)", config.Interval)
+	}
+	if config.Premake <= 0 {
+		return fmt.Errorf("click partitions premake must be positive")
+	}
+	if config.CheckInterval <= 0 {
+		return fmt.Errorf("click partitions check interval must be positive")
+	}
+	if config.RetentionMode != "drop" && config.RetentionMode != "detach" {
+		return fmt.Errorf("invalid click partitions retention mode: %s (expected drop or detach)", config.RetentionMode)
+	}
+	return nil
+}
+
+// validateRetention проверяет сроки хранения. Более крупные бакеты должны храниться
+// не меньше мелких: запросы читают свернутые таблицы вместо минутной статистики
+func validateRetention(config *RetentionConfig) error {
+	if config.Interval <= 0 {
+		return fmt.Errorf("retention interval must be positive")
+	}
+	if config.ChunkSize <= 0 {
+		return fmt.Errorf("retention chunk size must be positive")
+	}
+	if config.ChunkPause < 0 {
+		return fmt.Errorf("retention chunk pause cannot be negative")
+	}
+
+	horizons := []struct {
+		name string
+		days int
+	}{
+		{"clicks_days", config.ClicksDays},
+		{"stats_days", config.StatsDays},
+		{"hourly_days", config.HourlyDays},
+		{"daily_days", config.DailyDays},
+	}
+	for _, h := range horizons {
+		if h.days < 0 {
+			return fmt.Errorf("retention %s cannot be negative", h.name)
+		}
+	}
+
+	// 0 - бессрочно, поэтому сравниваются только заданные сроки
+	for i := 2; i < len(horizons); i++ {
+		prev, cur := horizons[i-1], horizons[i]
+		if prev.days == 0 && cur.days > 0 {
+			return fmt.Errorf("retention %s must be 0 when %s is 0", cur.name, prev.name)
+		}
+		if cur.days > 0 && cur.days < prev.days {
+			return fmt.Errorf("retention %s must not be less than %s", cur.name, prev.name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/clickcounter/app/internal/domain/apikey"
+	"github.com/clickcounter/app/internal/domain/tenant"
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// APIKeyRepository реализует интерфейс apikey.Repository для PostgreSQL
+type APIKeyRepository struct {
+	db     *DB
+	logger *logrus.Logger
+}
+
+// NewAPIKeyRepository создает новый экземпляр репозитория API ключей
+func NewAPIKeyRepository(db *DB, logger *logrus.Logger) *APIKeyRepository {
+	if logger == nil {
+		logger = logrus.New()
+	}
+
+	return &APIKeyRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// apiKeyColumns колонки ключа в порядке scanAPIKey
+const apiKeyColumns = `id, tenant_id, name, prefix, key_hash, scopes, banner_ids, created_at, expires_at, revoked_at`
+
+// GetByHash возвращает ключ по SHA-256 (в том числе отозванный или истекший).
+// Поиск не ограничивается арендатором: арендатор запроса определяется по найденному ключу
+func (r *APIKeyRepository) GetByHash(ctx context.Context, hash string) (*apikey.Key, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1`
+
+	key, err := scanAPIKey(r.db.Pool.QueryRow(ctx, query, hash))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, apikey.ErrKeyNotFound
+		}
+		r.logger.WithError(err).Error("Failed to get api key by hash")
+		return nil, fmt.Errorf("failed to get api key by hash: %w", err)
+	}
+
+	return key, nil
+}
+
+// List возвращает все ключи арендатора запроса, включая отозванные
+func (r *APIKeyRepository) List(ctx context.Context) ([]*apikey.Key, error) {
+	scope, args := tenantScope(ctx, "tenant_id", nil)
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE TRUE` + scope + ` ORDER BY id ASC`
+
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to list api keys")
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*apikey.Key
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			r.logger.WithError(err).Error("Failed to scan api key row")
+			return nil, fmt.Errorf("failed to scan api key row: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.WithError(err).Error("Error iterating api key rows")
+		return nil, fmt.Errorf("error iterating api key rows: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Create сохраняет ключ арендатора запроса (без арендатора - арендатора по умолчанию),
+// заполняя ID, TenantID и CreatedAt
+func (r *APIKeyRepository) Create(ctx context.Context, key *apikey.Key) error {
+	query := `
+		INSERT INTO api_keys (tenant_id, name, prefix, key_hash, scopes, banner_ids, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, tenant_id, created_at
+	`
+
+	bannerIDs := key.BannerIDs
+	if bannerIDs == nil {
+		bannerIDs = []int64{}
+	}
+
+	err := r.db.Pool.QueryRow(ctx, query,
+		tenant.IDOrDefault(ctx),
+		key.Name,
+		key.Prefix,
+		key.Hash,
+		scopeStrings(key.Scopes),
+		bannerIDs,
+		key.ExpiresAt,
+	).Scan(&key.ID, &key.TenantID, &key.CreatedAt)
+	if err != nil {
+		r.logger.WithError(err).WithField("name", key.Name).Error("Failed to create api key")
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return nil
+}
+
+// Revoke отзывает ключ арендатора запроса и возвращает его; повторный отзыв сохраняет исходное время
+func (r *APIKeyRepository) Revoke(ctx context.Context, id int64) (*apikey.Key, error) {
+	scope, args := tenantScope(ctx, "tenant_id", []any{id})
+	query := `
+		UPDATE api_keys
+		SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE id = $1` + scope + `
+		RETURNING ` + apiKeyColumns
+
+	key, err := scanAPIKey(r.db.Pool.QueryRow(ctx, query, args...))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, apikey.ErrKeyNotFound
+		}
+		r.logger.WithError(err).WithField("api_key_id", id).Error("Failed to revoke api key")
+		return nil, fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// scanAPIKey читает ключ из строки с колонками apiKeyColumns
+func scanAPIKey(row pgx.Row) (*apikey.Key, error) {
+	var (
+		key    apikey.Key
+		scopes []string
+	)
+	err := row.Scan(
+		&key.ID,
+		&key.TenantID,
+		&key.Name,
+		&key.Prefix,
+		&key.Hash,
+		&scopes,
+		&key.BannerIDs,
+		&key.CreatedAt,
+		&key.ExpiresAt,
+		&key.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Scopes = make([]apikey.Scope, len(scopes))
+	for i, scope := range scopes {
+		key.Scopes[i] = apikey.Scope(scope)
+	}
+
+	return &key, nil
+}
+
+// scopeStrings преобразует области доступа для записи в TEXT[]
+func scopeStrings(scopes []apikey.Scope) []string {
+	result := make([]string, len(scopes))
+	for i, scope := range scopes {
+		result[i] = string(scope)
+	}
+	return result
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/clickcounter/app/internal/domain/banner"
+	"github.com/clickcounter/app/internal/domain/tenant"
 	"github.com/jackc/pgx/v5"
 	"github.com/sirupsen/logrus"
 )
@@ -27,21 +28,25 @@ func NewBannerRepository(db *DB, logger *logrus.Logger) *BannerRepository {
 	}
 }
 
-// GetByID возвращает баннер по ID
+// GetByID возвращает баннер по ID (в том числе неактивный, проверка активности - на стороне сервиса).
+// Баннер другого арендатора не находится
 func (r *BannerRepository) GetByID(ctx context.Context, id int64) (*banner.Banner, error) {
+	scope, args := tenantScope(ctx, "tenant_id", []any{id})
 	query := `
-		SELECT id, name, created_at, updated_at, is_active
+		SELECT id, tenant_id, name, created_at, updated_at, is_active, starts_at, ends_at
 		FROM banners
-		WHERE id = $1 AND is_active = true
-	`
+		WHERE id = $1` + scope
 
 	var b banner.Banner
-	err := r.db.Pool.QueryRow(ctx, query, id).Scan(
+	err := r.db.Pool.QueryRow(ctx, query, args...).Scan(
 		&b.ID,
+		&b.TenantID,
 		&b.Name,
 		&b.CreatedAt,
 		&b.UpdatedAt,
 		&b.IsActive,
+		&b.StartsAt,
+		&b.EndsAt,
 	)
 
 	if err != nil {
@@ -55,21 +60,112 @@ func (r *BannerRepository) GetByID(ctx context.Context, id int64) (*banner.Banne
 	return &b, nil
 }
 
-// Exists проверяет существование баннера
-func (r *BannerRepository) Exists(ctx context.Context, id int64) (bool, error) {
-	query := `
-		SELECT EXISTS(
-			SELECT 1 FROM banners
-			WHERE id = $1 AND is_active = true
+// List возвращает страницу баннеров арендатора запроса и общее количество баннеров, подходящих под фильтр
+func (r *BannerRepository) List(ctx context.Context, filter banner.ListFilter) ([]*banner.Banner, int64, error) {
+	scope, countArgs := tenantScope(ctx, "tenant_id", []any{filter.Active})
+	args := append(countArgs, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`
+		SELECT id, tenant_id, name, created_at, updated_at, is_active, starts_at, ends_at, COUNT(*) OVER() AS total
+		FROM banners
+		WHERE ($1::boolean IS NULL OR is_active = $1)%s
+		ORDER BY id ASC
+		LIMIT $%d OFFSET $%d
+	`, scope, len(args)-1, len(args))
+
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"limit":  filter.Limit,
+			"offset": filter.Offset,
+		}).Error("Failed to list banners")
+		return nil, 0, fmt.Errorf("failed to list banners: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		banners []*banner.Banner
+		total   int64
+	)
+	for rows.Next() {
+		var b banner.Banner
+		err := rows.Scan(
+			&b.ID,
+			&b.TenantID,
+			&b.Name,
+			&b.CreatedAt,
+			&b.UpdatedAt,
+			&b.IsActive,
+			&b.StartsAt,
+			&b.EndsAt,
+			&total,
 		)
+		if err != nil {
+			r.logger.WithError(err).Error("Failed to scan banner row")
+			return nil, 0, fmt.Errorf("failed to scan banner row: %w", err)
+		}
+		banners = append(banners, &b)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.WithError(err).Error("Error iterating banner rows")
+		return nil, 0, fmt.Errorf("error iterating banner rows: %w", err)
+	}
+
+	// Страница за пределами списка: общее количество считаем отдельно
+	if len(banners) == 0 && filter.Offset > 0 {
+		countQuery := `
+			SELECT COUNT(*)
+			FROM banners
+			WHERE ($1::boolean IS NULL OR is_active = $1)` + scope
+		if err := r.db.Pool.QueryRow(ctx, countQuery, countArgs...).Scan(&total); err != nil {
+			r.logger.WithError(err).Error("Failed to count banners")
+			return nil, 0, fmt.Errorf("failed to count banners: %w", err)
+		}
+	}
+
+	return banners, total, nil
+}
+
+// Create создает баннер арендатора запроса (без арендатора - арендатора по умолчанию)
+func (r *BannerRepository) Create(ctx context.Context, b *banner.Banner) error {
+	query := `
+		INSERT INTO banners (tenant_id, name, is_active, starts_at, ends_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		RETURNING id, tenant_id, created_at, updated_at
+	`
+
+	err := r.db.Pool.QueryRow(ctx, query, tenant.IDOrDefault(ctx), b.Name, b.IsActive, b.StartsAt, b.EndsAt).Scan(
+		&b.ID,
+		&b.TenantID,
+		&b.CreatedAt,
+		&b.UpdatedAt,
+	)
+	if err != nil {
+		r.logger.WithError(err).WithField("name", b.Name).Error("Failed to create banner")
+		return fmt.Errorf("failed to create banner: %w", err)
+	}
+
+	return nil
+}
+
+// Update сохраняет название, флаг активности и окно показа баннера арендатора запроса
+func (r *BannerRepository) Update(ctx context.Context, b *banner.Banner) error {
+	scope, args := tenantScope(ctx, "tenant_id", []any{b.ID, b.Name, b.IsActive, b.StartsAt, b.EndsAt})
+	query := `
+		UPDATE banners
+		SET name = $2, is_active = $3, starts_at = $4, ends_at = $5, updated_at = NOW()
+		WHERE id = $1` + scope + `
+		RETURNING updated_at
 	`
 
-	var exists bool
-	err := r.db.Pool.QueryRow(ctx, query, id).Scan(&exists)
+	err := r.db.Pool.QueryRow(ctx, query, args...).Scan(&b.UpdatedAt)
 	if err != nil {
-		r.logger.WithError(err).WithField("banner_id", id).Error("Failed to check banner existence")
-		return false, fmt.Errorf("failed to check banner existence: %w", err)
+		if err == pgx.ErrNoRows {
+			return banner.ErrBannerNotFound
+		}
+		r.logger.WithError(err).WithField("banner_id", b.ID).Error("Failed to update banner")
+		return fmt.Errorf("failed to update banner: %w", err)
 	}
 
-	return exists, nil
+	return nil
 }
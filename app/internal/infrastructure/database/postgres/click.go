@@ -3,27 +3,55 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"net/netip"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/clickcounter/app/internal/domain/click"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/sirupsen/logrus"
 )
 
+// InsertMode определяет способ массовой вставки кликов
+type InsertMode string
+
+const (
+	// InsertModeBatch - INSERT на каждый клик внутри pgx.Batch
+	InsertModeBatch InsertMode = "batch"
+	// InsertModeCopy - COPY protocol с откатом на InsertModeBatch при ошибке
+	InsertModeCopy InsertMode = "copy"
+)
+
+// ParseInsertMode преобразует строку конфигурации в способ вставки
+func ParseInsertMode(value string) (InsertMode, error) {
+	switch mode := InsertMode(value); mode {
+	case InsertModeBatch, InsertModeCopy:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid click insert mode: %s", value)
+	}
+}
+
+// clickColumns колонки таблицы clicks для массовой вставки
+var clickColumns = []string{"banner_id", "timestamp", "user_ip", "user_agent", "is_duplicate", "is_bot", "event_id"}
+
 // ClickRepository реализует интерфейс click.Repository для PostgreSQL
 type ClickRepository struct {
-	db     *DB
-	logger *logrus.Logger
+	db         *DB
+	logger     *logrus.Logger
+	insertMode InsertMode
 }
 
 // ClickBatchRepository реализует интерфейс click.BatchRepository для PostgreSQL
 type ClickBatchRepository struct {
-	db        *DB
-	logger    *logrus.Logger
-	batch     []*click.Click
-	batchSize int
-	mutex     sync.RWMutex
+	db         *DB
+	logger     *logrus.Logger
+	batch      []*click.Click
+	batchSize  int
+	insertMode InsertMode
+	mutex      sync.RWMutex
 }
 
 // NewClickRepository создает новый экземпляр репозитория кликов
@@ -33,8 +61,9 @@ func NewClickRepository(db *DB, logger *logrus.Logger) *ClickRepository {
 	}
 
 	return &ClickRepository{
-		db:     db,
-		logger: logger,
+		db:         db,
+		logger:     logger,
+		insertMode: InsertModeBatch,
 	}
 }
 
@@ -49,27 +78,42 @@ func NewClickBatchRepository(db *DB, logger *logrus.Logger, batchSize int) *Clic
 	}
 
 	return &ClickBatchRepository{
-		db:        db,
-		logger:    logger,
-		batch:     make([]*click.Click, 0, batchSize),
-		batchSize: batchSize,
+		db:         db,
+		logger:     logger,
+		batch:      make([]*click.Click, 0, batchSize),
+		batchSize:  batchSize,
+		insertMode: InsertModeBatch,
 	}
 }
 
+// SetInsertMode устанавливает способ массовой вставки кликов
+func (r *ClickRepository) SetInsertMode(mode InsertMode) {
+	r.insertMode = mode
+}
+
 // Create создает новый клик
 func (r *ClickRepository) Create(ctx context.Context, c *click.Click) error {
 	query := `
-		INSERT INTO clicks (banner_id, timestamp, user_ip, user_agent)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO clicks (banner_id, timestamp, user_ip, user_agent, is_duplicate, is_bot, event_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id
 	`
 
-	err := r.db.Pool.QueryRow(ctx, query,
-		c.BannerID,
-		c.Timestamp,
-		c.UserIP,
-		c.UserAgent,
-	).Scan(&c.ID)
+	err := r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := assignClickXactID(ctx, tx); err != nil {
+			return err
+		}
+
+		return tx.QueryRow(ctx, query,
+			c.BannerID,
+			c.Timestamp,
+			clickUserIP(c.UserIP),
+			c.UserAgent,
+			c.Duplicate,
+			c.Bot,
+			clickEventID(c.EventID),
+		).Scan(&c.ID)
+	})
 
 	if err != nil {
 		r.logger.WithError(err).WithField("banner_id", c.BannerID).Error("Failed to create click")
@@ -85,48 +129,136 @@ func (r *ClickRepository) CreateBatch(ctx context.Context, clicks []*click.Click
 		return nil
 	}
 
-	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
-		query := `
-			INSERT INTO clicks (banner_id, timestamp, user_ip, user_agent)
-			VALUES ($1, $2, $3, $4)
-		`
+	if err := saveClicks(ctx, r.db, r.logger, r.insertMode, clicks); err != nil {
+		return err
+	}
 
-		batch := &pgx.Batch{}
-		for _, c := range clicks {
-			batch.Queue(query, c.BannerID, c.Timestamp, c.UserIP, c.UserAgent)
+	r.logger.WithField("count", len(clicks)).Info("Batch clicks created successfully")
+	return nil
+}
+
+// CreateBatchWithCounters сохраняет клики и инкременты минутной статистики в одной транзакции
+func (r *ClickRepository) CreateBatchWithCounters(ctx context.Context, clicks []*click.Click, counters click.MinuteCounters) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	if err := saveClicksWithCounters(ctx, r.db, r.logger, r.insertMode, clicks, counters); err != nil {
+		return err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"count":   len(clicks),
+		"buckets": len(counters),
+	}).Info("Batch clicks created with stats counters successfully")
+	return nil
+}
+
+// CopyBatch вставляет клики через COPY protocol без отката на batch insert
+func (r *ClickRepository) CopyBatch(ctx context.Context, clicks []*click.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	return copyClicksTx(ctx, r.db, clicks)
+}
+
+// InsertBatch вставляет клики через pgx.Batch с INSERT на каждый клик
+func (r *ClickRepository) InsertBatch(ctx context.Context, clicks []*click.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	return insertClicks(ctx, r.db, r.logger, clicks)
+}
+
+// FilterUnsaved возвращает клики, ключ идемпотентности которых еще не сохранен в clicks.
+// Поиск ограничен временем кликов, чтобы затрагивать только их секции
+func (r *ClickRepository) FilterUnsaved(ctx context.Context, clicks []*click.Click) ([]*click.Click, error) {
+	var (
+		eventIDs []pgtype.UUID
+		from, to time.Time
+	)
+	for _, c := range clicks {
+		id, ok := clickEventID(c.EventID).(pgtype.UUID)
+		if !ok {
+			continue
+		}
+		eventIDs = append(eventIDs, id)
+		if from.IsZero() || c.Timestamp.Before(from) {
+			from = c.Timestamp
 		}
+		if to.IsZero() || c.Timestamp.After(to) {
+			to = c.Timestamp
+		}
+	}
+
+	if len(eventIDs) == 0 {
+		return clicks, nil
+	}
 
-		results := tx.SendBatch(ctx, batch)
-		defer results.Close()
+	// Границы расширены на секунду: PostgreSQL хранит время с точностью до микросекунды
+	query := `
+		SELECT event_id::text
+		FROM clicks
+		WHERE event_id = ANY($1) AND timestamp >= $2 AND timestamp <= $3
+	`
 
-		for i := 0; i < len(clicks); i++ {
-			_, err := results.Exec()
-			if err != nil {
-				r.logger.WithError(err).WithField("batch_index", i).Error("Failed to execute batch insert")
-				return fmt.Errorf("failed to execute batch insert at index %d: %w", i, err)
-			}
+	rows, err := r.db.Pool.Query(ctx, query, eventIDs, from.Add(-time.Second), to.Add(time.Second))
+	if err != nil {
+		r.logger.WithError(err).WithField("count", len(eventIDs)).Error("Failed to check saved clicks")
+		return nil, fmt.Errorf("failed to check saved clicks: %w", err)
+	}
+	defer rows.Close()
+
+	saved := make(map[string]bool)
+	for rows.Next() {
+		var eventID string
+		if err := rows.Scan(&eventID); err != nil {
+			return nil, fmt.Errorf("failed to scan saved click: %w", err)
 		}
+		saved[eventID] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to check saved clicks: %w", err)
+	}
 
-		r.logger.WithField("count", len(clicks)).Info("Batch clicks created successfully")
-		return nil
-	})
+	if len(saved) == 0 {
+		return clicks, nil
+	}
+
+	unsaved := make([]*click.Click, 0, len(clicks)-len(saved))
+	for _, c := range clicks {
+		if !saved[strings.ToLower(c.EventID)] {
+			unsaved = append(unsaved, c)
+		}
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"count":   len(clicks),
+		"skipped": len(clicks) - len(unsaved),
+	}).Warn("Skipping clicks that are already saved")
+
+	return unsaved, nil
 }
 
-// GetByID возвращает клик по ID
+// GetByID возвращает клик по ID. Клик баннера другого арендатора не находится
 func (r *ClickRepository) GetByID(ctx context.Context, id int64) (*click.Click, error) {
+	scope, args := bannerTenantScope(ctx, "banner_id", []any{id})
 	query := `
-		SELECT id, banner_id, timestamp, user_ip, user_agent
+		SELECT id, banner_id, timestamp, COALESCE(host(user_ip), ''), user_agent, is_duplicate, is_bot
 		FROM clicks
-		WHERE id = $1
-	`
+		WHERE id = $1` + scope
 
 	var c click.Click
-	err := r.db.Pool.QueryRow(ctx, query, id).Scan(
+	err := r.db.Pool.QueryRow(ctx, query, args...).Scan(
 		&c.ID,
 		&c.BannerID,
 		&c.Timestamp,
 		&c.UserIP,
 		&c.UserAgent,
+		&c.Duplicate,
+		&c.Bot,
 	)
 
 	if err != nil {
@@ -142,14 +274,15 @@ func (r *ClickRepository) GetByID(ctx context.Context, id int64) (*click.Click,
 
 // GetByBannerID возвращает клики по ID баннера за период
 func (r *ClickRepository) GetByBannerID(ctx context.Context, bannerID int64, from, to time.Time) ([]*click.Click, error) {
+	scope, args := bannerTenantScope(ctx, "banner_id", []any{bannerID, from, to})
 	query := `
-		SELECT id, banner_id, timestamp, user_ip, user_agent
+		SELECT id, banner_id, timestamp, COALESCE(host(user_ip), ''), user_agent, is_duplicate, is_bot
 		FROM clicks
-		WHERE banner_id = $1 AND timestamp >= $2 AND timestamp <= $3
+		WHERE banner_id = $1 AND timestamp >= $2 AND timestamp <= $3` + scope + `
 		ORDER BY timestamp ASC
 	`
 
-	rows, err := r.db.Pool.Query(ctx, query, bannerID, from, to)
+	rows, err := r.db.Pool.Query(ctx, query, args...)
 	if err != nil {
 		r.logger.WithError(err).WithFields(logrus.Fields{
 			"banner_id": bannerID,
@@ -169,6 +302,8 @@ func (r *ClickRepository) GetByBannerID(ctx context.Context, bannerID int64, fro
 			&c.Timestamp,
 			&c.UserIP,
 			&c.UserAgent,
+			&c.Duplicate,
+			&c.Bot,
 		)
 		if err != nil {
 			r.logger.WithError(err).Error("Failed to scan click row")
@@ -187,15 +322,17 @@ func (r *ClickRepository) GetByBannerID(ctx context.Context, bannerID int64, fro
 
 // GetByBannerIDWithPagination возвращает клики с пагинацией
 func (r *ClickRepository) GetByBannerIDWithPagination(ctx context.Context, bannerID int64, from, to time.Time, limit, offset int) ([]*click.Click, error) {
-	query := `
-		SELECT id, banner_id, timestamp, user_ip, user_agent
+	scope, args := bannerTenantScope(ctx, "banner_id", []any{bannerID, from, to})
+	args = append(args, limit, offset)
+	query := fmt.Sprintf(`
+		SELECT id, banner_id, timestamp, COALESCE(host(user_ip), ''), user_agent, is_duplicate, is_bot
 		FROM clicks
-		WHERE banner_id = $1 AND timestamp >= $2 AND timestamp <= $3
+		WHERE banner_id = $1 AND timestamp >= $2 AND timestamp <= $3%s
 		ORDER BY timestamp ASC
-		LIMIT $4 OFFSET $5
-	`
+		LIMIT $%d OFFSET $%d
+	`, scope, len(args)-1, len(args))
 
-	rows, err := r.db.Pool.Query(ctx, query, bannerID, from, to, limit, offset)
+	rows, err := r.db.Pool.Query(ctx, query, args...)
 	if err != nil {
 		r.logger.WithError(err).WithFields(logrus.Fields{
 			"banner_id": bannerID,
@@ -217,6 +354,8 @@ func (r *ClickRepository) GetByBannerIDWithPagination(ctx context.Context, banne
 			&c.Timestamp,
 			&c.UserIP,
 			&c.UserAgent,
+			&c.Duplicate,
+			&c.Bot,
 		)
 		if err != nil {
 			r.logger.WithError(err).Error("Failed to scan click row")
@@ -235,14 +374,14 @@ func (r *ClickRepository) GetByBannerIDWithPagination(ctx context.Context, banne
 
 // CountByBannerID возвращает количество кликов по баннеру за период
 func (r *ClickRepository) CountByBannerID(ctx context.Context, bannerID int64, from, to time.Time) (int64, error) {
+	scope, args := bannerTenantScope(ctx, "banner_id", []any{bannerID, from, to})
 	query := `
 		SELECT COUNT(*)
 		FROM clicks
-		WHERE banner_id = $1 AND timestamp >= $2 AND timestamp <= $3
-	`
+		WHERE banner_id = $1 AND timestamp >= $2 AND timestamp <= $3` + scope
 
 	var count int64
-	err := r.db.Pool.QueryRow(ctx, query, bannerID, from, to).Scan(&count)
+	err := r.db.Pool.QueryRow(ctx, query, args...).Scan(&count)
 	if err != nil {
 		r.logger.WithError(err).WithFields(logrus.Fields{
 			"banner_id": bannerID,
@@ -255,16 +394,17 @@ func (r *ClickRepository) CountByBannerID(ctx context.Context, bannerID int64, f
 	return count, nil
 }
 
-// GetClicksForPeriod возвращает все клики за период
+// GetClicksForPeriod возвращает все клики за период (с арендатором в контексте - только его клики)
 func (r *ClickRepository) GetClicksForPeriod(ctx context.Context, from, to time.Time) ([]*click.Click, error) {
+	scope, args := bannerTenantScope(ctx, "banner_id", []any{from, to})
 	query := `
-		SELECT id, banner_id, timestamp, user_ip, user_agent
+		SELECT id, banner_id, timestamp, COALESCE(host(user_ip), ''), user_agent, is_duplicate, is_bot
 		FROM clicks
-		WHERE timestamp >= $1 AND timestamp <= $2
+		WHERE timestamp >= $1 AND timestamp <= $2` + scope + `
 		ORDER BY timestamp ASC
 	`
 
-	rows, err := r.db.Pool.Query(ctx, query, from, to)
+	rows, err := r.db.Pool.Query(ctx, query, args...)
 	if err != nil {
 		r.logger.WithError(err).WithFields(logrus.Fields{
 			"from": from,
@@ -283,6 +423,8 @@ func (r *ClickRepository) GetClicksForPeriod(ctx context.Context, from, to time.
 			&c.Timestamp,
 			&c.UserIP,
 			&c.UserAgent,
+			&c.Duplicate,
+			&c.Bot,
 		)
 		if err != nil {
 			r.logger.WithError(err).Error("Failed to scan click row")
@@ -299,14 +441,95 @@ func (r *ClickRepository) GetClicksForPeriod(ctx context.Context, from, to time.
 	return clicks, nil
 }
 
-// DeleteOldClicks удаляет старые клики
-func (r *ClickRepository) DeleteOldClicks(ctx context.Context, before time.Time) (int64, error) {
+// likeEscaper экранирует спецсимволы шаблона LIKE
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// searchConditions строит условия WHERE поиска кликов, ограниченные арендатором запроса.
+// Курсор учитывается только с withCursor
+func searchConditions(ctx context.Context, filter click.SearchFilter, withCursor bool) (string, []any) {
+	args := []any{filter.BannerID, filter.From, filter.To}
+	conditions := []string{"banner_id = $1", "timestamp >= $2", "timestamp < $3"}
+
+	if filter.IP.IsValid() {
+		args = append(args, filter.IP)
+		conditions = append(conditions, fmt.Sprintf("user_ip <<= $%d", len(args)))
+	}
+	if filter.UserAgent != "" {
+		args = append(args, "%"+likeEscaper.Replace(filter.UserAgent)+"%")
+		conditions = append(conditions, fmt.Sprintf("user_agent ILIKE $%d", len(args)))
+	}
+	if withCursor && filter.After != nil {
+		args = append(args, filter.After.Timestamp, filter.After.ID)
+		conditions = append(conditions, fmt.Sprintf("(timestamp, id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	scope, args := bannerTenantScope(ctx, "banner_id", args)
+	return strings.Join(conditions, " AND ") + scope, args
+}
+
+// Search возвращает клики баннера, подходящие под фильтр, от новых к старым.
+// Пагинация по ключу (timestamp, id) использует индекс (banner_id, timestamp)
+// и не перечитывает предыдущие страницы, в отличие от OFFSET
+func (r *ClickRepository) Search(ctx context.Context, filter click.SearchFilter, limit int) ([]*click.Click, error) {
+	where, args := searchConditions(ctx, filter, true)
+	args = append(args, limit)
+	query := fmt.Sprintf(`
+		SELECT id, banner_id, timestamp, COALESCE(host(user_ip), ''), COALESCE(user_agent, ''), is_duplicate, is_bot
+		FROM clicks
+		WHERE %s
+		ORDER BY timestamp DESC, id DESC
+		LIMIT $%d
+	`, where, len(args))
+
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		r.logger.WithError(err).WithField("banner_id", filter.BannerID).Error("Failed to search clicks")
+		return nil, fmt.Errorf("failed to search clicks: %w", err)
+	}
+	defer rows.Close()
+
+	clicks := make([]*click.Click, 0, limit)
+	for rows.Next() {
+		var c click.Click
+		if err := rows.Scan(&c.ID, &c.BannerID, &c.Timestamp, &c.UserIP, &c.UserAgent, &c.Duplicate, &c.Bot); err != nil {
+			return nil, fmt.Errorf("failed to scan click row: %w", err)
+		}
+		clicks = append(clicks, &c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating click rows: %w", err)
+	}
+
+	return clicks, nil
+}
+
+// Count возвращает количество кликов баннера, подходящих под фильтр
+func (r *ClickRepository) Count(ctx context.Context, filter click.SearchFilter) (int64, error) {
+	where, args := searchConditions(ctx, filter, false)
+
+	var count int64
+	if err := r.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM clicks WHERE `+where, args...).Scan(&count); err != nil {
+		r.logger.WithError(err).WithField("banner_id", filter.BannerID).Error("Failed to count clicks")
+		return 0, fmt.Errorf("failed to count clicks: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteOldClicks удаляет порцию старых кликов. Клики с ID больше maxID не удаляются
+// (0 - без ограничения): они еще не учтены фоновой агрегацией
+func (r *ClickRepository) DeleteOldClicks(ctx context.Context, before time.Time, maxID int64, limit int) (int64, error) {
 	query := `
 		DELETE FROM clicks
-		WHERE timestamp < $1
+		WHERE id IN (
+			SELECT id FROM clicks
+			WHERE timestamp < $1 AND ($2::bigint = 0 OR id <= $2::bigint)
+			LIMIT $3
+		)
 	`
 
-	result, err := r.db.Pool.Exec(ctx, query, before)
+	result, err := r.db.Pool.Exec(ctx, query, before, maxID, limit)
 	if err != nil {
 		r.logger.WithError(err).WithField("before", before).Error("Failed to delete old clicks")
 		return 0, fmt.Errorf("failed to delete old clicks: %w", err)
@@ -316,7 +539,8 @@ func (r *ClickRepository) DeleteOldClicks(ctx context.Context, before time.Time)
 	r.logger.WithFields(logrus.Fields{
 		"deleted": deleted,
 		"before":  before,
-	}).Info("Old clicks deleted successfully")
+		"max_id":  maxID,
+	}).Debug("Old clicks deleted")
 
 	return deleted, nil
 }
@@ -349,31 +573,12 @@ func (r *ClickBatchRepository) FlushBatch(ctx context.Context) error {
 	r.batch = r.batch[:0]
 
 	// Обрабатываем батч
-	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
-		query := `
-			INSERT INTO clicks (banner_id, timestamp, user_ip, user_agent)
-			VALUES ($1, $2, $3, $4)
-		`
-
-		batch := &pgx.Batch{}
-		for _, c := range batchToProcess {
-			batch.Queue(query, c.BannerID, c.Timestamp, c.UserIP, c.UserAgent)
-		}
-
-		results := tx.SendBatch(ctx, batch)
-		defer results.Close()
-
-		for i := 0; i < len(batchToProcess); i++ {
-			_, err := results.Exec()
-			if err != nil {
-				r.logger.WithError(err).WithField("batch_index", i).Error("Failed to execute batch insert")
-				return fmt.Errorf("failed to execute batch insert at index %d: %w", i, err)
-			}
-		}
+	if err := saveClicks(ctx, r.db, r.logger, r.insertMode, batchToProcess); err != nil {
+		return err
+	}
 
-		r.logger.WithField("count", len(batchToProcess)).Info("Batch clicks flushed successfully")
-		return nil
-	})
+	r.logger.WithField("count", len(batchToProcess)).Info("Batch clicks flushed successfully")
+	return nil
 }
 
 // GetBatchSize возвращает текущий размер батча
@@ -389,3 +594,177 @@ func (r *ClickBatchRepository) SetBatchSize(size int) {
 	defer r.mutex.Unlock()
 	r.batchSize = size
 }
+
+// SetInsertMode устанавливает способ массовой вставки кликов
+func (r *ClickBatchRepository) SetInsertMode(mode InsertMode) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.insertMode = mode
+}
+
+// Bulk insert helpers
+
+// saveClicks сохраняет клики выбранным способом. COPY выполняется одной атомарной
+// командой, поэтому при ошибке ни одна строка не записана и можно безопасно
+// повторить вставку через pgx.Batch
+func saveClicks(ctx context.Context, db *DB, logger *logrus.Logger, mode InsertMode, clicks []*click.Click) error {
+	if mode == InsertModeCopy {
+		err := copyClicksTx(ctx, db, clicks)
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return err
+		}
+
+		logger.WithError(err).WithField("count", len(clicks)).Warn("COPY of clicks failed, falling back to batch insert")
+	}
+
+	return insertClicks(ctx, db, logger, clicks)
+}
+
+// saveClicksWithCounters сохраняет клики и прибавляет счетчики к статистике в одной транзакции.
+// При ошибке COPY транзакция откатывается целиком и повторяется с pgx.Batch
+func saveClicksWithCounters(ctx context.Context, db *DB, logger *logrus.Logger, mode InsertMode, clicks []*click.Click, counters click.MinuteCounters) error {
+	increments := counters.Sorted()
+
+	save := func(mode InsertMode) error {
+		return db.WithTx(ctx, func(tx pgx.Tx) error {
+			if err := lockStatsBucketsShared(ctx, tx, increments); err != nil {
+				return err
+			}
+
+			var err error
+			if mode == InsertModeCopy {
+				err = copyClicks(ctx, tx, clicks)
+			} else {
+				err = insertClicksTx(ctx, tx, logger, clicks)
+			}
+			if err != nil {
+				return err
+			}
+
+			return incrementMinuteStats(ctx, tx, increments)
+		})
+	}
+
+	if mode == InsertModeCopy {
+		err := save(InsertModeCopy)
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return err
+		}
+
+		logger.WithError(err).WithField("count", len(clicks)).Warn("COPY of clicks with stats counters failed, falling back to batch insert")
+	}
+
+	return save(InsertModeBatch)
+}
+
+// copier приемник COPY: пул соединений или транзакция
+type copier interface {
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// copyClicks вставляет клики через COPY protocol
+func copyClicks(ctx context.Context, db copier, clicks []*click.Click) error {
+	rows := make([][]any, len(clicks))
+	for i, c := range clicks {
+		rows[i] = []any{c.BannerID, c.Timestamp, clickUserIP(c.UserIP), c.UserAgent, c.Duplicate, c.Bot, clickEventID(c.EventID)}
+	}
+
+	copied, err := db.CopyFrom(ctx, pgx.Identifier{"clicks"}, clickColumns, pgx.CopyFromRows(rows))
+	if err != nil {
+		return fmt.Errorf("failed to copy clicks: %w", err)
+	}
+
+	if copied != int64(len(clicks)) {
+		return fmt.Errorf("failed to copy clicks: copied %d of %d rows", copied, len(clicks))
+	}
+
+	return nil
+}
+
+// copyClicksTx вставляет клики через COPY protocol в отдельной транзакции
+func copyClicksTx(ctx context.Context, db *DB, clicks []*click.Click) error {
+	return db.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := assignClickXactID(ctx, tx); err != nil {
+			return err
+		}
+		return copyClicks(ctx, tx, clicks)
+	})
+}
+
+// insertClicks вставляет клики через pgx.Batch в одной транзакции
+func insertClicks(ctx context.Context, db *DB, logger *logrus.Logger, clicks []*click.Click) error {
+	return db.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := assignClickXactID(ctx, tx); err != nil {
+			return err
+		}
+		return insertClicksTx(ctx, tx, logger, clicks)
+	})
+}
+
+// assignClickXactID назначает транзакции ID до выделения ID кликов из последовательности.
+// На этом основан high-water mark фоновой агрегации (AggregatePendingClicks): транзакция,
+// выделившая ID клика, видна в снимке как активная, пока не завершится
+func assignClickXactID(ctx context.Context, tx pgx.Tx) error {
+	if _, err := tx.Exec(ctx, `SELECT pg_current_xact_id()`); err != nil {
+		return fmt.Errorf("failed to assign transaction ID: %w", err)
+	}
+	return nil
+}
+
+// insertClicksTx вставляет клики через pgx.Batch в переданной транзакции
+func insertClicksTx(ctx context.Context, tx pgx.Tx, logger *logrus.Logger, clicks []*click.Click) error {
+	query := `
+		INSERT INTO clicks (banner_id, timestamp, user_ip, user_agent, is_duplicate, is_bot, event_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	batch := &pgx.Batch{}
+	for _, c := range clicks {
+		batch.Queue(query, c.BannerID, c.Timestamp, clickUserIP(c.UserIP), c.UserAgent, c.Duplicate, c.Bot, clickEventID(c.EventID))
+	}
+
+	results := tx.SendBatch(ctx, batch)
+	defer results.Close()
+
+	for i := 0; i < len(clicks); i++ {
+		_, err := results.Exec()
+		if err != nil {
+			logger.WithError(err).WithField("batch_index", i).Error("Failed to execute batch insert")
+			return fmt.Errorf("failed to execute batch insert at index %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// clickUserIP преобразует IP в значение колонки user_ip (NULL для пустого или некорректного).
+// Используется всеми способами вставки, чтобы COPY и INSERT сохраняли один и тот же клик одинаково
+func clickUserIP(userIP string) any {
+	addr, err := netip.ParseAddr(userIP)
+	if err != nil {
+		return nil
+	}
+	return addr
+}
+
+// clickEventID преобразует ключ идемпотентности клика в значение колонки event_id
+// (NULL для кликов без ключа или с некорректным ключом)
+func clickEventID(eventID string) any {
+	if eventID == "" {
+		return nil
+	}
+
+	var id pgtype.UUID
+	if err := id.Scan(eventID); err != nil {
+		return nil
+	}
+	return id
+}
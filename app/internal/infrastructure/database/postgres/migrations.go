@@ -0,0 +1,375 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// migrationLockID ключ advisory lock, под которым применяются миграции:
+// несколько экземпляров сервиса, стартующих одновременно, применяют их по очереди
+const migrationLockID int64 = 4815162342
+
+// migrationFilePattern разбирает имя файла миграции NNN_name.up.sql / NNN_name.down.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+var (
+	// ErrDirtySchema возвращается, если предыдущая миграция была прервана
+	// (флаг dirty в schema_migrations) и схему нужно исправить вручную
+	ErrDirtySchema = errors.New("database schema is dirty")
+
+	// ErrUnknownMigration возвращается при обращении к версии, которой нет среди миграций
+	ErrUnknownMigration = errors.New("unknown migration version")
+)
+
+// Migration миграция схемы с SQL для применения и отката
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus миграция и признак ее применения
+type MigrationStatus struct {
+	Migration
+	Applied bool
+}
+
+// SchemaVersion текущая версия схемы из schema_migrations.
+// Version 0 - миграции не применялись
+type SchemaVersion struct {
+	Version int64
+	Dirty   bool
+}
+
+// Migrator применяет и откатывает миграции схемы. Версия хранится в таблице
+// schema_migrations в формате golang-migrate, поэтому миграции можно применять
+// как сервером, так и контейнером migrate
+type Migrator struct {
+	db         *DB
+	migrations []Migration
+	logger     *logrus.Logger
+}
+
+// NewMigrator создает мигратор для миграций из fsys
+func NewMigrator(db *DB, fsys fs.FS, logger *logrus.Logger) (*Migrator, error) {
+	if logger == nil {
+		logger = logrus.New()
+	}
+
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		logger:     logger,
+	}, nil
+}
+
+// loadMigrations читает миграции из корня fsys и упорядочивает их по версии
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrations возвращает известные миграции в порядке применения
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Latest возвращает версию последней известной миграции
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version возвращает текущую версию схемы, не создавая schema_migrations
+func (m *Migrator) Version(ctx context.Context) (SchemaVersion, error) {
+	var exists bool
+	if err := m.db.Pool.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return SchemaVersion{}, fmt.Errorf("failed to check schema_migrations: %w", err)
+	}
+	if !exists {
+		return SchemaVersion{}, nil
+	}
+
+	return readSchemaVersion(ctx, m.db.Pool)
+}
+
+// Status возвращает текущую версию схемы и признак применения каждой миграции
+func (m *Migrator) Status(ctx context.Context) (SchemaVersion, []MigrationStatus, error) {
+	current, err := m.Version(ctx)
+	if err != nil {
+		return SchemaVersion{}, nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		statuses = append(statuses, MigrationStatus{
+			Migration: migration,
+			Applied:   migration.Version <= current.Version,
+		})
+	}
+	return current, statuses, nil
+}
+
+// Up применяет все непримененные миграции и возвращает их количество
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	return m.migrate(ctx, func(current int64) (int64, error) {
+		// Схема новее известных миграций (например, после отката бинарного файла) не откатывается
+		if current > m.Latest() {
+			m.logger.WithFields(logrus.Fields{
+				"version": current,
+				"latest":  m.Latest(),
+			}).Warn("Database schema is newer than known migrations")
+			return current, nil
+		}
+		return m.Latest(), nil
+	})
+}
+
+// Down откатывает steps последних примененных миграций и возвращает их количество
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	return m.migrate(ctx, func(current int64) (int64, error) {
+		if current == 0 {
+			return 0, nil
+		}
+		index := m.indexOf(current)
+		if index < 0 {
+			return 0, fmt.Errorf("%w: %d", ErrUnknownMigration, current)
+		}
+		if index-steps < 0 {
+			return 0, nil
+		}
+		return m.migrations[index-steps].Version, nil
+	})
+}
+
+// To применяет или откатывает миграции до версии target (0 - откатить все)
+func (m *Migrator) To(ctx context.Context, target int64) (int, error) {
+	if target != 0 && m.indexOf(target) < 0 {
+		return 0, fmt.Errorf("%w: %d", ErrUnknownMigration, target)
+	}
+
+	return m.migrate(ctx, func(int64) (int64, error) {
+		return target, nil
+	})
+}
+
+// migrate переводит схему к версии, вычисленной от текущей, под advisory lock.
+// Каждая миграция выполняется в своей транзакции вместе с обновлением версии,
+// поэтому ошибка откатывает только ее
+func (m *Migrator) migrate(ctx context.Context, targetOf func(current int64) (int64, error)) (int, error) {
+	conn, err := m.db.Pool.Acquire(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	m.logger.Debug("Waiting for migration lock")
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return 0, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// Контекст мог быть отменен, а блокировку нужно снять в любом случае
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID); err != nil {
+			m.logger.WithError(err).Error("Failed to release migration lock")
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT NOT NULL PRIMARY KEY,
+			dirty BOOLEAN NOT NULL
+		)
+	`); err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	current, err := readSchemaVersion(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if current.Dirty {
+		return 0, fmt.Errorf("%w at version %d: fix the schema manually and reset the version", ErrDirtySchema, current.Version)
+	}
+
+	target, err := targetOf(current.Version)
+	if err != nil {
+		return 0, err
+	}
+
+	if target > current.Version {
+		return m.applyUp(ctx, conn, current.Version, target)
+	}
+	if target < current.Version {
+		return m.applyDown(ctx, conn, current.Version, target)
+	}
+
+	m.logger.WithField("version", current.Version).Info("Database schema is up to date")
+	return 0, nil
+}
+
+// applyUp применяет миграции с версиями в (current, target]
+func (m *Migrator) applyUp(ctx context.Context, conn *pgxpool.Conn, current, target int64) (int, error) {
+	applied := 0
+	for _, migration := range m.migrations {
+		if migration.Version <= current || migration.Version > target {
+			continue
+		}
+
+		if err := m.apply(ctx, conn, migration, migration.Up, migration.Version); err != nil {
+			return applied, err
+		}
+		applied++
+
+		m.logger.WithFields(logrus.Fields{
+			"version": migration.Version,
+			"name":    migration.Name,
+		}).Info("Migration applied")
+	}
+	return applied, nil
+}
+
+// applyDown откатывает миграции с версиями в (target, current] от новых к старым
+func (m *Migrator) applyDown(ctx context.Context, conn *pgxpool.Conn, current, target int64) (int, error) {
+	index := m.indexOf(current)
+	if index < 0 {
+		return 0, fmt.Errorf("%w: %d", ErrUnknownMigration, current)
+	}
+
+	reverted := 0
+	for ; index >= 0 && m.migrations[index].Version > target; index-- {
+		migration := m.migrations[index]
+		if migration.Down == "" {
+			return reverted, fmt.Errorf("migration %d_%s has no down script", migration.Version, migration.Name)
+		}
+
+		previous := int64(0)
+		if index > 0 {
+			previous = m.migrations[index-1].Version
+		}
+
+		if err := m.apply(ctx, conn, migration, migration.Down, previous); err != nil {
+			return reverted, err
+		}
+		reverted++
+
+		m.logger.WithFields(logrus.Fields{
+			"version": migration.Version,
+			"name":    migration.Name,
+		}).Info("Migration reverted")
+	}
+	return reverted, nil
+}
+
+// apply выполняет скрипт миграции и записывает новую версию в одной транзакции
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, migration Migration, script string, version int64) error {
+	err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		// Без аргументов запрос выполняется простым протоколом и может содержать несколько команд
+		if _, err := tx.Exec(ctx, script); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations`); err != nil {
+			return err
+		}
+		if version == 0 {
+			return nil
+		}
+		_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, FALSE)`, version)
+		return err
+	})
+	if err != nil {
+		m.logger.WithError(err).WithFields(logrus.Fields{
+			"version": migration.Version,
+			"name":    migration.Name,
+		}).Error("Migration failed")
+		return fmt.Errorf("failed to run migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	return nil
+}
+
+// indexOf возвращает индекс миграции с версией version или -1
+func (m *Migrator) indexOf(version int64) int {
+	for i, migration := range m.migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// readSchemaVersion читает версию схемы из schema_migrations
+func readSchemaVersion(ctx context.Context, q interface {
+	QueryRow(context.Context, string, ...any) pgx.Row
+}) (SchemaVersion, error) {
+	var current SchemaVersion
+	err := q.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&current.Version, &current.Dirty)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return SchemaVersion{}, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return current, nil
+}
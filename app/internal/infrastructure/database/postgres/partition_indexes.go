@@ -0,0 +1,179 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/domain/tenant"
+)
+
+// partitionIndex индекс таблицы кликов, который миграция создает только на самой таблице
+// (CREATE INDEX ... ON ONLY): на существующих секциях менеджер строит его без блокировки вставок
+type partitionIndex struct {
+	Parent  string // имя индекса секционированной таблицы
+	Suffix  string // суффикс имени индекса секции
+	Unique  bool
+	Columns string
+}
+
+// partitionIndexes индексы, достраиваемые на секциях
+var partitionIndexes = []partitionIndex{
+	{Parent: "idx_clicks_event_id", Suffix: "event_id_idx", Unique: true, Columns: "event_id, timestamp"},
+}
+
+// buildPartitionIndexes достраивает индексы секций в фоне и повторяет попытку через
+// retryInterval, пока все индексы не будут построены или менеджер не будет остановлен
+func (m *PartitionManager) buildPartitionIndexes(retryInterval time.Duration) {
+	defer m.wg.Done()
+
+	ctx, cancel := context.WithCancel(tenant.WithSystem(context.Background()))
+	defer cancel()
+	go func() {
+		select {
+		case <-m.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		err := m.ensurePartitionIndexes(ctx)
+		if err == nil {
+			return
+		}
+		m.logger.WithError(err).Error("Failed to build click partition indexes")
+
+		if retryInterval <= 0 {
+			return
+		}
+		select {
+		case <-time.After(retryInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ensurePartitionIndexes строит недостающие индексы секций с CREATE INDEX CONCURRENTLY и
+// присоединяет их к индексу таблицы. Индексы строятся вне транзакции, поэтому вставки
+// кликов не блокируются; оставшийся после прерванной сборки невалидный индекс пересоздается
+func (m *PartitionManager) ensurePartitionIndexes(ctx context.Context) error {
+	for _, index := range partitionIndexes {
+		var exists bool
+		if err := m.db.Pool.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, index.Parent).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check index %s: %w", index.Parent, err)
+		}
+		if !exists {
+			// Миграция с индексом еще не применена
+			continue
+		}
+
+		partitions, err := m.partitionsWithoutIndex(ctx, index.Parent)
+		if err != nil {
+			return err
+		}
+
+		for _, partition := range partitions {
+			if err := m.buildPartitionIndex(ctx, index, partition); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// partitionsWithoutIndex возвращает секции таблицы кликов, индекс которых не присоединен к parent
+func (m *PartitionManager) partitionsWithoutIndex(ctx context.Context, parent string) ([]string, error) {
+	rows, err := m.db.Pool.Query(ctx, `
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = $1::regclass
+			AND NOT EXISTS (
+				SELECT 1
+				FROM pg_inherits ii
+				JOIN pg_index x ON x.indexrelid = ii.inhrelid
+				WHERE ii.inhparent = $2::regclass AND x.indrelid = c.oid
+			)
+		ORDER BY c.relname
+	`, clicksTable, parent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list click partitions without index %s: %w", parent, err)
+	}
+
+	defer rows.Close()
+
+	var partitions []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan click partition: %w", err)
+		}
+		partitions = append(partitions, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list click partitions without index %s: %w", parent, err)
+	}
+	return partitions, nil
+}
+
+// buildPartitionIndex строит индекс секции и присоединяет его к индексу таблицы
+func (m *PartitionManager) buildPartitionIndex(ctx context.Context, index partitionIndex, partition string) error {
+	name := partition + "_" + index.Suffix
+	indexName := pgx.Identifier{name}.Sanitize()
+
+	// Невалидный индекс остается после прерванной сборки CONCURRENTLY
+	var valid bool
+	err := m.db.Pool.QueryRow(ctx, `SELECT indisvalid FROM pg_index WHERE indexrelid = to_regclass($1)`, indexName).Scan(&valid)
+	if err == nil && !valid {
+		if _, err := m.db.Pool.Exec(ctx, `DROP INDEX CONCURRENTLY IF EXISTS `+indexName); err != nil {
+			return fmt.Errorf("failed to drop invalid index %s: %w", name, err)
+		}
+	} else if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to check index %s: %w", name, err)
+	}
+
+	unique := ""
+	if index.Unique {
+		unique = "UNIQUE "
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"partition": partition,
+		"index":     name,
+	}).Info("Building click partition index")
+
+	start := time.Now()
+	// Без аргументов запрос выполняется простым протоколом вне транзакции, как требует CONCURRENTLY
+	query := fmt.Sprintf(`CREATE %sINDEX CONCURRENTLY IF NOT EXISTS %s ON %s (%s)`,
+		unique, indexName, pgx.Identifier{partition}.Sanitize(), index.Columns)
+	if _, err := m.db.Pool.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to build index %s: %w", name, err)
+	}
+
+	err = m.db.WithTx(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SET LOCAL lock_timeout = '`+partitionLockTimeout+`'`); err != nil {
+			return fmt.Errorf("failed to set lock timeout: %w", err)
+		}
+		_, err := tx.Exec(ctx, fmt.Sprintf(`ALTER INDEX %s ATTACH PARTITION %s`,
+			pgx.Identifier{index.Parent}.Sanitize(), indexName))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to attach index %s: %w", name, err)
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"partition": partition,
+		"index":     name,
+		"duration":  time.Since(start),
+	}).Info("Click partition index built")
+
+	return nil
+}
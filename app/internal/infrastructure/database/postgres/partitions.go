@@ -0,0 +1,483 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/domain/tenant"
+)
+
+// PartitionInterval размер секции таблицы кликов
+type PartitionInterval string
+
+// Поддерживаемые размеры секций
+const (
+	PartitionDaily   PartitionInterval = "day"
+	PartitionMonthly PartitionInterval = "month"
+)
+
+const (
+	// clicksTable секционированная таблица кликов
+	clicksTable = "clicks"
+
+	// partitionLockTimeout ограничивает ожидание блокировки таблицы кликов при DDL:
+	// ожидающий DDL блокирует вставки кликов, поэтому лучше повторить операцию позже
+	partitionLockTimeout = "5s"
+
+	// movedClicksTable временная таблица для кликов, переносимых из секции по умолчанию
+	movedClicksTable = "clicks_default_moved"
+)
+
+// ErrInvalidPartitionInterval возвращается при неизвестном размере секции
+var ErrInvalidPartitionInterval = errors.New("invalid partition interval")
+
+// partitionBoundPattern разбирает границы секции из pg_get_expr (при TimeZone = UTC)
+var partitionBoundPattern = regexp.MustCompile(`FROM \('([^']+)'\) TO \('([^']+)'\)`)
+
+// ClickPartition секция таблицы кликов с диапазоном [From, To)
+type ClickPartition struct {
+	Name    string
+	From    time.Time
+	To      time.Time
+	Default bool // секция по умолчанию для кликов вне диапазонов
+}
+
+// PartitionManager создает секции таблицы кликов заранее и удаляет устаревшие целиком.
+// Реализует интерфейс click.RetentionRepository
+type PartitionManager struct {
+	db       *DB
+	interval PartitionInterval
+	premake  int
+	detach   bool
+	logger   *logrus.Logger
+
+	defaultRows int64 // клики в секции по умолчанию при последней проверке
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewPartitionManager создает менеджер секций. premake - количество будущих секций,
+// создаваемых заранее; detach - отсоединять устаревшие секции вместо удаления (для архивации)
+func NewPartitionManager(db *DB, interval PartitionInterval, premake int, detach bool, logger *logrus.Logger) (*PartitionManager, error) {
+	if logger == nil {
+		logger = logrus.New()
+	}
+
+	if interval != PartitionDaily && interval != PartitionMonthly {
+		return nil, fmt.Errorf("%w: %q (expected day or month)", ErrInvalidPartitionInterval, interval)
+	}
+	if premake < 1 {
+		premake = 1
+	}
+
+	return &PartitionManager{
+		db:       db,
+		interval: interval,
+		premake:  premake,
+		detach:   detach,
+		logger:   logger,
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Partitions возвращает секции таблицы кликов, упорядоченные по началу диапазона
+func (m *PartitionManager) Partitions(ctx context.Context) ([]ClickPartition, error) {
+	var partitions []ClickPartition
+	err := m.db.WithTx(ctx, func(tx pgx.Tx) error {
+		// Границы печатаются в часовом поясе сессии
+		if _, err := tx.Exec(ctx, `SET LOCAL TimeZone = 'UTC'`); err != nil {
+			return fmt.Errorf("failed to set time zone: %w", err)
+		}
+
+		rows, err := tx.Query(ctx, `
+			SELECT c.relname, pg_get_expr(c.relpartbound, c.oid)
+			FROM pg_inherits i
+			JOIN pg_class c ON c.oid = i.inhrelid
+			WHERE i.inhparent = $1::regclass
+		`, clicksTable)
+		if err != nil {
+			return fmt.Errorf("failed to list click partitions: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var name, bound string
+			if err := rows.Scan(&name, &bound); err != nil {
+				return fmt.Errorf("failed to scan click partition: %w", err)
+			}
+
+			partition, err := parsePartitionBound(name, bound)
+			if err != nil {
+				m.logger.WithError(err).WithField("partition", name).Warn("Skipping click partition with unsupported bounds")
+				continue
+			}
+			partitions = append(partitions, partition)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].From.Before(partitions[j].From)
+	})
+	return partitions, nil
+}
+
+// parsePartitionBound разбирает выражение границ секции
+func parsePartitionBound(name, bound string) (ClickPartition, error) {
+	if bound == "DEFAULT" {
+		return ClickPartition{Name: name, Default: true}, nil
+	}
+
+	match := partitionBoundPattern.FindStringSubmatch(bound)
+	if match == nil {
+		return ClickPartition{}, fmt.Errorf("unexpected partition bound: %s", bound)
+	}
+
+	const layout = "2006-01-02 15:04:05.999999-07"
+	from, err := time.Parse(layout, match[1])
+	if err != nil {
+		return ClickPartition{}, fmt.Errorf("failed to parse partition start: %w", err)
+	}
+	to, err := time.Parse(layout, match[2])
+	if err != nil {
+		return ClickPartition{}, fmt.Errorf("failed to parse partition end: %w", err)
+	}
+
+	return ClickPartition{Name: name, From: from.UTC(), To: to.UTC()}, nil
+}
+
+// EnsurePartitions создает секции от конца последней существующей до now плюс premake
+// интервалов и возвращает имена созданных секций
+func (m *PartitionManager) EnsurePartitions(ctx context.Context, now time.Time) ([]string, error) {
+	partitions, err := m.Partitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	start := m.truncate(now)
+	for _, partition := range partitions {
+		if !partition.Default && partition.To.After(start) {
+			start = partition.To
+		}
+	}
+
+	// Текущая секция и premake следующих
+	end := m.advance(m.truncate(now), m.premake+1)
+
+	var created []string
+	for from := start; from.Before(end); {
+		to := m.next(from)
+		name := m.partitionName(from)
+
+		if err := m.createPartition(ctx, name, from, to); err != nil {
+			return created, err
+		}
+		created = append(created, name)
+		from = to
+	}
+
+	if len(created) > 0 {
+		m.logger.WithFields(logrus.Fields{
+			"partitions": created,
+			"interval":   m.interval,
+		}).Info("Click partitions created")
+	}
+
+	return created, nil
+}
+
+// createPartition создает секцию [from, to). Клики этого диапазона, попавшие в секцию
+// по умолчанию, переносятся в новую секцию в той же транзакции: иначе PostgreSQL не
+// создаст секцию, диапазон которой пересекается со строками секции по умолчанию
+func (m *PartitionManager) createPartition(ctx context.Context, name string, from, to time.Time) error {
+	query := fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')`,
+		pgx.Identifier{name}.Sanitize(),
+		pgx.Identifier{clicksTable}.Sanitize(),
+		from.UTC().Format(time.RFC3339),
+		to.UTC().Format(time.RFC3339),
+	)
+
+	moved := pgx.Identifier{movedClicksTable}.Sanitize()
+	clicks := pgx.Identifier{clicksTable}.Sanitize()
+
+	var movedRows int64
+	err := m.db.WithTx(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SET LOCAL lock_timeout = '`+partitionLockTimeout+`'`); err != nil {
+			return fmt.Errorf("failed to set lock timeout: %w", err)
+		}
+
+		// Секции диапазона еще нет, поэтому его клики могут быть только в секции по умолчанию
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`CREATE TEMP TABLE %s (LIKE %s) ON COMMIT DROP`, moved, clicks)); err != nil {
+			return fmt.Errorf("failed to create table for moved clicks: %w", err)
+		}
+		tag, err := tx.Exec(ctx, fmt.Sprintf(`
+			WITH moved AS (
+				DELETE FROM %s WHERE timestamp >= $1 AND timestamp < $2
+				RETURNING *
+			)
+			INSERT INTO %s SELECT * FROM moved
+		`, clicks, moved), from, to)
+		if err != nil {
+			return fmt.Errorf("failed to move clicks out of default partition: %w", err)
+		}
+		movedRows = tag.RowsAffected()
+
+		if _, err := tx.Exec(ctx, query); err != nil {
+			return err
+		}
+
+		if movedRows > 0 {
+			if _, err := tx.Exec(ctx, fmt.Sprintf(`INSERT INTO %s SELECT * FROM %s`, clicks, moved)); err != nil {
+				return fmt.Errorf("failed to insert moved clicks: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		m.logger.WithError(err).WithFields(logrus.Fields{
+			"partition": name,
+			"from":      from,
+			"to":        to,
+		}).Error("Failed to create click partition")
+		return fmt.Errorf("failed to create click partition %s: %w", name, err)
+	}
+
+	if movedRows > 0 {
+		m.logger.WithFields(logrus.Fields{
+			"partition": name,
+			"clicks":    movedRows,
+		}).Warn("Clicks moved from default partition to the new partition")
+	}
+
+	return nil
+}
+
+// DropOldClicks удаляет (или отсоединяет) секции, целиком лежащие раньше before, и
+// возвращает количество кликов в них (для секций - по статистике планировщика). Секция с кликами, ID которых больше maxID
+// (0 - без ограничения), и все более поздние секции не трогаются: клики еще не агрегированы.
+// Из секции по умолчанию построчно удаляются клики старше before не новее maxID
+func (m *PartitionManager) DropOldClicks(ctx context.Context, before time.Time, maxID int64) (int64, error) {
+	partitions, err := m.Partitions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var removed int64
+	for _, partition := range partitions {
+		if !partition.Default {
+			continue
+		}
+
+		query := fmt.Sprintf(`DELETE FROM %s WHERE timestamp < $1 AND ($2 = 0 OR id <= $2)`, pgx.Identifier{partition.Name}.Sanitize())
+		tag, err := m.db.Pool.Exec(ctx, query, before, maxID)
+		if err != nil {
+			return removed, fmt.Errorf("failed to delete old clicks from default partition: %w", err)
+		}
+		removed += tag.RowsAffected()
+
+		if tag.RowsAffected() > 0 {
+			m.logger.WithFields(logrus.Fields{
+				"partition": partition.Name,
+				"clicks":    tag.RowsAffected(),
+			}).Info("Old clicks deleted from default partition")
+		}
+	}
+
+	for _, partition := range partitions {
+		if partition.Default || partition.To.After(before) {
+			continue
+		}
+
+		// MAX(id) читается из индекса первичного ключа (id, timestamp), а количество кликов
+		// берется из оценки pg_class.reltuples: полный подсчет сканировал бы секцию при
+		// каждом запуске, пока она ждет агрегации
+		var rows, partitionMaxID int64
+		name := pgx.Identifier{partition.Name}.Sanitize()
+		query := fmt.Sprintf(`
+			SELECT
+				COALESCE((SELECT MAX(id) FROM %s), 0),
+				GREATEST(c.reltuples, 0)::BIGINT
+			FROM pg_class c
+			WHERE c.oid = $1::regclass
+		`, name)
+		if err := m.db.Pool.QueryRow(ctx, query, name).Scan(&partitionMaxID, &rows); err != nil {
+			return removed, fmt.Errorf("failed to inspect click partition %s: %w", partition.Name, err)
+		}
+
+		if maxID > 0 && partitionMaxID > maxID {
+			m.logger.WithFields(logrus.Fields{
+				"partition":     partition.Name,
+				"max_click_id":  partitionMaxID,
+				"aggregated_id": maxID,
+			}).Warn("Click partition has unaggregated clicks, keeping it")
+			break
+		}
+
+		if err := m.removePartition(ctx, partition.Name); err != nil {
+			return removed, err
+		}
+		removed += rows
+
+		m.logger.WithFields(logrus.Fields{
+			"partition": partition.Name,
+			"clicks":    rows,
+			"detached":  m.detach,
+		}).Info("Old click partition removed")
+	}
+
+	return removed, nil
+}
+
+// removePartition удаляет или отсоединяет секцию
+func (m *PartitionManager) removePartition(ctx context.Context, name string) error {
+	query := `DROP TABLE ` + pgx.Identifier{name}.Sanitize()
+	if m.detach {
+		query = fmt.Sprintf(`ALTER TABLE %s DETACH PARTITION %s`,
+			pgx.Identifier{clicksTable}.Sanitize(), pgx.Identifier{name}.Sanitize())
+	}
+
+	err := m.db.WithTx(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SET LOCAL lock_timeout = '`+partitionLockTimeout+`'`); err != nil {
+			return fmt.Errorf("failed to set lock timeout: %w", err)
+		}
+		_, err := tx.Exec(ctx, query)
+		return err
+	})
+	if err != nil {
+		m.logger.WithError(err).WithField("partition", name).Error("Failed to remove click partition")
+		return fmt.Errorf("failed to remove click partition %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// Start создает недостающие секции сразу и затем проверяет их с интервалом checkInterval.
+// Периодическая проверка запускается и при ошибке первой попытки. Индексы секций,
+// добавленные миграциями, достраиваются в фоне
+func (m *PartitionManager) Start(ctx context.Context, checkInterval time.Duration) error {
+	err := m.maintain(ctx)
+
+	m.wg.Add(1)
+	go m.buildPartitionIndexes(checkInterval)
+
+	if checkInterval > 0 {
+		m.wg.Add(1)
+		go m.watch(checkInterval)
+	}
+	return err
+}
+
+// maintain создает секции и подсчитывает клики в секции по умолчанию
+func (m *PartitionManager) maintain(ctx context.Context) error {
+	if _, err := m.EnsurePartitions(ctx, time.Now()); err != nil {
+		return err
+	}
+
+	partitions, err := m.Partitions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, partition := range partitions {
+		if !partition.Default {
+			continue
+		}
+
+		// Секция по умолчанию должна оставаться пустой, поэтому подсчет дешевый
+		var rows int64
+		query := fmt.Sprintf(`SELECT COUNT(*) FROM %s`, pgx.Identifier{partition.Name}.Sanitize())
+		if err := m.db.Pool.QueryRow(ctx, query).Scan(&rows); err != nil {
+			return fmt.Errorf("failed to check default click partition: %w", err)
+		}
+		atomic.StoreInt64(&m.defaultRows, rows)
+
+		if rows > 0 {
+			m.logger.WithFields(logrus.Fields{
+				"partition": partition.Name,
+				"clicks":    rows,
+			}).Warn("Default click partition is not empty: clicks arrived outside of created partitions")
+		}
+	}
+
+	return nil
+}
+
+// DefaultPartitionRows возвращает количество кликов в секции по умолчанию при последней проверке
+func (m *PartitionManager) DefaultPartitionRows() int64 {
+	return atomic.LoadInt64(&m.defaultRows)
+}
+
+// watch периодически создает секции наперед
+func (m *PartitionManager) watch(interval time.Duration) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(tenant.WithSystem(context.Background()), interval)
+			if err := m.maintain(ctx); err != nil {
+				m.logger.WithError(err).Error("Click partition maintenance failed")
+			}
+			cancel()
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// Close останавливает периодическое создание секций
+func (m *PartitionManager) Close() {
+	m.closeOnce.Do(func() {
+		close(m.done)
+	})
+	m.wg.Wait()
+}
+
+// truncate возвращает начало интервала, содержащего t (UTC)
+func (m *PartitionManager) truncate(t time.Time) time.Time {
+	t = t.UTC()
+	if m.interval == PartitionMonthly {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// next возвращает начало интервала, следующего за содержащим t
+func (m *PartitionManager) next(t time.Time) time.Time {
+	return m.advance(m.truncate(t), 1)
+}
+
+// advance сдвигает начало интервала на n интервалов
+func (m *PartitionManager) advance(t time.Time, n int) time.Time {
+	if m.interval == PartitionMonthly {
+		return t.AddDate(0, n, 0)
+	}
+	return t.AddDate(0, 0, n)
+}
+
+// partitionName возвращает имя секции по началу ее диапазона
+func (m *PartitionManager) partitionName(from time.Time) string {
+	from = from.UTC()
+	if m.interval == PartitionMonthly {
+		return clicksTable + "_p" + from.Format("200601")
+	}
+	return clicksTable + "_p" + from.Format("20060102")
+}
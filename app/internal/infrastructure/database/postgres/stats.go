@@ -2,10 +2,13 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/clickcounter/app/internal/domain/click"
 	"github.com/clickcounter/app/internal/domain/stats"
+	"github.com/clickcounter/app/pkg/hll"
 	"github.com/jackc/pgx/v5"
 	"github.com/sirupsen/logrus"
 )
@@ -48,14 +51,15 @@ func NewStatsAggregationRepository(db *DB, logger *logrus.Logger) *StatsAggregat
 
 // GetByBannerIDAndPeriod возвращает статистику по баннеру за период
 func (r *StatsRepository) GetByBannerIDAndPeriod(ctx context.Context, bannerID int64, from, to time.Time) ([]*stats.Stat, error) {
+	scope, args := bannerTenantScope(ctx, "banner_id", []any{bannerID, from, to})
 	query := `
 		SELECT id, banner_id, timestamp, count, created_at, updated_at
 		FROM stats
-		WHERE banner_id = $1 AND timestamp >= $2 AND timestamp <= $3
+		WHERE banner_id = $1 AND timestamp >= $2 AND timestamp <= $3` + scope + `
 		ORDER BY timestamp ASC
 	`
 
-	rows, err := r.db.Pool.Query(ctx, query, bannerID, from, to)
+	rows, err := r.db.Pool.Query(ctx, query, args...)
 	if err != nil {
 		r.logger.WithError(err).WithFields(logrus.Fields{
 			"banner_id": bannerID,
@@ -92,115 +96,207 @@ func (r *StatsRepository) GetByBannerIDAndPeriod(ctx context.Context, bannerID i
 	return statsList, nil
 }
 
-// GetAggregatedStats возвращает агрегированную статистику по минутам
-func (r *StatsRepository) GetAggregatedStats(ctx context.Context, bannerID int64, from, to time.Time) ([]*stats.MinuteStat, error) {
-	query := `
-		SELECT timestamp, count
-		FROM stats
-		WHERE banner_id = $1 AND timestamp >= $2 AND timestamp <= $3
-		ORDER BY timestamp ASC
-	`
+// bucketExpressions выражения группировки минутных строк stats по размеру бакета.
+// Бакеты считаются в UTC, недели начинаются с понедельника
+var bucketExpressions = map[stats.Granularity]string{
+	stats.GranularityMinute:     "timestamp",
+	stats.GranularityFiveMinute: "date_bin('5 minutes', timestamp, TIMESTAMPTZ '2000-01-01 00:00:00+00')",
+	stats.GranularityHour:       "date_trunc('hour', timestamp, 'UTC')",
+	stats.GranularityDay:        "date_trunc('day', timestamp, 'UTC')",
+	stats.GranularityWeek:       "date_trunc('week', timestamp, 'UTC')",
+}
 
-	rows, err := r.db.Pool.Query(ctx, query, bannerID, from, to)
+// GetAggregatedStats возвращает статистику, сгруппированную по бакетам opts.Granularity.
+// Крайние бакеты содержат только клики внутри запрошенного периода. Для часовых и более
+// крупных бакетов свернутые часы и дни берутся из stats_hourly и stats_daily, а края
+// периода и еще не свернутое время - из минутной статистики
+func (r *StatsRepository) GetAggregatedStats(ctx context.Context, bannerID int64, from, to time.Time, opts stats.QueryOptions) ([]*stats.BucketStat, error) {
+	byBanner, err := r.queryBucketStats(ctx, "banner_id = $1", bannerID, from, to, opts)
 	if err != nil {
 		r.logger.WithError(err).WithFields(logrus.Fields{
-			"banner_id": bannerID,
-			"from":      from,
-			"to":        to,
+			"banner_id":   bannerID,
+			"from":        from,
+			"to":          to,
+			"granularity": opts.BucketGranularity(),
 		}).Error("Failed to get aggregated stats")
-		return nil, fmt.Errorf("failed to get aggregated stats: %w", err)
+		return nil, err
+	}
+	return byBanner[bannerID], nil
+}
+
+// GetAggregatedStatsForBanners возвращает статистику нескольких баннеров одним запросом,
+// сгруппированную по баннерам и бакетам. Баннеры без кликов за период в результат не попадают
+func (r *StatsRepository) GetAggregatedStatsForBanners(ctx context.Context, bannerIDs []int64, from, to time.Time, opts stats.QueryOptions) (map[int64][]*stats.BucketStat, error) {
+	if len(bannerIDs) == 0 {
+		return map[int64][]*stats.BucketStat{}, nil
+	}
+
+	byBanner, err := r.queryBucketStats(ctx, "banner_id = ANY($1)", bannerIDs, from, to, opts)
+	if err != nil {
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"banners":     len(bannerIDs),
+			"from":        from,
+			"to":          to,
+			"granularity": opts.BucketGranularity(),
+		}).Error("Failed to get aggregated stats for banners")
+		return nil, err
+	}
+	return byBanner, nil
+}
+
+// StreamAggregatedStats читает статистику баннера, сгруппированную по бакетам, и передает
+// бакеты в fn по мере получения строк из pgx.Rows, не накапливая результат в памяти.
+// Соединение с БД занято до конца выгрузки. Ошибка fn прерывает чтение и возвращается
+func (r *StatsRepository) StreamAggregatedStats(ctx context.Context, bannerID int64, from, to time.Time, opts stats.QueryOptions, fn func(*stats.BucketStat) error) error {
+	query, args, err := r.bucketStatsQuery(ctx, "banner_id = $1", bannerID, from, to, opts)
+	if err != nil || query == "" {
+		return err
+	}
+
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to export aggregated stats: %w", err)
 	}
 	defer rows.Close()
 
-	var minuteStats []*stats.MinuteStat
 	for rows.Next() {
-		var timestamp time.Time
-		var count int64
-		err := rows.Scan(&timestamp, &count)
+		_, bucketStat, err := scanBucketStat(rows, opts)
 		if err != nil {
-			r.logger.WithError(err).Error("Failed to scan aggregated stats row")
-			return nil, fmt.Errorf("failed to scan aggregated stats row: %w", err)
+			return err
+		}
+		if err := fn(bucketStat); err != nil {
+			return err
 		}
-		minuteStats = append(minuteStats, stats.NewMinuteStat(timestamp, count))
 	}
 
 	if err := rows.Err(); err != nil {
-		r.logger.WithError(err).Error("Error iterating aggregated stats rows")
-		return nil, fmt.Errorf("error iterating aggregated stats rows: %w", err)
+		return fmt.Errorf("error iterating exported stats rows: %w", err)
 	}
 
-	return minuteStats, nil
+	return nil
 }
 
-// CreateOrUpdate создает новую статистику или обновляет существующую
-func (r *StatsRepository) CreateOrUpdate(ctx context.Context, s *stats.Stat) error {
-	query := `
-		INSERT INTO stats (banner_id, timestamp, count, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT (banner_id, timestamp)
-		DO UPDATE SET
-			count = stats.count + EXCLUDED.count,
-			updated_at = EXCLUDED.updated_at
-		RETURNING id
-	`
+// queryBucketStats выбирает статистику баннеров, подходящих под bannerFilter по аргументу
+// $1 (bannerArg), и группирует ее по баннерам и бакетам
+func (r *StatsRepository) queryBucketStats(ctx context.Context, bannerFilter string, bannerArg any, from, to time.Time, opts stats.QueryOptions) (map[int64][]*stats.BucketStat, error) {
+	byBanner := make(map[int64][]*stats.BucketStat)
 
-	err := r.db.Pool.QueryRow(ctx, query,
-		s.BannerID,
-		s.Timestamp,
-		s.Count,
-		s.CreatedAt,
-		s.UpdatedAt,
-	).Scan(&s.ID)
+	query, args, err := r.bucketStatsQuery(ctx, bannerFilter, bannerArg, from, to, opts)
+	if err != nil {
+		return nil, err
+	}
+	if query == "" {
+		return byBanner, nil
+	}
 
+	rows, err := r.db.Pool.Query(ctx, query, args...)
 	if err != nil {
-		r.logger.WithError(err).WithFields(logrus.Fields{
-			"banner_id": s.BannerID,
-			"timestamp": s.Timestamp,
-			"count":     s.Count,
-		}).Error("Failed to create or update stats")
-		return fmt.Errorf("failed to create or update stats: %w", err)
+		return nil, fmt.Errorf("failed to get aggregated stats: %w", err)
 	}
+	defer rows.Close()
 
-	return nil
+	for rows.Next() {
+		bannerID, bucketStat, err := scanBucketStat(rows, opts)
+		if err != nil {
+			return nil, err
+		}
+		byBanner[bannerID] = append(byBanner[bannerID], bucketStat)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating aggregated stats rows: %w", err)
+	}
+
+	return byBanner, nil
 }
 
-// IncrementCount увеличивает счетчик для определенной минуты
-func (r *StatsRepository) IncrementCount(ctx context.Context, bannerID int64, timestamp time.Time, delta int64) error {
-	// Округляем до минуты
-	minuteTimestamp := timestamp.Truncate(time.Minute)
+// bucketStatsQuery строит запрос статистики, сгруппированной по баннерам и бакетам
+// в порядке (banner_id, bucket), с ограничением баннеров арендатором запроса.
+// Пустой запрос - в периоде нет ни одной минуты
+func (r *StatsRepository) bucketStatsQuery(ctx context.Context, bannerFilter string, bannerArg any, from, to time.Time, opts stats.QueryOptions) (string, []any, error) {
+	granularity := opts.BucketGranularity()
+	bucketExpr, ok := bucketExpressions[granularity]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: %q", stats.ErrInvalidGranularity, granularity)
+	}
+
+	countExpr := "count"
+	if opts.IncludeBots {
+		countExpr = "count + bot_count"
+	}
+
+	// Скетчи HyperLogLog не объединяются в SQL - собираем их массивом и сливаем в приложении
+	visitorsExpr := "NULL::bytea[]"
+	if opts.Uniques {
+		visitorsExpr = "array_agg(visitors_hll) FILTER (WHERE visitors_hll IS NOT NULL)"
+	}
+
+	// Минутные строки лежат на границах минут: включительный to соответствует
+	// полуинтервалу [ceil(from), floor(to) + 1 минута)
+	start := from.UTC().Truncate(time.Minute)
+	if start.Before(from) {
+		start = start.Add(time.Minute)
+	}
+	end := to.UTC().Truncate(time.Minute).Add(time.Minute)
+
+	// Крупные бакеты читаются из свернутых таблиц, где это возможно
+	var boundaries map[stats.RollupLevel]time.Time
+	levels := rollupLevelsFor(granularity)
+	if len(levels) > 0 {
+		var err error
+		if boundaries, err = loadRollupBoundaries(ctx, r.db); err != nil {
+			return "", nil, err
+		}
+	}
+
+	sources := planStatsSources(start, end, levels, boundaries)
+	if len(sources) == 0 {
+		return "", nil, nil
+	}
+	scope, args := bannerTenantScope(ctx, "banner_id", []any{bannerArg})
+	sourcesQuery, args := statsSourcesQuery(sources, bannerFilter+scope, args)
 
 	query := `
-		INSERT INTO stats (banner_id, timestamp, count, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-		ON CONFLICT (banner_id, timestamp)
-		DO UPDATE SET
-			count = stats.count + $3,
-			updated_at = NOW()
+		SELECT banner_id, ` + bucketExpr + ` AS bucket, SUM(` + countExpr + `)::bigint, ` + visitorsExpr + `
+		FROM (` + sourcesQuery + `
+		) s
+		GROUP BY banner_id, bucket
+		ORDER BY banner_id ASC, bucket ASC
 	`
+	return query, args, nil
+}
 
-	_, err := r.db.Pool.Exec(ctx, query, bannerID, minuteTimestamp, delta)
-	if err != nil {
-		r.logger.WithError(err).WithFields(logrus.Fields{
-			"banner_id": bannerID,
-			"timestamp": minuteTimestamp,
-			"delta":     delta,
-		}).Error("Failed to increment stats count")
-		return fmt.Errorf("failed to increment stats count: %w", err)
+// scanBucketStat читает строку запроса bucketStatsQuery
+func scanBucketStat(rows pgx.Rows, opts stats.QueryOptions) (int64, *stats.BucketStat, error) {
+	var bannerID int64
+	var timestamp time.Time
+	var count int64
+	var visitors [][]byte
+	if err := rows.Scan(&bannerID, &timestamp, &count, &visitors); err != nil {
+		return 0, nil, fmt.Errorf("failed to scan aggregated stats row: %w", err)
 	}
 
-	return nil
+	bucketStat := stats.NewBucketStat(opts.BucketGranularity(), timestamp, count)
+	if opts.Uniques {
+		sketch, err := mergeVisitorSketches(visitors)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to decode visitors sketch of banner %d at %s: %w", bannerID, timestamp, err)
+		}
+		bucketStat.SetVisitors(sketch)
+	}
+	return bannerID, bucketStat, nil
 }
 
 // GetTotalCount возвращает общее количество кликов за период
 func (r *StatsRepository) GetTotalCount(ctx context.Context, bannerID int64, from, to time.Time) (int64, error) {
+	scope, args := bannerTenantScope(ctx, "banner_id", []any{bannerID, from, to})
 	query := `
 		SELECT COALESCE(SUM(count), 0)
 		FROM stats
-		WHERE banner_id = $1 AND timestamp >= $2 AND timestamp <= $3
-	`
+		WHERE banner_id = $1 AND timestamp >= $2 AND timestamp <= $3` + scope
 
 	var total int64
-	err := r.db.Pool.QueryRow(ctx, query, bannerID, from, to).Scan(&total)
+	err := r.db.Pool.QueryRow(ctx, query, args...).Scan(&total)
 	if err != nil {
 		r.logger.WithError(err).WithFields(logrus.Fields{
 			"banner_id": bannerID,
@@ -217,14 +313,14 @@ func (r *StatsRepository) GetTotalCount(ctx context.Context, bannerID int64, fro
 func (r *StatsRepository) GetStatByMinute(ctx context.Context, bannerID int64, timestamp time.Time) (*stats.Stat, error) {
 	minuteTimestamp := timestamp.Truncate(time.Minute)
 
+	scope, args := bannerTenantScope(ctx, "banner_id", []any{bannerID, minuteTimestamp})
 	query := `
 		SELECT id, banner_id, timestamp, count, created_at, updated_at
 		FROM stats
-		WHERE banner_id = $1 AND timestamp = $2
-	`
+		WHERE banner_id = $1 AND timestamp = $2` + scope
 
 	var s stats.Stat
-	err := r.db.Pool.QueryRow(ctx, query, bannerID, minuteTimestamp).Scan(
+	err := r.db.Pool.QueryRow(ctx, query, args...).Scan(
 		&s.ID,
 		&s.BannerID,
 		&s.Timestamp,
@@ -247,51 +343,138 @@ func (r *StatsRepository) GetStatByMinute(ctx context.Context, bannerID int64, t
 	return &s, nil
 }
 
-// BatchCreateOrUpdate создает или обновляет множество записей статистики
-func (r *StatsRepository) BatchCreateOrUpdate(ctx context.Context, statsList []*stats.Stat) error {
-	if len(statsList) == 0 {
+// incrementMinuteStats прибавляет предагрегированные счетчики к минутной статистике в транзакции.
+// Счетчики должны быть упорядочены (см. click.MinuteCounters.Sorted), чтобы параллельные
+// транзакции блокировали строки stats в одном порядке
+func incrementMinuteStats(ctx context.Context, tx pgx.Tx, increments []click.MinuteCount) error {
+	if len(increments) == 0 {
 		return nil
 	}
 
-	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
-		query := `
-			INSERT INTO stats (banner_id, timestamp, count, created_at, updated_at)
-			VALUES ($1, $2, $3, $4, $5)
-			ON CONFLICT (banner_id, timestamp)
-			DO UPDATE SET
-				count = stats.count + EXCLUDED.count,
-				updated_at = EXCLUDED.updated_at
-		`
+	bannerIDs := make([]int64, len(increments))
+	minutes := make([]time.Time, len(increments))
+	deltas := make([]int64, len(increments))
+	botDeltas := make([]int64, len(increments))
+	for i, inc := range increments {
+		bannerIDs[i] = inc.BannerID
+		minutes[i] = inc.Minute
+		deltas[i] = inc.Count
+		botDeltas[i] = inc.BotCount
+	}
 
-		batch := &pgx.Batch{}
-		for _, s := range statsList {
-			batch.Queue(query, s.BannerID, s.Timestamp, s.Count, s.CreatedAt, s.UpdatedAt)
+	// RETURNING отдает текущие скетчи посетителей уже заблокированных строк
+	query := `
+		INSERT INTO stats (banner_id, timestamp, count, bot_count, created_at, updated_at)
+		SELECT banner_id, minute_timestamp, delta, bot_delta, NOW(), NOW()
+		FROM unnest($1::bigint[], $2::timestamptz[], $3::bigint[], $4::bigint[]) AS t(banner_id, minute_timestamp, delta, bot_delta)
+		ON CONFLICT (banner_id, timestamp)
+		DO UPDATE SET
+			count = stats.count + EXCLUDED.count,
+			bot_count = stats.bot_count + EXCLUDED.bot_count,
+			updated_at = EXCLUDED.updated_at
+		RETURNING banner_id, timestamp, visitors_hll
+	`
+
+	rows, err := tx.Query(ctx, query, bannerIDs, minutes, deltas, botDeltas)
+	if err != nil {
+		return fmt.Errorf("failed to increment minute stats: %w", err)
+	}
+
+	stored := make(map[minuteRowKey][]byte, len(increments))
+	for rows.Next() {
+		var (
+			bannerID  int64
+			timestamp time.Time
+			sketch    []byte
+		)
+		if err := rows.Scan(&bannerID, &timestamp, &sketch); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan minute stats row: %w", err)
+		}
+		if sketch != nil {
+			stored[minuteRowKey{bannerID, timestamp.UnixNano()}] = sketch
 		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to increment minute stats: %w", err)
+	}
+
+	return mergeMinuteVisitors(ctx, tx, increments, stored)
+}
+
+// minuteRowKey ключ строки минутной статистики
+type minuteRowKey struct {
+	bannerID int64
+	minute   int64 // UnixNano
+}
+
+// mergeMinuteVisitors объединяет скетчи посетителей батча с сохраненными и записывает результат.
+// Строки уже заблокированы инкрементом в этой транзакции, поэтому чтение-изменение-запись безопасно
+func mergeMinuteVisitors(ctx context.Context, tx pgx.Tx, increments []click.MinuteCount, stored map[minuteRowKey][]byte) error {
+	var (
+		bannerIDs []int64
+		minutes   []time.Time
+		sketches  [][]byte
+	)
 
-		results := tx.SendBatch(ctx, batch)
-		defer results.Close()
+	for _, inc := range increments {
+		if inc.Visitors == nil {
+			continue
+		}
 
-		for i := 0; i < len(statsList); i++ {
-			_, err := results.Exec()
+		merged := hll.NewDefault()
+		if data, ok := stored[minuteRowKey{inc.BannerID, inc.Minute.UnixNano()}]; ok {
+			existing, err := hll.Decode(data)
 			if err != nil {
-				r.logger.WithError(err).WithField("batch_index", i).Error("Failed to execute batch stats insert")
-				return fmt.Errorf("failed to execute batch stats insert at index %d: %w", i, err)
+				return fmt.Errorf("failed to decode visitors sketch: %w", err)
 			}
+			merged = existing
+		}
+		if err := merged.Merge(inc.Visitors); err != nil {
+			return fmt.Errorf("failed to merge visitors sketch: %w", err)
 		}
 
-		r.logger.WithField("count", len(statsList)).Info("Batch stats created/updated successfully")
+		data, err := merged.MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("failed to encode visitors sketch: %w", err)
+		}
+
+		bannerIDs = append(bannerIDs, inc.BannerID)
+		minutes = append(minutes, inc.Minute)
+		sketches = append(sketches, data)
+	}
+
+	if len(sketches) == 0 {
 		return nil
-	})
+	}
+
+	query := `
+		UPDATE stats s
+		SET visitors_hll = v.sketch
+		FROM unnest($1::bigint[], $2::timestamptz[], $3::bytea[]) AS v(banner_id, minute_timestamp, sketch)
+		WHERE s.banner_id = v.banner_id AND s.timestamp = v.minute_timestamp
+	`
+
+	if _, err := tx.Exec(ctx, query, bannerIDs, minutes, sketches); err != nil {
+		return fmt.Errorf("failed to update visitors sketches: %w", err)
+	}
+
+	return nil
 }
 
-// DeleteOldStats удаляет старую статистику
-func (r *StatsRepository) DeleteOldStats(ctx context.Context, before time.Time) (int64, error) {
+// DeleteOldStats удаляет порцию старой минутной статистики
+func (r *StatsRepository) DeleteOldStats(ctx context.Context, before time.Time, limit int) (int64, error) {
 	query := `
 		DELETE FROM stats
-		WHERE timestamp < $1
+		WHERE id IN (
+			SELECT id FROM stats
+			WHERE timestamp < $1
+			LIMIT $2
+		)
 	`
 
-	result, err := r.db.Pool.Exec(ctx, query, before)
+	result, err := r.db.Pool.Exec(ctx, query, before, limit)
 	if err != nil {
 		r.logger.WithError(err).WithField("before", before).Error("Failed to delete old stats")
 		return 0, fmt.Errorf("failed to delete old stats: %w", err)
@@ -301,33 +484,26 @@ func (r *StatsRepository) DeleteOldStats(ctx context.Context, before time.Time)
 	r.logger.WithFields(logrus.Fields{
 		"deleted": deleted,
 		"before":  before,
-	}).Info("Old stats deleted successfully")
+	}).Debug("Old stats deleted")
 
 	return deleted, nil
 }
 
 // Aggregation Repository Methods
 
-// AggregateClicksToStats агрегирует клики в статистику по минутам
+// AggregateClicksToStats агрегирует клики в статистику по минутам.
+// Минутные бакеты, затронутые периодом, пересчитываются целиком из clicks,
+// поэтому повторный вызов с любым (в том числе пересекающимся) окном идемпотентен
 func (r *StatsAggregationRepository) AggregateClicksToStats(ctx context.Context, from, to time.Time) error {
-	query := `
-		INSERT INTO stats (banner_id, timestamp, count, created_at, updated_at)
-		SELECT 
-			banner_id,
-			date_trunc('minute', timestamp) as minute_timestamp,
-			COUNT(*) as click_count,
-			NOW() as created_at,
-			NOW() as updated_at
+	bucketsQuery := `
+		SELECT DISTINCT banner_id, date_trunc('minute', timestamp) AS minute_timestamp
 		FROM clicks
-		WHERE timestamp >= $1 AND timestamp <= $2
-		GROUP BY banner_id, date_trunc('minute', timestamp)
-		ON CONFLICT (banner_id, timestamp)
-		DO UPDATE SET
-			count = stats.count + EXCLUDED.count,
-			updated_at = EXCLUDED.updated_at
+		WHERE timestamp >= date_trunc('minute', $1::timestamptz)
+			AND timestamp < date_trunc('minute', $2::timestamptz) + INTERVAL '1 minute'
+			AND NOT is_duplicate
 	`
 
-	result, err := r.db.Pool.Exec(ctx, query, from, to)
+	rowsAffected, err := r.execRecompute(ctx, bucketsQuery, from, to)
 	if err != nil {
 		r.logger.WithError(err).WithFields(logrus.Fields{
 			"from": from,
@@ -336,7 +512,6 @@ func (r *StatsAggregationRepository) AggregateClicksToStats(ctx context.Context,
 		return fmt.Errorf("failed to aggregate clicks to stats: %w", err)
 	}
 
-	rowsAffected := result.RowsAffected()
 	r.logger.WithFields(logrus.Fields{
 		"rows_affected": rowsAffected,
 		"from":          from,
@@ -346,26 +521,19 @@ func (r *StatsAggregationRepository) AggregateClicksToStats(ctx context.Context,
 	return nil
 }
 
-// AggregateClicksForBanner агрегирует клики для конкретного баннера
+// AggregateClicksForBanner агрегирует клики для конкретного баннера.
+// Как и AggregateClicksToStats, пересчитывает минутные бакеты целиком и безопасен для повторного вызова
 func (r *StatsAggregationRepository) AggregateClicksForBanner(ctx context.Context, bannerID int64, from, to time.Time) error {
-	query := `
-		INSERT INTO stats (banner_id, timestamp, count, created_at, updated_at)
-		SELECT 
-			banner_id,
-			date_trunc('minute', timestamp) as minute_timestamp,
-			COUNT(*) as click_count,
-			NOW() as created_at,
-			NOW() as updated_at
+	bucketsQuery := `
+		SELECT DISTINCT banner_id, date_trunc('minute', timestamp) AS minute_timestamp
 		FROM clicks
-		WHERE banner_id = $1 AND timestamp >= $2 AND timestamp <= $3
-		GROUP BY banner_id, date_trunc('minute', timestamp)
-		ON CONFLICT (banner_id, timestamp)
-		DO UPDATE SET
-			count = stats.count + EXCLUDED.count,
-			updated_at = EXCLUDED.updated_at
+		WHERE banner_id = $1
+			AND timestamp >= date_trunc('minute', $2::timestamptz)
+			AND timestamp < date_trunc('minute', $3::timestamptz) + INTERVAL '1 minute'
+			AND NOT is_duplicate
 	`
 
-	result, err := r.db.Pool.Exec(ctx, query, bannerID, from, to)
+	rowsAffected, err := r.execRecompute(ctx, bucketsQuery, bannerID, from, to)
 	if err != nil {
 		r.logger.WithError(err).WithFields(logrus.Fields{
 			"banner_id": bannerID,
@@ -375,7 +543,6 @@ func (r *StatsAggregationRepository) AggregateClicksForBanner(ctx context.Contex
 		return fmt.Errorf("failed to aggregate clicks for banner: %w", err)
 	}
 
-	rowsAffected := result.RowsAffected()
 	r.logger.WithFields(logrus.Fields{
 		"banner_id":     bannerID,
 		"rows_affected": rowsAffected,
@@ -386,45 +553,284 @@ func (r *StatsAggregationRepository) AggregateClicksForBanner(ctx context.Contex
 	return nil
 }
 
-// GetClickCountsByMinute возвращает количество кликов, сгруппированных по минутам
-func (r *StatsAggregationRepository) GetClickCountsByMinute(ctx context.Context, bannerID int64, from, to time.Time) (map[time.Time]int64, error) {
+// execRecompute пересчитывает в отдельной транзакции минутные бакеты, выбранные bucketsQuery
+func (r *StatsAggregationRepository) execRecompute(ctx context.Context, bucketsQuery string, args ...any) (int64, error) {
+	var rowsAffected int64
+	err := r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		rowsAffected, err = recomputeBuckets(ctx, tx, bucketsQuery, args...)
+		return err
+	})
+	return rowsAffected, err
+}
+
+// bucketLockKeys ключи advisory-блокировки минутного бакета: пара int4 (баннер, минута эпохи).
+// Пространство ключей из двух int4 не пересекается с ключами bigint (блокировка миграций).
+// Совпадение ключей разных бакетов приводит только к лишнему ожиданию
+const bucketLockKeys = `(b.banner_id & 2147483647)::int4, (extract(epoch FROM b.minute_timestamp)::bigint / 60)::int4`
+
+// lockStatsBucketsShared берет разделяемые блокировки бакетов, в которые транзакция записи
+// кликов прибавляет счетчики, и назначает ей ID транзакции до выделения ID кликов.
+// Параллельные записи друг друга не блокируют; пересчет бакета (recomputeBuckets) ждет их
+// коммита, а записи, начатые во время пересчета, ждут его коммита и прибавляются к новому значению.
+// Счетчики должны быть упорядочены, чтобы блокировки брались в одном порядке
+func lockStatsBucketsShared(ctx context.Context, tx pgx.Tx, increments []click.MinuteCount) error {
+	bannerIDs := make([]int64, len(increments))
+	minutes := make([]time.Time, len(increments))
+	for i, inc := range increments {
+		bannerIDs[i] = inc.BannerID
+		minutes[i] = inc.Minute
+	}
+
+	// Запрос заодно назначает ID транзакции до вставки кликов (см. assignClickXactID)
 	query := `
-		SELECT 
-			date_trunc('minute', timestamp) as minute_timestamp,
-			COUNT(*) as click_count
-		FROM clicks
-		WHERE banner_id = $1 AND timestamp >= $2 AND timestamp <= $3
-		GROUP BY date_trunc('minute', timestamp)
-		ORDER BY minute_timestamp ASC
+		SELECT pg_current_xact_id()::text, count(*)
+		FROM (
+			SELECT banner_id, minute_timestamp
+			FROM unnest($1::bigint[], $2::timestamptz[]) AS t(banner_id, minute_timestamp)
+			ORDER BY banner_id, minute_timestamp
+		) b
+		WHERE pg_advisory_xact_lock_shared(` + bucketLockKeys + `)::text = ''
 	`
 
-	rows, err := r.db.Pool.Query(ctx, query, bannerID, from, to)
+	if _, err := tx.Exec(ctx, query, bannerIDs, minutes); err != nil {
+		return fmt.Errorf("failed to lock stats buckets: %w", err)
+	}
+	return nil
+}
+
+// recomputeBuckets пересчитывает из clicks минутные бакеты, выбранные bucketsQuery
+// (колонки banner_id, minute_timestamp), и возвращает количество измененных строк stats.
+//
+// Бакеты блокируются эксклюзивно по одному в порядке
+// (banner_id, minute_timestamp): это дожидается коммита транзакций, прибавляющих счетчики
+// к этим бакетам (lockStatsBucketsShared), и не мешает вставке кликов в остальные бакеты.
+// Пересчет выполняется следующим запросом с новым снимком (READ COMMITTED) и только по
+// заблокированным бакетам, поэтому не перезаписывает незакоммиченный инкремент
+func recomputeBuckets(ctx context.Context, tx pgx.Tx, bucketsQuery string, args ...any) (int64, error) {
+	lockQuery := `
+		SELECT b.banner_id, b.minute_timestamp
+		FROM (` + bucketsQuery + `
+			ORDER BY banner_id, minute_timestamp
+		) b
+		WHERE pg_advisory_xact_lock(` + bucketLockKeys + `)::text = ''
+	`
+
+	rows, err := tx.Query(ctx, lockQuery, args...)
 	if err != nil {
-		r.logger.WithError(err).WithFields(logrus.Fields{
-			"banner_id": bannerID,
-			"from":      from,
-			"to":        to,
-		}).Error("Failed to get click counts by minute")
-		return nil, fmt.Errorf("failed to get click counts by minute: %w", err)
+		return 0, fmt.Errorf("failed to lock stats buckets: %w", err)
 	}
-	defer rows.Close()
 
-	clickCounts := make(map[time.Time]int64)
+	var (
+		bannerIDs []int64
+		minutes   []time.Time
+	)
 	for rows.Next() {
-		var timestamp time.Time
-		var count int64
-		err := rows.Scan(&timestamp, &count)
+		var (
+			bannerID int64
+			minute   time.Time
+		)
+		if err := rows.Scan(&bannerID, &minute); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan stats bucket: %w", err)
+		}
+		bannerIDs = append(bannerIDs, bannerID)
+		minutes = append(minutes, minute)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to lock stats buckets: %w", err)
+	}
+
+	if len(bannerIDs) == 0 {
+		return 0, nil
+	}
+
+	recomputeQuery := `
+		INSERT INTO stats (banner_id, timestamp, count, bot_count, created_at, updated_at)
+		SELECT
+			t.banner_id,
+			t.minute_timestamp,
+			COUNT(*) FILTER (WHERE NOT c.is_bot) as click_count,
+			COUNT(*) FILTER (WHERE c.is_bot) as bot_click_count,
+			NOW() as created_at,
+			NOW() as updated_at
+		FROM unnest($1::bigint[], $2::timestamptz[]) AS t(banner_id, minute_timestamp)
+		JOIN clicks c ON c.banner_id = t.banner_id
+			AND c.timestamp >= t.minute_timestamp
+			AND c.timestamp < t.minute_timestamp + INTERVAL '1 minute'
+			AND NOT c.is_duplicate
+		GROUP BY t.banner_id, t.minute_timestamp
+		ON CONFLICT (banner_id, timestamp)
+		DO UPDATE SET
+			count = EXCLUDED.count,
+			bot_count = EXCLUDED.bot_count,
+			updated_at = EXCLUDED.updated_at
+		WHERE (stats.count, stats.bot_count) IS DISTINCT FROM (EXCLUDED.count, EXCLUDED.bot_count)
+	`
+
+	tag, err := tx.Exec(ctx, recomputeQuery, bannerIDs, minutes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to recompute stats buckets: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// clicksAggregatorName имя записи high-water mark фоновой агрегации кликов
+const clicksAggregatorName = "clicks"
+
+// Ожидание завершения транзакций, выделивших ID кликов до high-water mark
+const (
+	snapshotWaitTimeout  = 5 * time.Second
+	snapshotPollInterval = 20 * time.Millisecond
+)
+
+// GetAggregatedClickID возвращает ID последнего клика, учтенного фоновой агрегацией
+func (r *StatsAggregationRepository) GetAggregatedClickID(ctx context.Context) (int64, error) {
+	var lastID int64
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT last_click_id FROM stats_aggregation_state WHERE name = $1
+	`, clicksAggregatorName).Scan(&lastID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to get aggregation state")
+		return 0, fmt.Errorf("failed to get aggregation state: %w", err)
+	}
+
+	return lastID, nil
+}
+
+// clicksUpperBound возвращает ID, все клики не больше которого закоммичены (или откачены).
+// MAX(id) читается вместе с xmax снимка; затем ожидается, пока самая старая активная
+// транзакция не станет новее xmax. Транзакции записи получают ID транзакции до выделения
+// ID кликов (assignClickXactID), поэтому к этому моменту завершены все транзакции,
+// выделившие ID не больше MAX(id). ok = false - долгая транзакция не завершилась за
+// snapshotWaitTimeout, проход откладывается
+func (r *StatsAggregationRepository) clicksUpperBound(ctx context.Context) (upperID int64, ok bool, err error) {
+	var xmax string
+	err = r.db.Pool.QueryRow(ctx, `
+		SELECT COALESCE(MAX(id), 0), pg_snapshot_xmax(pg_current_snapshot())::text FROM clicks
+	`).Scan(&upperID, &xmax)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to determine clicks upper bound: %w", err)
+	}
+
+	deadline := time.Now().Add(snapshotWaitTimeout)
+	for {
+		var settled bool
+		err = r.db.Pool.QueryRow(ctx, `
+			SELECT pg_snapshot_xmin(pg_current_snapshot()) >= $1::xid8
+		`, xmax).Scan(&settled)
 		if err != nil {
-			r.logger.WithError(err).Error("Failed to scan click counts row")
-			return nil, fmt.Errorf("failed to scan click counts row: %w", err)
+			return 0, false, fmt.Errorf("failed to wait for click transactions: %w", err)
+		}
+		if settled {
+			return upperID, true, nil
+		}
+
+		if time.Now().After(deadline) {
+			return 0, false, nil
+		}
+
+		select {
+		case <-time.After(snapshotPollInterval):
+		case <-ctx.Done():
+			return 0, false, ctx.Err()
 		}
-		clickCounts[timestamp] = count
 	}
+}
 
-	if err := rows.Err(); err != nil {
-		r.logger.WithError(err).Error("Error iterating click counts rows")
-		return nil, fmt.Errorf("error iterating click counts rows: %w", err)
+// AggregatePendingClicks переносит в статистику клики, появившиеся после high-water mark
+func (r *StatsAggregationRepository) AggregatePendingClicks(ctx context.Context, limit int64) (*stats.AggregationResult, error) {
+	upperID, settled, err := r.clicksUpperBound(ctx)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to determine clicks upper bound")
+		return nil, err
+	}
+
+	result := &stats.AggregationResult{}
+	err = r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		stateQuery := `
+			INSERT INTO stats_aggregation_state (name, last_click_id, updated_at)
+			VALUES ($1, 0, NOW())
+			ON CONFLICT (name) DO NOTHING
+		`
+		if _, err := tx.Exec(ctx, stateQuery, clicksAggregatorName); err != nil {
+			return fmt.Errorf("failed to init aggregation state: %w", err)
+		}
+
+		// Блокируем запись состояния, чтобы параллельные агрегаторы не учли клики дважды
+		var lastID int64
+		err := tx.QueryRow(ctx, `
+			SELECT last_click_id
+			FROM stats_aggregation_state
+			WHERE name = $1
+			FOR UPDATE
+		`, clicksAggregatorName).Scan(&lastID)
+		if err != nil {
+			return fmt.Errorf("failed to get aggregation state: %w", err)
+		}
+
+		result.FromClickID = lastID
+		result.ToClickID = lastID
+		if !settled || upperID <= lastID {
+			return nil
+		}
+
+		toID := upperID
+		if limit > 0 && toID-lastID > limit {
+			toID = lastID + limit
+			result.HasMore = true
+		}
+
+		// Пересчитываем целиком каждый минутный бакет, в который попали новые клики.
+		// Абсолютное значение вместо инкремента согласовано с AggregateClicksForBanner:
+		// клик, уже учтенный пересчетом окна, не будет добавлен повторно
+		touchedQuery := `
+			SELECT DISTINCT banner_id, date_trunc('minute', timestamp) AS minute_timestamp
+			FROM clicks
+			WHERE id > $1 AND id <= $2
+		`
+		rowsAffected, err := recomputeBuckets(ctx, tx, touchedQuery, lastID, toID)
+		if err != nil {
+			return fmt.Errorf("failed to aggregate pending clicks: %w", err)
+		}
+		result.RowsAffected = rowsAffected
+
+		updateQuery := `
+			UPDATE stats_aggregation_state
+			SET last_click_id = $2, updated_at = NOW()
+			WHERE name = $1
+		`
+		if _, err := tx.Exec(ctx, updateQuery, clicksAggregatorName, toID); err != nil {
+			return fmt.Errorf("failed to update aggregation state: %w", err)
+		}
+
+		result.ToClickID = toID
+		return nil
+	})
+	if err != nil {
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"last_click_id": result.FromClickID,
+			"upper_id":      upperID,
+		}).Error("Failed to aggregate pending clicks")
+		return nil, err
+	}
+
+	if !settled {
+		r.logger.WithField("last_click_id", result.FromClickID).Warn("Long-running transactions are still open, pending clicks aggregation postponed")
+	}
+
+	if result.ToClickID > result.FromClickID {
+		r.logger.WithFields(logrus.Fields{
+			"from_click_id": result.FromClickID,
+			"to_click_id":   result.ToClickID,
+			"rows_affected": result.RowsAffected,
+			"has_more":      result.HasMore,
+		}).Info("Pending clicks aggregated to stats successfully")
 	}
 
-	return clickCounts, nil
+	return result, nil
 }
@@ -0,0 +1,315 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/domain/click"
+	"github.com/clickcounter/app/migrations"
+)
+
+// testDatabaseURLEnv переменная окружения с DSN тестовой базы. База очищается тестами,
+// поэтому нужна отдельная; без переменной тесты с PostgreSQL пропускаются
+const testDatabaseURLEnv = "CLICKCOUNTER_TEST_DATABASE_URL"
+
+// requireDBTestsEnv переменная окружения, при которой пропуск тестов с PostgreSQL - ошибка (CI)
+const requireDBTestsEnv = "CLICKCOUNTER_REQUIRE_DB_TESTS"
+
+// statsRow значения строки stats, которые пересчитывает агрегация
+type statsRow struct {
+	Count    int64
+	BotCount int64
+}
+
+// newTestDB подключается к тестовой базе, применяет миграции и очищает клики и статистику
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	dsn := os.Getenv(testDatabaseURLEnv)
+	if dsn == "" {
+		message := fmt.Sprintf("PostgreSQL test skipped: %s is not set (see README, \"Тесты репозитория PostgreSQL\")", testDatabaseURLEnv)
+		if os.Getenv(requireDBTestsEnv) != "" {
+			t.Fatal(message)
+		}
+		t.Skip(message)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	db := &DB{Pool: pool, config: &Config{}, logger: logger}
+
+	migrator, err := NewMigrator(db, migrations.FS, logger)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	_, err = pool.Exec(ctx, `TRUNCATE clicks, stats, stats_aggregation_state RESTART IDENTITY`)
+	if err != nil {
+		t.Fatalf("failed to truncate tables: %v", err)
+	}
+
+	return db
+}
+
+// testMinute возвращает начало минуты, отстоящей от base на offset минут
+func testMinute(base time.Time, offset int) time.Time {
+	return base.Add(time.Duration(offset) * time.Minute)
+}
+
+// insertTestClicks вставляет клики напрямую, в обход счетчиков статистики
+func insertTestClicks(t *testing.T, db *DB, clicks []*click.Click) {
+	t.Helper()
+
+	if err := insertClicks(context.Background(), db, db.logger, clicks); err != nil {
+		t.Fatalf("failed to insert clicks: %v", err)
+	}
+}
+
+// readStats возвращает строки stats по (баннер, минута)
+func readStats(t *testing.T, db *DB) map[click.MinuteKey]statsRow {
+	t.Helper()
+
+	rows, err := db.Pool.Query(context.Background(), `SELECT banner_id, timestamp, count, bot_count FROM stats`)
+	if err != nil {
+		t.Fatalf("failed to read stats: %v", err)
+	}
+	defer rows.Close()
+
+	result := make(map[click.MinuteKey]statsRow)
+	for rows.Next() {
+		var (
+			key click.MinuteKey
+			row statsRow
+		)
+		if err := rows.Scan(&key.BannerID, &key.Minute, &row.Count, &row.BotCount); err != nil {
+			t.Fatalf("failed to scan stats row: %v", err)
+		}
+		key.Minute = key.Minute.UTC()
+		result[key] = row
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("failed to read stats: %v", err)
+	}
+
+	return result
+}
+
+// expectedStats подсчитывает ожидаемые строки stats по всем кликам баннеров
+func expectedStats(clicks []*click.Click, bannerIDs ...int64) map[click.MinuteKey]statsRow {
+	include := make(map[int64]bool, len(bannerIDs))
+	for _, id := range bannerIDs {
+		include[id] = true
+	}
+
+	result := make(map[click.MinuteKey]statsRow)
+	for key, count := range click.CountByMinute(clicks) {
+		if len(include) > 0 && !include[key.BannerID] {
+			continue
+		}
+		result[key] = statsRow{Count: count.Count, BotCount: count.BotCount}
+	}
+	return result
+}
+
+// assertStats сравнивает строки stats с ожидаемыми
+func assertStats(t *testing.T, step string, got, want map[click.MinuteKey]statsRow) {
+	t.Helper()
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("%s: stats mismatch\n got: %s\nwant: %s", step, formatStats(got), formatStats(want))
+	}
+}
+
+// formatStats форматирует строки stats для сообщения об ошибке
+func formatStats(rows map[click.MinuteKey]statsRow) string {
+	result := ""
+	for key, row := range rows {
+		result += fmt.Sprintf("[%d %s count=%d bots=%d] ", key.BannerID, key.Minute.Format(time.RFC3339), row.Count, row.BotCount)
+	}
+	return result
+}
+
+// testClicks возвращает клики двух баннеров в пяти минутах, начиная с base,
+// включая дубликаты и клики ботов
+func testClicks(base time.Time) []*click.Click {
+	var clicks []*click.Click
+	for minute := 0; minute < 5; minute++ {
+		for _, bannerID := range []int64{1, 2} {
+			for i := 0; i <= minute; i++ {
+				clicks = append(clicks, &click.Click{
+					BannerID:  bannerID,
+					Timestamp: testMinute(base, minute).Add(time.Duration(i) * time.Second),
+					UserIP:    fmt.Sprintf("192.0.2.%d", i+1),
+					UserAgent: "test-agent",
+					Bot:       i%3 == 2,
+				})
+			}
+			clicks = append(clicks, &click.Click{
+				BannerID:  bannerID,
+				Timestamp: testMinute(base, minute).Add(30 * time.Second),
+				UserIP:    "192.0.2.1",
+				UserAgent: "test-agent",
+				Duplicate: true,
+			})
+		}
+	}
+	return clicks
+}
+
+// testBase начало минуты в прошлом, чтобы все тестовые клики попали в текущую секцию
+func testBase() time.Time {
+	return time.Now().UTC().Truncate(time.Minute).Add(-10 * time.Minute)
+}
+
+func TestAggregateClicksToStatsOverlappingWindows(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewStatsAggregationRepository(db, db.logger)
+	ctx := context.Background()
+
+	base := testBase()
+	clicks := testClicks(base)
+	insertTestClicks(t, db, clicks)
+
+	if err := repo.AggregateClicksToStats(ctx, testMinute(base, 0), testMinute(base, 3)); err != nil {
+		t.Fatalf("first aggregation: %v", err)
+	}
+	if err := repo.AggregateClicksToStats(ctx, testMinute(base, 2), testMinute(base, 4)); err != nil {
+		t.Fatalf("second aggregation: %v", err)
+	}
+	first := readStats(t, db)
+	assertStats(t, "after overlapping windows", first, expectedStats(clicks))
+
+	if err := repo.AggregateClicksToStats(ctx, testMinute(base, 1), testMinute(base, 4)); err != nil {
+		t.Fatalf("repeated aggregation: %v", err)
+	}
+	assertStats(t, "after repeated window", readStats(t, db), first)
+
+	// Клик, пришедший между проходами, учитывается ровно один раз
+	late := &click.Click{BannerID: 1, Timestamp: testMinute(base, 3).Add(45 * time.Second), UserIP: "192.0.2.99", UserAgent: "late"}
+	insertTestClicks(t, db, []*click.Click{late})
+	clicks = append(clicks, late)
+
+	if err := repo.AggregateClicksToStats(ctx, testMinute(base, 2), testMinute(base, 4)); err != nil {
+		t.Fatalf("aggregation after late click: %v", err)
+	}
+	if err := repo.AggregateClicksToStats(ctx, testMinute(base, 3), testMinute(base, 3)); err != nil {
+		t.Fatalf("repeated aggregation after late click: %v", err)
+	}
+	assertStats(t, "after late click", readStats(t, db), expectedStats(clicks))
+}
+
+func TestAggregateClicksForBannerOverlappingWindows(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewStatsAggregationRepository(db, db.logger)
+	ctx := context.Background()
+
+	base := testBase()
+	clicks := testClicks(base)
+	insertTestClicks(t, db, clicks)
+
+	if err := repo.AggregateClicksForBanner(ctx, 1, testMinute(base, 0), testMinute(base, 3)); err != nil {
+		t.Fatalf("first aggregation: %v", err)
+	}
+	if err := repo.AggregateClicksForBanner(ctx, 1, testMinute(base, 2), testMinute(base, 4)); err != nil {
+		t.Fatalf("second aggregation: %v", err)
+	}
+	first := readStats(t, db)
+	assertStats(t, "after overlapping windows", first, expectedStats(clicks, 1))
+
+	if err := repo.AggregateClicksForBanner(ctx, 1, testMinute(base, 0), testMinute(base, 4)); err != nil {
+		t.Fatalf("repeated aggregation: %v", err)
+	}
+	assertStats(t, "after repeated window", readStats(t, db), first)
+
+	late := &click.Click{BannerID: 1, Timestamp: testMinute(base, 4).Add(50 * time.Second), UserIP: "192.0.2.99", UserAgent: "late", Bot: true}
+	insertTestClicks(t, db, []*click.Click{late})
+	clicks = append(clicks, late)
+
+	if err := repo.AggregateClicksForBanner(ctx, 1, testMinute(base, 3), testMinute(base, 4)); err != nil {
+		t.Fatalf("aggregation after late click: %v", err)
+	}
+	if err := repo.AggregateClicksForBanner(ctx, 1, testMinute(base, 4), testMinute(base, 4)); err != nil {
+		t.Fatalf("repeated aggregation after late click: %v", err)
+	}
+	assertStats(t, "after late click", readStats(t, db), expectedStats(clicks, 1))
+}
+
+func TestAggregatePendingClicksIdempotent(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewStatsAggregationRepository(db, db.logger)
+	clickRepo := NewClickRepository(db, db.logger)
+	ctx := context.Background()
+
+	base := testBase()
+	clicks := testClicks(base)
+	insertTestClicks(t, db, clicks)
+
+	// Небольшой лимит: проходы по пересекающимся бакетам
+	for i := 0; ; i++ {
+		result, err := repo.AggregatePendingClicks(ctx, 7)
+		if err != nil {
+			t.Fatalf("aggregation pass %d: %v", i, err)
+		}
+		if !result.HasMore {
+			break
+		}
+		if i > len(clicks) {
+			t.Fatalf("aggregation did not finish after %d passes", i)
+		}
+	}
+	first := readStats(t, db)
+	assertStats(t, "after pending aggregation", first, expectedStats(clicks))
+
+	result, err := repo.AggregatePendingClicks(ctx, 0)
+	if err != nil {
+		t.Fatalf("repeated aggregation: %v", err)
+	}
+	if result.ToClickID != result.FromClickID {
+		t.Fatalf("repeated aggregation advanced high-water mark from %d to %d", result.FromClickID, result.ToClickID)
+	}
+	if err := repo.AggregateClicksToStats(ctx, testMinute(base, 1), testMinute(base, 3)); err != nil {
+		t.Fatalf("window aggregation: %v", err)
+	}
+	assertStats(t, "after repeated and window aggregation", readStats(t, db), first)
+
+	// Клик, записанный сервисом вместе с инкрементом статистики между проходами,
+	// не должен учитываться повторно
+	late := []*click.Click{
+		{BannerID: 2, Timestamp: testMinute(base, 2).Add(40 * time.Second), UserIP: "192.0.2.99", UserAgent: "late"},
+		{BannerID: 2, Timestamp: testMinute(base, 5), UserIP: "192.0.2.99", UserAgent: "late"},
+	}
+	if err := clickRepo.CreateBatchWithCounters(ctx, late, click.CountByMinute(late)); err != nil {
+		t.Fatalf("failed to save late clicks: %v", err)
+	}
+	clicks = append(clicks, late...)
+	assertStats(t, "after late clicks", readStats(t, db), expectedStats(clicks))
+
+	for i := 0; i < 2; i++ {
+		if _, err := repo.AggregatePendingClicks(ctx, 0); err != nil {
+			t.Fatalf("aggregation after late clicks, pass %d: %v", i, err)
+		}
+	}
+	if err := repo.AggregateClicksToStats(ctx, testMinute(base, 2), testMinute(base, 5)); err != nil {
+		t.Fatalf("window aggregation after late clicks: %v", err)
+	}
+	assertStats(t, "after aggregating late clicks", readStats(t, db), expectedStats(clicks))
+}
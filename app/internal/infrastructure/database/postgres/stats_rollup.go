@@ -0,0 +1,446 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/domain/stats"
+	"github.com/clickcounter/app/pkg/hll"
+)
+
+// minuteStatsTable таблица минутной статистики
+const minuteStatsTable = "stats"
+
+// rollupTable описание уровня свертки: откуда и куда сворачивается статистика
+type rollupTable struct {
+	source   string
+	target   string
+	unit     string // единица date_trunc
+	interval string // длительность бакета (без перехода на летнее время)
+}
+
+// rollupTables таблицы уровней свертки
+var rollupTables = map[stats.RollupLevel]rollupTable{
+	stats.RollupHourly: {source: minuteStatsTable, target: "stats_hourly", unit: "hour", interval: "1 hour"},
+	stats.RollupDaily:  {source: "stats_hourly", target: "stats_daily", unit: "day", interval: "24 hours"},
+}
+
+// StatsRollupRepository реализует интерфейс stats.RollupRepository для PostgreSQL
+type StatsRollupRepository struct {
+	db     *DB
+	logger *logrus.Logger
+}
+
+// NewStatsRollupRepository создает новый экземпляр репозитория свертки статистики
+func NewStatsRollupRepository(db *DB, logger *logrus.Logger) *StatsRollupRepository {
+	if logger == nil {
+		logger = logrus.New()
+	}
+
+	return &StatsRollupRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// RollupStats пересчитывает бакеты уровня, в которых изменились строки исходной таблицы.
+// Бакет пересчитывается целиком, поэтому повторный проход по тем же данным ничего не меняет.
+//
+// Проход учитывает строки с updated_at в (source_updated_at, NOW() - settleDelay] и сдвигает
+// source_updated_at на правую границу. Писатели заполняют updated_at через NOW(), то есть
+// временем начала своей транзакции, поэтому строка, закоммиченная позже прохода, не будет
+// пропущена, только если ее транзакция длилась меньше settleDelay (минимум проверяется
+// в конфигурации). Бакеты старше срока хранения источника не пересчитываются: retention
+// уже удалила часть их строк, и пересчет уменьшил бы свернутые значения
+func (r *StatsRollupRepository) RollupStats(ctx context.Context, level stats.RollupLevel, settleDelay, sourceRetention time.Duration, batchSize int) (*stats.RollupResult, error) {
+	table, ok := rollupTables[level]
+	if !ok {
+		return nil, fmt.Errorf("unknown rollup level: %q", level)
+	}
+	if batchSize <= 0 {
+		batchSize = 5000
+	}
+
+	result := &stats.RollupResult{Level: level}
+	err := r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		stateQuery := `
+			INSERT INTO stats_rollup_state (name)
+			VALUES ($1)
+			ON CONFLICT (name) DO NOTHING
+		`
+		if _, err := tx.Exec(ctx, stateQuery, string(level)); err != nil {
+			return fmt.Errorf("failed to init rollup state: %w", err)
+		}
+
+		// Уровень, который уже сворачивает другой экземпляр, пропускаем
+		var sourceUpdatedAt, cutoff time.Time
+		err := tx.QueryRow(ctx, `
+			SELECT source_updated_at, NOW() - make_interval(secs => $2)
+			FROM stats_rollup_state
+			WHERE name = $1
+			FOR UPDATE SKIP LOCKED
+		`, string(level), settleDelay.Seconds()).Scan(&sourceUpdatedAt, &cutoff)
+		if errors.Is(err, pgx.ErrNoRows) {
+			result.Skipped = true
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get rollup state: %w", err)
+		}
+
+		// Дневной уровень не может быть полнее часового, из которого сворачивается
+		completeBefore := cutoff
+		if level == stats.RollupDaily {
+			var hourlyComplete time.Time
+			err := tx.QueryRow(ctx, `
+				SELECT complete_before FROM stats_rollup_state WHERE name = $1
+			`, string(stats.RollupHourly)).Scan(&hourlyComplete)
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("failed to get hourly rollup state: %w", err)
+			}
+			if hourlyComplete.Before(completeBefore) {
+				completeBefore = hourlyComplete
+			}
+		}
+		result.CompleteBefore = completeBefore.UTC().Truncate(level.BucketSize())
+
+		if !cutoff.After(sourceUpdatedAt) {
+			return nil
+		}
+
+		// Курсор ограничивает память: при первом проходе изменившимися считаются все строки
+		cursorQuery := fmt.Sprintf(`
+			DECLARE rollup_dirty NO SCROLL CURSOR FOR
+			SELECT DISTINCT banner_id, date_trunc('%[1]s', timestamp, 'UTC')
+			FROM %[2]s
+			WHERE updated_at > $1 AND updated_at <= $2
+				AND ($3 = 0 OR date_trunc('%[1]s', timestamp, 'UTC') >= NOW() - make_interval(secs => $3))
+		`, table.unit, table.source)
+		if _, err := tx.Exec(ctx, cursorQuery, sourceUpdatedAt, cutoff, sourceRetention.Seconds()); err != nil {
+			return fmt.Errorf("failed to find changed stats buckets: %w", err)
+		}
+
+		for {
+			bannerIDs, buckets, err := fetchRollupBuckets(ctx, tx, batchSize)
+			if err != nil {
+				return err
+			}
+			if len(bannerIDs) == 0 {
+				break
+			}
+
+			if err := rollupBuckets(ctx, tx, table, bannerIDs, buckets); err != nil {
+				return err
+			}
+			result.Buckets += int64(len(bannerIDs))
+		}
+
+		if _, err := tx.Exec(ctx, `CLOSE rollup_dirty`); err != nil {
+			return fmt.Errorf("failed to close rollup cursor: %w", err)
+		}
+
+		updateQuery := `
+			UPDATE stats_rollup_state
+			SET source_updated_at = $2, complete_before = $3, updated_at = NOW()
+			WHERE name = $1
+		`
+		if _, err := tx.Exec(ctx, updateQuery, string(level), cutoff, result.CompleteBefore); err != nil {
+			return fmt.Errorf("failed to update rollup state: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		r.logger.WithError(err).WithField("level", level).Error("Failed to roll up stats")
+		return nil, err
+	}
+
+	if result.Buckets > 0 {
+		r.logger.WithFields(logrus.Fields{
+			"level":           level,
+			"buckets":         result.Buckets,
+			"complete_before": result.CompleteBefore,
+		}).Info("Stats rolled up successfully")
+	}
+
+	return result, nil
+}
+
+// fetchRollupBuckets читает следующую порцию изменившихся бакетов из курсора
+func fetchRollupBuckets(ctx context.Context, tx pgx.Tx, batchSize int) ([]int64, []time.Time, error) {
+	rows, err := tx.Query(ctx, fmt.Sprintf(`FETCH %d FROM rollup_dirty`, batchSize))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch changed stats buckets: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		bannerIDs []int64
+		buckets   []time.Time
+	)
+	for rows.Next() {
+		var bannerID int64
+		var bucket time.Time
+		if err := rows.Scan(&bannerID, &bucket); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan changed stats bucket: %w", err)
+		}
+		bannerIDs = append(bannerIDs, bannerID)
+		buckets = append(buckets, bucket)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch changed stats buckets: %w", err)
+	}
+
+	return bannerIDs, buckets, nil
+}
+
+// rollupBuckets пересчитывает бакеты из исходной таблицы и записывает их абсолютные значения
+func rollupBuckets(ctx context.Context, tx pgx.Tx, table rollupTable, bannerIDs []int64, buckets []time.Time) error {
+	// Скетчи посетителей объединяются в приложении, суммы - в SQL
+	aggregateQuery := fmt.Sprintf(`
+		SELECT d.banner_id, d.bucket,
+			SUM(s.count)::bigint,
+			SUM(s.bot_count)::bigint,
+			array_agg(s.visitors_hll) FILTER (WHERE s.visitors_hll IS NOT NULL)
+		FROM unnest($1::bigint[], $2::timestamptz[]) AS d(banner_id, bucket)
+		JOIN %s s ON s.banner_id = d.banner_id
+			AND s.timestamp >= d.bucket
+			AND s.timestamp < d.bucket + INTERVAL '%s'
+		GROUP BY d.banner_id, d.bucket
+	`, table.source, table.interval)
+
+	rows, err := tx.Query(ctx, aggregateQuery, bannerIDs, buckets)
+	if err != nil {
+		return fmt.Errorf("failed to aggregate %s: %w", table.target, err)
+	}
+
+	var (
+		ids       []int64
+		times     []time.Time
+		counts    []int64
+		botCounts []int64
+		sketches  [][]byte
+	)
+	for rows.Next() {
+		var (
+			bannerID        int64
+			bucket          time.Time
+			count, botCount int64
+			encoded         [][]byte
+		)
+		if err := rows.Scan(&bannerID, &bucket, &count, &botCount, &encoded); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan %s row: %w", table.target, err)
+		}
+
+		var sketch []byte
+		merged, err := mergeVisitorSketches(encoded)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		if merged != nil {
+			if sketch, err = merged.MarshalBinary(); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to encode visitors sketch: %w", err)
+			}
+		}
+
+		ids = append(ids, bannerID)
+		times = append(times, bucket)
+		counts = append(counts, count)
+		botCounts = append(botCounts, botCount)
+		sketches = append(sketches, sketch)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to aggregate %s: %w", table.target, err)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	upsertQuery := fmt.Sprintf(`
+		INSERT INTO %[1]s (banner_id, timestamp, count, bot_count, visitors_hll, updated_at)
+		SELECT banner_id, bucket, count, bot_count, visitors_hll, NOW()
+		FROM unnest($1::bigint[], $2::timestamptz[], $3::bigint[], $4::bigint[], $5::bytea[])
+			AS t(banner_id, bucket, count, bot_count, visitors_hll)
+		ON CONFLICT (banner_id, timestamp)
+		DO UPDATE SET
+			count = EXCLUDED.count,
+			bot_count = EXCLUDED.bot_count,
+			visitors_hll = EXCLUDED.visitors_hll,
+			updated_at = EXCLUDED.updated_at
+		WHERE (%[1]s.count, %[1]s.bot_count, %[1]s.visitors_hll)
+			IS DISTINCT FROM (EXCLUDED.count, EXCLUDED.bot_count, EXCLUDED.visitors_hll)
+	`, table.target)
+
+	if _, err := tx.Exec(ctx, upsertQuery, ids, times, counts, botCounts, sketches); err != nil {
+		return fmt.Errorf("failed to upsert %s: %w", table.target, err)
+	}
+
+	return nil
+}
+
+// mergeVisitorSketches объединяет закодированные скетчи посетителей (nil - скетчей нет)
+func mergeVisitorSketches(encoded [][]byte) (*hll.Sketch, error) {
+	var merged *hll.Sketch
+	for _, data := range encoded {
+		sketch, err := hll.Decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode visitors sketch: %w", err)
+		}
+		if merged == nil {
+			merged = sketch
+			continue
+		}
+		if err := merged.Merge(sketch); err != nil {
+			return nil, fmt.Errorf("failed to merge visitors sketch: %w", err)
+		}
+	}
+	return merged, nil
+}
+
+// statsSource диапазон [from, to) одной таблицы статистики
+type statsSource struct {
+	table    string
+	from, to time.Time
+}
+
+// rollupLevelsFor возвращает уровни свертки, подходящие для размера бакета, от крупного к мелкому.
+// Уровень подходит, если бакет запроса состоит из целых бакетов уровня
+func rollupLevelsFor(granularity stats.Granularity) []stats.RollupLevel {
+	switch granularity {
+	case stats.GranularityHour:
+		return []stats.RollupLevel{stats.RollupHourly}
+	case stats.GranularityDay, stats.GranularityWeek:
+		return []stats.RollupLevel{stats.RollupDaily, stats.RollupHourly}
+	default:
+		return nil
+	}
+}
+
+// planStatsSources разбивает период [from, to) на диапазоны таблиц. Свернутая таблица
+// используется для бакетов, целиком лежащих внутри периода и уже свернутых;
+// края периода и еще не свернутое время читаются из более мелких таблиц
+func planStatsSources(from, to time.Time, levels []stats.RollupLevel, completeBefore map[stats.RollupLevel]time.Time) []statsSource {
+	if !from.Before(to) {
+		return nil
+	}
+	if len(levels) == 0 {
+		return []statsSource{{table: minuteStatsTable, from: from, to: to}}
+	}
+
+	level, finer := levels[0], levels[1:]
+	size := level.BucketSize()
+
+	start := from.Truncate(size)
+	if start.Before(from) {
+		start = start.Add(size)
+	}
+	end := to.Truncate(size)
+	if complete := completeBefore[level]; complete.Before(end) {
+		end = complete
+	}
+
+	if !start.Before(end) {
+		return planStatsSources(from, to, finer, completeBefore)
+	}
+
+	sources := planStatsSources(from, start, finer, completeBefore)
+	sources = append(sources, statsSource{table: rollupTables[level].target, from: start, to: end})
+	return append(sources, planStatsSources(end, to, finer, completeBefore)...)
+}
+
+// GetRollupBoundaries возвращает границы свернутых данных по уровням
+func (r *StatsRollupRepository) GetRollupBoundaries(ctx context.Context) (map[stats.RollupLevel]time.Time, error) {
+	boundaries, err := loadRollupBoundaries(ctx, r.db)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to get rollup boundaries")
+		return nil, err
+	}
+	return boundaries, nil
+}
+
+// DeleteOldRollups удаляет порцию свернутой статистики уровня старше before
+func (r *StatsRepository) DeleteOldRollups(ctx context.Context, level stats.RollupLevel, before time.Time, limit int) (int64, error) {
+	table, ok := rollupTables[level]
+	if !ok {
+		return 0, fmt.Errorf("unknown rollup level: %q", level)
+	}
+
+	query := fmt.Sprintf(`
+		DELETE FROM %[1]s
+		WHERE (banner_id, timestamp) IN (
+			SELECT banner_id, timestamp FROM %[1]s
+			WHERE timestamp < $1
+			LIMIT $2
+		)
+	`, table.target)
+
+	result, err := r.db.Pool.Exec(ctx, query, before, limit)
+	if err != nil {
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"level":  level,
+			"before": before,
+		}).Error("Failed to delete old rollups")
+		return 0, fmt.Errorf("failed to delete old %s: %w", table.target, err)
+	}
+
+	deleted := result.RowsAffected()
+	r.logger.WithFields(logrus.Fields{
+		"level":   level,
+		"deleted": deleted,
+		"before":  before,
+	}).Debug("Old rollups deleted")
+
+	return deleted, nil
+}
+
+// loadRollupBoundaries читает границы свернутых данных по уровням
+func loadRollupBoundaries(ctx context.Context, db *DB) (map[stats.RollupLevel]time.Time, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT name, complete_before FROM stats_rollup_state`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rollup state: %w", err)
+	}
+	defer rows.Close()
+
+	boundaries := make(map[stats.RollupLevel]time.Time, len(rollupTables))
+	for rows.Next() {
+		var name string
+		var completeBefore time.Time
+		if err := rows.Scan(&name, &completeBefore); err != nil {
+			return nil, fmt.Errorf("failed to scan rollup state: %w", err)
+		}
+		boundaries[stats.RollupLevel(name)] = completeBefore
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get rollup state: %w", err)
+	}
+
+	return boundaries, nil
+}
+
+// statsSourcesQuery объединяет строки диапазонов таблиц в один подзапрос.
+// bannerFilter - условие на banner_id по аргументам args, границы диапазонов добавляются к args
+func statsSourcesQuery(sources []statsSource, bannerFilter string, args []any) (string, []any) {
+	parts := make([]string, 0, len(sources))
+	for _, source := range sources {
+		args = append(args, source.from, source.to)
+		parts = append(parts, fmt.Sprintf(`
+			SELECT banner_id, timestamp, count, bot_count, visitors_hll
+			FROM %s
+			WHERE %s AND timestamp >= $%d AND timestamp < $%d`,
+			source.table, bannerFilter, len(args)-1, len(args)))
+	}
+	return strings.Join(parts, "\n\t\t\tUNION ALL"), args
+}
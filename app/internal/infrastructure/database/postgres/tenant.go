@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/clickcounter/app/internal/domain/tenant"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sirupsen/logrus"
+)
+
+// uniqueViolation код ошибки PostgreSQL о нарушении уникальности
+const uniqueViolation = "23505"
+
+// TenantRepository реализует интерфейс tenant.Repository для PostgreSQL
+type TenantRepository struct {
+	db     *DB
+	logger *logrus.Logger
+}
+
+// NewTenantRepository создает новый экземпляр репозитория арендаторов
+func NewTenantRepository(db *DB, logger *logrus.Logger) *TenantRepository {
+	if logger == nil {
+		logger = logrus.New()
+	}
+
+	return &TenantRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetByID возвращает арендатора по ID
+func (r *TenantRepository) GetByID(ctx context.Context, id int64) (*tenant.Tenant, error) {
+	query := `SELECT id, name, created_at FROM tenants WHERE id = $1`
+
+	var t tenant.Tenant
+	err := r.db.Pool.QueryRow(ctx, query, id).Scan(&t.ID, &t.Name, &t.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, tenant.ErrTenantNotFound
+		}
+		r.logger.WithError(err).WithField("tenant_id", id).Error("Failed to get tenant by ID")
+		return nil, fmt.Errorf("failed to get tenant by ID: %w", err)
+	}
+
+	return &t, nil
+}
+
+// List возвращает всех арендаторов
+func (r *TenantRepository) List(ctx context.Context) ([]*tenant.Tenant, error) {
+	query := `SELECT id, name, created_at FROM tenants ORDER BY id ASC`
+
+	rows, err := r.db.Pool.Query(ctx, query)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to list tenants")
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var tenants []*tenant.Tenant
+	for rows.Next() {
+		var t tenant.Tenant
+		if err := rows.Scan(&t.ID, &t.Name, &t.CreatedAt); err != nil {
+			r.logger.WithError(err).Error("Failed to scan tenant row")
+			return nil, fmt.Errorf("failed to scan tenant row: %w", err)
+		}
+		tenants = append(tenants, &t)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.WithError(err).Error("Error iterating tenant rows")
+		return nil, fmt.Errorf("error iterating tenant rows: %w", err)
+	}
+
+	return tenants, nil
+}
+
+// Create создает арендатора, заполняя ID и CreatedAt
+func (r *TenantRepository) Create(ctx context.Context, t *tenant.Tenant) error {
+	query := `
+		INSERT INTO tenants (name)
+		VALUES ($1)
+		RETURNING id, created_at
+	`
+
+	err := r.db.Pool.QueryRow(ctx, query, t.Name).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return tenant.ErrDuplicateName
+		}
+		r.logger.WithError(err).WithField("name", t.Name).Error("Failed to create tenant")
+		return fmt.Errorf("failed to create tenant: %w", err)
+	}
+
+	return nil
+}
+
+// tenantScope возвращает условие " AND <column> = $n", ограничивающее таблицу с колонкой
+// tenant_id (banners, api_keys) арендатором запроса, и args с добавленным ID арендатора.
+// Системная операция (tenant.WithSystem) не ограничивается; без арендатора и без отметки
+// условие ложно, чтобы ошибка в цепочке middleware не открывала данные всех арендаторов
+func tenantScope(ctx context.Context, column string, args []any) (string, []any) {
+	id, ok := tenant.FromContext(ctx)
+	if !ok {
+		return unscoped(ctx), args
+	}
+
+	args = append(args, id)
+	return fmt.Sprintf(" AND %s = $%d", column, len(args)), args
+}
+
+// bannerTenantScope возвращает условие, ограничивающее таблицу с колонкой banner_id
+// (clicks, stats и свертки) баннерами арендатора запроса. Клики и статистика
+// принадлежат арендатору баннера через внешний ключ. Без арендатора - как tenantScope
+func bannerTenantScope(ctx context.Context, column string, args []any) (string, []any) {
+	id, ok := tenant.FromContext(ctx)
+	if !ok {
+		return unscoped(ctx), args
+	}
+
+	args = append(args, id)
+	return fmt.Sprintf(" AND %s IN (SELECT id FROM banners WHERE tenant_id = $%d)", column, len(args)), args
+}
+
+// unscoped возвращает условие для запроса без арендатора: пустое для системной операции, иначе ложное
+func unscoped(ctx context.Context) string {
+	if tenant.IsSystem(ctx) {
+		return ""
+	}
+	return " AND FALSE"
+}
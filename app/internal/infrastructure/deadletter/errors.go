@@ -0,0 +1,8 @@
+package deadletter
+
+import "errors"
+
+var (
+	// ErrInvalidFile возвращается при попытке прочитать файл вне директории dead-letter
+	ErrInvalidFile = errors.New("invalid dead-letter file")
+)
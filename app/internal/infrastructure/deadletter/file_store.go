@@ -0,0 +1,163 @@
+package deadletter
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/domain/click"
+)
+
+const (
+	fileExt = ".ndjson"
+	tmpExt  = ".tmp"
+)
+
+// FileStore реализует интерфейс click.DeadLetterQueue: каждый батч
+// записывается в отдельный NDJSON файл (одна строка - один клик)
+type FileStore struct {
+	dir    string
+	logger *logrus.Logger
+	seq    uint64
+}
+
+// NewFileStore создает хранилище dead-letter файлов в указанной директории
+func NewFileStore(dir string, logger *logrus.Logger) (*FileStore, error) {
+	if logger == nil {
+		logger = logrus.New()
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create dead-letter directory: %w", err)
+	}
+
+	return &FileStore{
+		dir:    dir,
+		logger: logger,
+	}, nil
+}
+
+// Write атомарно сохраняет батч в новый NDJSON файл
+func (s *FileStore) Write(clicks []*click.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	name := fmt.Sprintf("clicks-%s-%06d%s",
+		time.Now().UTC().Format("20060102T150405.000000000"),
+		atomic.AddUint64(&s.seq, 1),
+		fileExt,
+	)
+	path := filepath.Join(s.dir, name)
+	tmpPath := path + tmpExt
+
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create dead-letter file: %w", err)
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, c := range clicks {
+		if err := encoder.Encode(c); err != nil {
+			file.Close()
+			os.Remove(tmpPath)
+			return fmt.Errorf("failed to encode dead-letter click: %w", err)
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write dead-letter file: %w", err)
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to sync dead-letter file: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to close dead-letter file: %w", err)
+	}
+
+	// Переименование делает файл видимым для reingest только целиком
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to publish dead-letter file: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"file":   name,
+		"clicks": len(clicks),
+	}).Warn("Click batch written to dead-letter file")
+
+	return nil
+}
+
+// List возвращает отсортированные пути dead-letter файлов
+func (s *FileStore) List() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead-letter directory: %w", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), fileExt) {
+			continue
+		}
+		files = append(files, filepath.Join(s.dir, entry.Name()))
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// Read читает клики из dead-letter файла
+func (s *FileStore) Read(path string) ([]*click.Click, error) {
+	if filepath.Dir(path) != filepath.Clean(s.dir) || !strings.HasSuffix(path, fileExt) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidFile, path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead-letter file: %w", err)
+	}
+	defer file.Close()
+
+	var clicks []*click.Click
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for decoder.More() {
+		var c click.Click
+		if err := decoder.Decode(&c); err != nil {
+			return nil, fmt.Errorf("failed to decode dead-letter file %s: %w", filepath.Base(path), err)
+		}
+		clicks = append(clicks, &c)
+	}
+
+	return clicks, nil
+}
+
+// Remove удаляет dead-letter файл после успешного повторного сохранения
+func (s *FileStore) Remove(path string) error {
+	if filepath.Dir(path) != filepath.Clean(s.dir) || !strings.HasSuffix(path, fileExt) {
+		return fmt.Errorf("%w: %s", ErrInvalidFile, path)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove dead-letter file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,339 @@
+package livestats
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/domain/click"
+)
+
+// UpdateType тип обновления live-статистики
+type UpdateType string
+
+const (
+	// UpdateRunning - текущее значение еще не закрытой минуты
+	UpdateRunning UpdateType = "running"
+	// UpdateFinal - итог закрытой минуты
+	UpdateFinal UpdateType = "final"
+)
+
+// Update обновление счетчика минуты баннера
+type Update struct {
+	Type     UpdateType `json:"type"`
+	BannerID int64      `json:"banner_id"`
+	Minute   time.Time  `json:"ts"`
+	Count    int64      `json:"v"`    // клики без ботов и дубликатов, как count в stats
+	BotCount int64      `json:"bots"` // клики ботов, как bot_count в stats
+}
+
+// Ошибки подписки
+var (
+	ErrTooManySubscribers = errors.New("too many live stats subscribers")
+	ErrSlowConsumer       = errors.New("live stats subscriber is too slow")
+	ErrHubClosed          = errors.New("live stats hub is closed")
+)
+
+// Options параметры рассылки live-статистики
+type Options struct {
+	BufferSize     int           // емкость буфера обновлений одного подписчика
+	TickInterval   time.Duration // период рассылки изменившихся счетчиков текущей минуты
+	MaxSubscribers int           // максимум одновременных подписчиков (0 - без ограничения)
+}
+
+// HubStats счетчики рассылки для метрик
+type HubStats struct {
+	Subscribers int   `json:"subscribers"`
+	Evicted     int64 `json:"evicted"`
+}
+
+// Subscription подписка на обновления одного баннера
+type Subscription struct {
+	BannerID int64
+
+	hub     *Hub
+	updates chan Update
+	err     error // причина закрытия канала, защищена мьютексом хаба
+}
+
+// Updates возвращает канал обновлений. Канал закрывается при отписке,
+// вытеснении медленного подписчика и остановке хаба
+func (s *Subscription) Updates() <-chan Update {
+	return s.updates
+}
+
+// Err возвращает причину закрытия канала обновлений (nil - подписка закрыта самим подписчиком)
+func (s *Subscription) Err() error {
+	s.hub.mutex.Lock()
+	defer s.hub.mutex.Unlock()
+	return s.err
+}
+
+// Close отменяет подписку
+func (s *Subscription) Close() {
+	s.hub.mutex.Lock()
+	defer s.hub.mutex.Unlock()
+	s.hub.removeUnsafe(s, nil)
+}
+
+// minuteCounter счетчики текущей минуты баннера
+type minuteCounter struct {
+	minute   time.Time
+	count    int64
+	botCount int64
+	changed  bool    // изменился после последней рассылки
+	closed   *Update // итог предыдущей минуты, закрытой кликом новой минуты до тика
+}
+
+// Hub считает клики текущей минуты по баннерам и рассылает обновления подписчикам.
+// Реализует интерфейс click.Publisher. Каждый подписчик получает обновления через
+// собственный буфер; подписчик, не успевающий разбирать буфер, вытесняется, чтобы
+// не задерживать рассылку остальным и регистрацию кликов
+type Hub struct {
+	opts   Options
+	logger *logrus.Logger
+
+	mutex       sync.Mutex
+	minute      time.Time // текущая минута рассылки
+	counters    map[int64]*minuteCounter
+	subscribers map[int64]map[*Subscription]struct{}
+	total       int
+	closed      bool
+
+	evicted int64
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewHub создает хаб live-статистики и запускает периодическую рассылку
+func NewHub(opts Options, logger *logrus.Logger) *Hub {
+	if logger == nil {
+		logger = logrus.New()
+	}
+
+	// Устанавливаем разумные значения по умолчанию если переданы некорректные
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 64
+	}
+	if opts.TickInterval <= 0 {
+		opts.TickInterval = time.Second
+	}
+	if opts.MaxSubscribers < 0 {
+		opts.MaxSubscribers = 0
+	}
+
+	h := &Hub{
+		opts:        opts,
+		logger:      logger,
+		minute:      time.Now().UTC().Truncate(time.Minute),
+		counters:    make(map[int64]*minuteCounter),
+		subscribers: make(map[int64]map[*Subscription]struct{}),
+		done:        make(chan struct{}),
+	}
+
+	h.wg.Add(1)
+	go h.run()
+
+	return h
+}
+
+// Publish учитывает клик в счетчике текущей минуты баннера. Дубликаты не учитываются,
+// как и в минутной статистике
+func (h *Hub) Publish(c *click.Click) {
+	if c.Duplicate {
+		return
+	}
+	minute := c.GetMinuteTimestamp().UTC()
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	counter, ok := h.counters[c.BannerID]
+	switch {
+	case !ok:
+		counter = &minuteCounter{minute: minute}
+		h.counters[c.BannerID] = counter
+	case minute.After(counter.minute):
+		// Минута сменилась раньше тика: итог предыдущей разошлет тик
+		counter.closed = &Update{
+			Type:     UpdateFinal,
+			BannerID: c.BannerID,
+			Minute:   counter.minute,
+			Count:    counter.count,
+			BotCount: counter.botCount,
+		}
+		counter.minute, counter.count, counter.botCount = minute, 0, 0
+	case minute.Before(counter.minute):
+		// Клик закрытой минуты, опоздавший на доли секунды, в live-счетчики не попадает
+		return
+	}
+
+	if c.Bot {
+		counter.botCount++
+	} else {
+		counter.count++
+	}
+	counter.changed = true
+}
+
+// Subscribe подписывается на обновления баннера и возвращает текущее значение его минуты
+func (h *Hub) Subscribe(bannerID int64) (*Subscription, Update, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.closed {
+		return nil, Update{}, ErrHubClosed
+	}
+	if h.opts.MaxSubscribers > 0 && h.total >= h.opts.MaxSubscribers {
+		return nil, Update{}, ErrTooManySubscribers
+	}
+
+	sub := &Subscription{
+		BannerID: bannerID,
+		hub:      h,
+		updates:  make(chan Update, h.opts.BufferSize),
+	}
+	if h.subscribers[bannerID] == nil {
+		h.subscribers[bannerID] = make(map[*Subscription]struct{})
+	}
+	h.subscribers[bannerID][sub] = struct{}{}
+	h.total++
+
+	snapshot := Update{Type: UpdateRunning, BannerID: bannerID, Minute: h.minute}
+	if counter, ok := h.counters[bannerID]; ok && counter.minute.Equal(h.minute) {
+		snapshot.Count, snapshot.BotCount = counter.count, counter.botCount
+	}
+
+	return sub, snapshot, nil
+}
+
+// Stats возвращает количество подписчиков и вытесненных медленных подписчиков
+func (h *Hub) Stats() HubStats {
+	h.mutex.Lock()
+	subscribers := h.total
+	h.mutex.Unlock()
+
+	return HubStats{
+		Subscribers: subscribers,
+		Evicted:     atomic.LoadInt64(&h.evicted),
+	}
+}
+
+// Close останавливает рассылку и закрывает все подписки
+func (h *Hub) Close() {
+	h.closeOnce.Do(func() {
+		close(h.done)
+		h.wg.Wait()
+
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+
+		h.closed = true
+		for _, subs := range h.subscribers {
+			for sub := range subs {
+				h.removeUnsafe(sub, ErrHubClosed)
+			}
+		}
+	})
+}
+
+// run рассылает обновления по таймеру до остановки хаба
+func (h *Hub) run() {
+	defer h.wg.Done()
+
+	ticker := time.NewTicker(h.opts.TickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			h.tick(now)
+		case <-h.done:
+			return
+		}
+	}
+}
+
+// tick закрывает прошедшую минуту и рассылает изменившиеся счетчики текущей
+func (h *Hub) tick(now time.Time) {
+	minute := now.UTC().Truncate(time.Minute)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if minute.After(h.minute) {
+		// Итог отправляется каждому подписчику, в том числе для минуты без кликов
+		for bannerID := range h.subscribers {
+			final := Update{Type: UpdateFinal, BannerID: bannerID, Minute: h.minute}
+			if counter, ok := h.counters[bannerID]; ok {
+				switch {
+				case counter.minute.Equal(h.minute):
+					final.Count, final.BotCount = counter.count, counter.botCount
+				case counter.closed != nil && counter.closed.Minute.Equal(h.minute):
+					final = *counter.closed
+				}
+			}
+			h.broadcastUnsafe(bannerID, final)
+		}
+
+		for bannerID, counter := range h.counters {
+			if counter.minute.Before(minute) {
+				delete(h.counters, bannerID)
+				continue
+			}
+			counter.closed = nil
+		}
+		h.minute = minute
+	}
+
+	for bannerID, counter := range h.counters {
+		if !counter.changed {
+			continue
+		}
+		counter.changed = false
+
+		h.broadcastUnsafe(bannerID, Update{
+			Type:     UpdateRunning,
+			BannerID: bannerID,
+			Minute:   counter.minute,
+			Count:    counter.count,
+			BotCount: counter.botCount,
+		})
+	}
+}
+
+// broadcastUnsafe отправляет обновление подписчикам баннера без ожидания.
+// Подписчик с заполненным буфером вытесняется. Вызывается под мьютексом
+func (h *Hub) broadcastUnsafe(bannerID int64, update Update) {
+	for sub := range h.subscribers[bannerID] {
+		select {
+		case sub.updates <- update:
+		default:
+			atomic.AddInt64(&h.evicted, 1)
+			h.removeUnsafe(sub, ErrSlowConsumer)
+			h.logger.WithField("banner_id", bannerID).Warn("Slow live stats subscriber evicted")
+		}
+	}
+}
+
+// removeUnsafe удаляет подписку и закрывает ее канал. Вызывается под мьютексом
+func (h *Hub) removeUnsafe(sub *Subscription, reason error) {
+	subs := h.subscribers[sub.BannerID]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subscribers, sub.BannerID)
+	}
+	h.total--
+
+	sub.err = reason
+	close(sub.updates)
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/clickcounter/app/internal/infrastructure/cache/memory"
+)
+
+// memoryCacheCollector снимает счетчики кэшей в памяти в момент сбора метрик
+type memoryCacheCollector struct {
+	caches map[string]*memory.MemoryCache
+
+	hits   *prometheus.Desc
+	misses *prometheus.Desc
+	items  *prometheus.Desc
+}
+
+// NewMemoryCacheCollector создает коллектор счетчиков кэшей; ключ карты - имя кэша
+func NewMemoryCacheCollector(caches map[string]*memory.MemoryCache) prometheus.Collector {
+	return &memoryCacheCollector{
+		caches: caches,
+		hits: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cache", "hits_total"),
+			"Количество попаданий в кэш",
+			[]string{"cache"}, nil,
+		),
+		misses: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cache", "misses_total"),
+			"Количество промахов кэша",
+			[]string{"cache"}, nil,
+		),
+		items: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cache", "items"),
+			"Количество элементов в кэше (включая истекшие, еще не удаленные очисткой)",
+			[]string{"cache"}, nil,
+		),
+	}
+}
+
+// Describe реализует prometheus.Collector
+func (c *memoryCacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.items
+}
+
+// Collect реализует prometheus.Collector
+func (c *memoryCacheCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, cache := range c.caches {
+		stats := cache.Stats()
+		ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits), name)
+		ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses), name)
+		ch <- prometheus.MustNewConstMetric(c.items, prometheus.GaugeValue, float64(stats.Items), name)
+	}
+}
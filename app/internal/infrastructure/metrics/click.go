@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/clickcounter/app/internal/domain/click"
+)
+
+// ClickFlushMetrics метрики записи батчей кликов в БД
+type ClickFlushMetrics struct {
+	duration *prometheus.HistogramVec
+	size     prometheus.Histogram
+}
+
+// NewClickFlushMetrics создает и регистрирует метрики записи батчей кликов
+func NewClickFlushMetrics(registerer prometheus.Registerer) *ClickFlushMetrics {
+	m := &ClickFlushMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "click_flush",
+			Name:      "duration_seconds",
+			Help:      "Время записи батча кликов в БД (одна попытка)",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"result"}),
+		size: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "click_flush",
+			Name:      "batch_size",
+			Help:      "Количество кликов в записываемом батче",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 9),
+		}),
+	}
+
+	registerer.MustRegister(m.duration, m.size)
+	return m
+}
+
+// Observe учитывает попытку записи батча (сигнатура click.Options.FlushObserver)
+func (m *ClickFlushMetrics) Observe(clicks int, duration time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+
+	m.duration.WithLabelValues(result).Observe(duration.Seconds())
+	m.size.Observe(float64(clicks))
+}
+
+// clickPipelineCollector снимает состояние конвейера кликов в момент сбора метрик
+type clickPipelineCollector struct {
+	stats func() click.PipelineStats
+
+	queueDepth    *prometheus.Desc
+	queueCapacity *prometheus.Desc
+	inFlight      *prometheus.Desc
+	clicks        *prometheus.Desc
+	flushErrors   *prometheus.Desc
+	retries       *prometheus.Desc
+}
+
+// NewClickPipelineCollector создает коллектор состояния конвейера кликов.
+// stats обычно click.Service.Stats
+func NewClickPipelineCollector(stats func() click.PipelineStats) prometheus.Collector {
+	return &clickPipelineCollector{
+		stats: stats,
+		queueDepth: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "click_queue", "depth"),
+			"Количество кликов, ожидающих записи (в очереди и в собираемом батче)",
+			nil, nil,
+		),
+		queueCapacity: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "click_queue", "capacity"),
+			"Емкость очереди кликов",
+			nil, nil,
+		),
+		inFlight: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "click_flush", "in_flight"),
+			"Количество батчей, записываемых в БД",
+			nil, nil,
+		),
+		clicks: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "clicks", "total"),
+			"Количество кликов по исходу обработки",
+			[]string{"outcome"}, nil,
+		),
+		flushErrors: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "click_flush", "errors_total"),
+			"Количество батчей, не записанных в БД после всех повторов",
+			nil, nil,
+		),
+		retries: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "click_flush", "retries_total"),
+			"Количество повторных попыток записи батчей",
+			nil, nil,
+		),
+	}
+}
+
+// Describe реализует prometheus.Collector
+func (c *clickPipelineCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.queueDepth
+	ch <- c.queueCapacity
+	ch <- c.inFlight
+	ch <- c.clicks
+	ch <- c.flushErrors
+	ch <- c.retries
+}
+
+// Collect реализует prometheus.Collector
+func (c *clickPipelineCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+
+	ch <- prometheus.MustNewConstMetric(c.queueDepth, prometheus.GaugeValue, float64(stats.QueueDepth))
+	ch <- prometheus.MustNewConstMetric(c.queueCapacity, prometheus.GaugeValue, float64(stats.QueueCapacity))
+	ch <- prometheus.MustNewConstMetric(c.inFlight, prometheus.GaugeValue, float64(stats.InFlight))
+
+	ch <- prometheus.MustNewConstMetric(c.clicks, prometheus.CounterValue, float64(stats.Enqueued), "enqueued")
+	ch <- prometheus.MustNewConstMetric(c.clicks, prometheus.CounterValue, float64(stats.Dropped), "dropped")
+	ch <- prometheus.MustNewConstMetric(c.clicks, prometheus.CounterValue, float64(stats.Duplicates), "duplicate")
+	ch <- prometheus.MustNewConstMetric(c.clicks, prometheus.CounterValue, float64(stats.Bots), "bot")
+	ch <- prometheus.MustNewConstMetric(c.clicks, prometheus.CounterValue, float64(stats.Rejected), "rejected")
+	ch <- prometheus.MustNewConstMetric(c.clicks, prometheus.CounterValue, float64(stats.Flushed), "flushed")
+	ch <- prometheus.MustNewConstMetric(c.clicks, prometheus.CounterValue, float64(stats.DeadLettered), "dead_lettered")
+
+	ch <- prometheus.MustNewConstMetric(c.flushErrors, prometheus.CounterValue, float64(stats.FlushErrors))
+	ch <- prometheus.MustNewConstMetric(c.retries, prometheus.CounterValue, float64(stats.Retries))
+}
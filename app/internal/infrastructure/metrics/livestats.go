@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/clickcounter/app/internal/infrastructure/livestats"
+)
+
+// liveStatsCollector снимает состояние рассылки live-статистики в момент сбора метрик
+type liveStatsCollector struct {
+	stats func() livestats.HubStats
+
+	subscribers *prometheus.Desc
+	evicted     *prometheus.Desc
+}
+
+// NewLiveStatsCollector создает коллектор рассылки live-статистики.
+// stats обычно livestats.Hub.Stats
+func NewLiveStatsCollector(stats func() livestats.HubStats) prometheus.Collector {
+	return &liveStatsCollector{
+		stats: stats,
+		subscribers: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "live_stats", "subscribers"),
+			"Количество открытых потоков live-статистики",
+			nil, nil,
+		),
+		evicted: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "live_stats", "evicted_total"),
+			"Количество потоков, закрытых из-за медленного клиента",
+			nil, nil,
+		),
+	}
+}
+
+// Describe реализует prometheus.Collector
+func (c *liveStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.subscribers
+	ch <- c.evicted
+}
+
+// Collect реализует prometheus.Collector
+func (c *liveStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+	ch <- prometheus.MustNewConstMetric(c.subscribers, prometheus.GaugeValue, float64(stats.Subscribers))
+	ch <- prometheus.MustNewConstMetric(c.evicted, prometheus.CounterValue, float64(stats.Evicted))
+}
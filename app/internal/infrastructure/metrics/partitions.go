@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// partitionsCollector снимает количество кликов в секции по умолчанию в момент сбора метрик
+type partitionsCollector struct {
+	defaultRows func() int64
+
+	defaultClicks *prometheus.Desc
+}
+
+// NewClickPartitionsCollector создает коллектор секций таблицы кликов.
+// defaultRows обычно postgres.PartitionManager.DefaultPartitionRows
+func NewClickPartitionsCollector(defaultRows func() int64) prometheus.Collector {
+	return &partitionsCollector{
+		defaultRows: defaultRows,
+		defaultClicks: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "click_partitions", "default_rows"),
+			"Количество кликов в секции по умолчанию при последней проверке (должно быть 0)",
+			nil, nil,
+		),
+	}
+}
+
+// Describe реализует prometheus.Collector
+func (c *partitionsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.defaultClicks
+}
+
+// Collect реализует prometheus.Collector
+func (c *partitionsCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(c.defaultClicks, prometheus.GaugeValue, float64(c.defaultRows()))
+}
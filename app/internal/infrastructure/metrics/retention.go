@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// retentionCollector снимает количество строк, удаленных очисткой, в момент сбора метрик
+type retentionCollector struct {
+	totals func() map[string]int64
+
+	deleted *prometheus.Desc
+}
+
+// NewRetentionCollector создает коллектор очистки устаревших данных.
+// totals обычно worker.Retention.Totals
+func NewRetentionCollector(totals func() map[string]int64) prometheus.Collector {
+	return &retentionCollector{
+		totals: totals,
+		deleted: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "retention", "deleted_rows_total"),
+			"Количество строк, удаленных по сроку хранения",
+			[]string{"table"}, nil,
+		),
+	}
+}
+
+// Describe реализует prometheus.Collector
+func (c *retentionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.deleted
+}
+
+// Collect реализует prometheus.Collector
+func (c *retentionCollector) Collect(ch chan<- prometheus.Metric) {
+	for table, deleted := range c.totals() {
+		ch <- prometheus.MustNewConstMetric(c.deleted, prometheus.CounterValue, float64(deleted), table)
+	}
+}
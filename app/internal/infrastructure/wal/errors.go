@@ -0,0 +1,17 @@
+package wal
+
+import "errors"
+
+var (
+	// ErrLogClosed возвращается при попытке записи в закрытый журнал
+	ErrLogClosed = errors.New("write-ahead log is closed")
+
+	// ErrInvalidSyncPolicy возвращается при неизвестной политике fsync
+	ErrInvalidSyncPolicy = errors.New("invalid write-ahead log sync policy")
+
+	// ErrCorruptedRecord возвращается при повреждении записи сегмента
+	ErrCorruptedRecord = errors.New("corrupted write-ahead log record")
+
+	// ErrRecordTooLarge возвращается при попытке записать клик больше maxRecordSize
+	ErrRecordTooLarge = errors.New("write-ahead log record is too large")
+)
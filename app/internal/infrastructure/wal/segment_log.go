@@ -0,0 +1,392 @@
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/domain/click"
+)
+
+// SyncPolicy определяет, когда данные сегмента сбрасываются на диск через fsync
+type SyncPolicy string
+
+const (
+	// SyncAlways - fsync после каждой записи
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval - fsync по таймеру, если были записи
+	SyncInterval SyncPolicy = "interval"
+	// SyncNone - fsync только при закрытии сегмента, остальное на усмотрение ОС
+	SyncNone SyncPolicy = "none"
+)
+
+const (
+	segmentExt       = ".wal"
+	recordHeaderSize = 8 // длина payload (uint32) + crc32 (uint32)
+
+	// maxRecordSize ограничивает payload записи. User-Agent ограничен заголовками запроса
+	// (MaxHeaderBytes сервера, 1 МБ), а JSON экранирует символ не более чем в 6 байт.
+	// Длина больше предела в заголовке записи - признак поврежденного хвоста, а не клика
+	maxRecordSize = 8 << 20
+)
+
+// ParseSyncPolicy преобразует строку конфигурации в политику fsync
+func ParseSyncPolicy(value string) (SyncPolicy, error) {
+	switch policy := SyncPolicy(value); policy {
+	case SyncAlways, SyncInterval, SyncNone:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidSyncPolicy, value)
+	}
+}
+
+// SegmentLog реализует интерфейс click.Journal поверх append-only сегментов на диске.
+// Seal закрывает текущий сегмент, Release удаляет его после того,
+// как все записанные в него клики сохранены в БД
+type SegmentLog struct {
+	dir    string
+	policy SyncPolicy
+	logger *logrus.Logger
+
+	mutex    sync.Mutex
+	active   *os.File
+	activeID uint64
+	nextID   uint64
+	dirty    bool
+	closed   bool
+
+	// Сегменты, оставшиеся от предыдущего запуска
+	recovered []uint64
+
+	ticker *time.Ticker
+	done   chan struct{}
+}
+
+// NewSegmentLog открывает журнал в указанной директории
+func NewSegmentLog(dir string, policy SyncPolicy, syncInterval time.Duration, logger *logrus.Logger) (*SegmentLog, error) {
+	if logger == nil {
+		logger = logrus.New()
+	}
+
+	if _, err := ParseSyncPolicy(string(policy)); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create write-ahead log directory: %w", err)
+	}
+
+	ids, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &SegmentLog{
+		dir:       dir,
+		policy:    policy,
+		logger:    logger,
+		nextID:    1,
+		recovered: ids,
+		done:      make(chan struct{}),
+	}
+	if len(ids) > 0 {
+		l.nextID = ids[len(ids)-1] + 1
+	}
+
+	if policy == SyncInterval {
+		if syncInterval <= 0 {
+			syncInterval = 100 * time.Millisecond
+		}
+		l.ticker = time.NewTicker(syncInterval)
+		go l.startSync()
+	}
+
+	logger.WithFields(logrus.Fields{
+		"dir":                dir,
+		"sync_policy":        policy,
+		"recovered_segments": len(ids),
+	}).Info("Click write-ahead log opened")
+
+	return l, nil
+}
+
+// Append дописывает клик в текущий сегмент и возвращает ID сегмента
+func (l *SegmentLog) Append(c *click.Click) (uint64, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal click for write-ahead log: %w", err)
+	}
+	if len(payload) > maxRecordSize {
+		return 0, fmt.Errorf("%w: %d bytes", ErrRecordTooLarge, len(payload))
+	}
+
+	record := make([]byte, recordHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	copy(record[recordHeaderSize:], payload)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.closed {
+		return 0, ErrLogClosed
+	}
+
+	if l.active == nil {
+		if err := l.openSegmentUnsafe(); err != nil {
+			return 0, err
+		}
+	}
+
+	// Пишем без буферизации: запись переживает падение процесса сразу после Append
+	if _, err := l.active.Write(record); err != nil {
+		return 0, fmt.Errorf("failed to append click to write-ahead log: %w", err)
+	}
+
+	if l.policy == SyncAlways {
+		if err := l.active.Sync(); err != nil {
+			return 0, fmt.Errorf("failed to sync write-ahead log: %w", err)
+		}
+	} else {
+		l.dirty = true
+	}
+
+	return l.activeID, nil
+}
+
+// Seal закрывает текущий сегмент и возвращает его ID (0, если записей не было)
+func (l *SegmentLog) Seal() (uint64, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.active == nil {
+		return 0, nil
+	}
+
+	id := l.activeID
+	if err := l.closeSegmentUnsafe(); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Release удаляет сегмент, клики из которого сохранены в БД
+func (l *SegmentLog) Release(segmentID uint64) error {
+	if segmentID == 0 {
+		return nil
+	}
+
+	if err := os.Remove(l.segmentPath(segmentID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove write-ahead log segment %d: %w", segmentID, err)
+	}
+
+	return nil
+}
+
+// Replay последовательно передает в fn клики из сегментов, оставшихся от предыдущего запуска
+func (l *SegmentLog) Replay(fn func(segmentID uint64, clicks []*click.Click) error) error {
+	l.mutex.Lock()
+	recovered := l.recovered
+	l.recovered = nil
+	l.mutex.Unlock()
+
+	for i, id := range recovered {
+		clicks, err := l.readSegment(id)
+		if err != nil {
+			l.restoreRecovered(recovered[i:])
+			return err
+		}
+
+		if err := fn(id, clicks); err != nil {
+			l.restoreRecovered(recovered[i:])
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close закрывает журнал, сбрасывая активный сегмент на диск
+func (l *SegmentLog) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+
+	if l.ticker != nil {
+		l.ticker.Stop()
+		close(l.done)
+	}
+
+	if l.active != nil {
+		return l.closeSegmentUnsafe()
+	}
+
+	return nil
+}
+
+// startSync периодически выполняет fsync активного сегмента
+func (l *SegmentLog) startSync() {
+	for {
+		select {
+		case <-l.ticker.C:
+			l.mutex.Lock()
+			if l.active != nil && l.dirty {
+				if err := l.active.Sync(); err != nil {
+					l.logger.WithError(err).Error("Failed to sync write-ahead log segment")
+				} else {
+					l.dirty = false
+				}
+			}
+			l.mutex.Unlock()
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// openSegmentUnsafe открывает новый активный сегмент (без мьютекса)
+func (l *SegmentLog) openSegmentUnsafe() error {
+	id := l.nextID
+	file, err := os.OpenFile(l.segmentPath(id), os.O_CREATE|os.O_WRONLY|os.O_APPEND|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open write-ahead log segment %d: %w", id, err)
+	}
+
+	l.active = file
+	l.activeID = id
+	l.nextID = id + 1
+	l.dirty = false
+
+	return nil
+}
+
+// closeSegmentUnsafe сбрасывает и закрывает активный сегмент (без мьютекса)
+func (l *SegmentLog) closeSegmentUnsafe() error {
+	file := l.active
+	l.active = nil
+	l.dirty = false
+
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to sync write-ahead log segment %d: %w", l.activeID, err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close write-ahead log segment %d: %w", l.activeID, err)
+	}
+
+	return nil
+}
+
+// readSegment читает клики из сегмента, отбрасывая недописанный хвост
+func (l *SegmentLog) readSegment(id uint64) ([]*click.Click, error) {
+	file, err := os.Open(l.segmentPath(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open write-ahead log segment %d: %w", id, err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	header := make([]byte, recordHeaderSize)
+
+	var clicks []*click.Click
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if err == io.EOF {
+				return clicks, nil
+			}
+			// Частично записанный заголовок - процесс упал посреди Append
+			l.logTornRecord(id, len(clicks), err)
+			return clicks, nil
+		}
+
+		size := binary.LittleEndian.Uint32(header[0:4])
+		checksum := binary.LittleEndian.Uint32(header[4:8])
+
+		// Длину из поврежденного заголовка нельзя использовать для выделения памяти
+		if size > maxRecordSize {
+			l.logTornRecord(id, len(clicks), fmt.Errorf("%w: record length %d exceeds %d", ErrCorruptedRecord, size, maxRecordSize))
+			return clicks, nil
+		}
+
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			l.logTornRecord(id, len(clicks), err)
+			return clicks, nil
+		}
+
+		if crc32.ChecksumIEEE(payload) != checksum {
+			l.logTornRecord(id, len(clicks), ErrCorruptedRecord)
+			return clicks, nil
+		}
+
+		var c click.Click
+		if err := json.Unmarshal(payload, &c); err != nil {
+			return nil, fmt.Errorf("%w: segment %d: %v", ErrCorruptedRecord, id, err)
+		}
+		clicks = append(clicks, &c)
+	}
+}
+
+// logTornRecord логирует отброшенный хвост сегмента
+func (l *SegmentLog) logTornRecord(id uint64, recordsRead int, err error) {
+	l.logger.WithError(err).WithFields(logrus.Fields{
+		"segment_id":   id,
+		"records_read": recordsRead,
+	}).Warn("Discarding torn tail of write-ahead log segment")
+}
+
+// restoreRecovered возвращает необработанные сегменты для повторного Replay
+func (l *SegmentLog) restoreRecovered(ids []uint64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.recovered = append(append([]uint64(nil), ids...), l.recovered...)
+}
+
+// segmentPath возвращает путь к файлу сегмента
+func (l *SegmentLog) segmentPath(id uint64) string {
+	return filepath.Join(l.dir, fmt.Sprintf("%020d%s", id, segmentExt))
+}
+
+// listSegments возвращает отсортированные ID сегментов в директории
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read write-ahead log directory: %w", err)
+	}
+
+	var ids []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil || id == 0 {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
@@ -11,4 +11,16 @@ var (
 
 	// ErrInvalidTimeFormat возвращается при некорректном формате времени
 	ErrInvalidTimeFormat = errors.New("invalid time format, expected RFC3339")
+
+	// ErrInvalidBannerSet возвращается, если не задан ровно один из banner_ids и all_active
+	ErrInvalidBannerSet = errors.New("exactly one of banner_ids and all_active is required")
+
+	// ErrInvalidFormat возвращается при неизвестном формате ответа в параметре format
+	ErrInvalidFormat = errors.New("invalid format, expected json, csv or ndjson")
+
+	// ErrInvalidIPFilter возвращается при некорректном адресе или подсети в фильтре по IP
+	ErrInvalidIPFilter = errors.New("invalid ip filter, expected IP address or CIDR")
+
+	// ErrInvalidCursor возвращается при некорректной позиции пагинации
+	ErrInvalidCursor = errors.New("invalid cursor")
 )
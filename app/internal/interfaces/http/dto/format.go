@@ -0,0 +1,89 @@
+package dto
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// StatsFormat формат ответа статистики
+type StatsFormat string
+
+// Поддерживаемые форматы ответа статистики
+const (
+	StatsFormatJSON   StatsFormat = "json"
+	StatsFormatCSV    StatsFormat = "csv"
+	StatsFormatNDJSON StatsFormat = "ndjson"
+)
+
+// statsMediaTypes форматы по типам Accept. application/json и шаблоны оставляют JSON
+var statsMediaTypes = map[string]StatsFormat{
+	"text/csv":             StatsFormatCSV,
+	"application/x-ndjson": StatsFormatNDJSON,
+	"application/ndjson":   StatsFormatNDJSON,
+	"application/json":     StatsFormatJSON,
+	"application/*":        StatsFormatJSON,
+	"*/*":                  StatsFormatJSON,
+}
+
+// StatsFormatFromRequest определяет формат ответа статистики по параметру запроса format
+// (json, csv, ndjson) или, если он не задан, по заголовку Accept. Формат задается в URL,
+// а не в теле, чтобы его можно было определить до чтения тела запроса. Заголовок Accept
+// без поддерживаемых типов оставляет JSON, как до появления выгрузки
+func StatsFormatFromRequest(r *http.Request) (StatsFormat, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		switch f := StatsFormat(strings.ToLower(format)); f {
+		case StatsFormatJSON, StatsFormatCSV, StatsFormatNDJSON:
+			return f, nil
+		default:
+			return "", ErrInvalidFormat
+		}
+	}
+
+	return negotiateStatsFormat(r.Header.Get("Accept")), nil
+}
+
+// negotiateStatsFormat выбирает поддерживаемый тип с наибольшим q из заголовка Accept.
+// При равных q побеждает тип, указанный раньше
+func negotiateStatsFormat(accept string) StatsFormat {
+	best, bestQ := StatsFormatJSON, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		format, ok := statsMediaTypes[strings.ToLower(strings.TrimSpace(params[0]))]
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		if q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+	return best
+}
+
+// Streamed сообщает, отдается ли формат потоком (выгрузка) вместо документа JSON
+func (f StatsFormat) Streamed() bool {
+	return f == StatsFormatCSV || f == StatsFormatNDJSON
+}
+
+// ContentType возвращает тип содержимого ответа
+func (f StatsFormat) ContentType() string {
+	switch f {
+	case StatsFormatCSV:
+		return "text/csv; charset=utf-8"
+	case StatsFormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/json; charset=utf-8"
+	}
+}
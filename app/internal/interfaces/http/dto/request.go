@@ -1,13 +1,29 @@
 package dto
 
 import (
+	"encoding/base64"
+	"net/netip"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/clickcounter/app/internal/domain/apikey"
+	"github.com/clickcounter/app/internal/domain/click"
 )
 
 // StatsRequest представляет запрос для получения статистики
 type StatsRequest struct {
 	From string `json:"from" binding:"required" example:"2024-12-12T10:00:00Z"`
 	To   string `json:"to" binding:"required" example:"2024-12-12T10:05:00Z"`
+
+	// IncludeBots - учитывать клики, помеченные фильтром ботов
+	IncludeBots bool `json:"include_bots,omitempty" example:"false"`
+
+	// Uniques - добавить оценку уникальных посетителей (поле u)
+	Uniques bool `json:"uniques,omitempty" example:"false"`
+
+	// Granularity - размер бакета: minute (по умолчанию), 5m, hour, day, week
+	Granularity string `json:"granularity,omitempty" example:"hour"`
 }
 
 // Validate проверяет корректность временных меток
@@ -38,3 +54,179 @@ func (r *StatsRequest) GetFromTime() (time.Time, error) {
 func (r *StatsRequest) GetToTime() (time.Time, error) {
 	return time.Parse(time.RFC3339, r.To)
 }
+
+// MultiStatsRequest представляет запрос статистики набора баннеров
+type MultiStatsRequest struct {
+	StatsRequest
+
+	// BannerIDs - ID баннеров (не более 500)
+	BannerIDs []int64 `json:"banner_ids,omitempty" example:"1,2,3"`
+
+	// AllActive - все активные баннеры вместо banner_ids
+	AllActive bool `json:"all_active,omitempty" example:"false"`
+}
+
+// Validate проверяет временные метки и выбор баннеров
+func (r *MultiStatsRequest) Validate() error {
+	if err := r.StatsRequest.Validate(); err != nil {
+		return err
+	}
+
+	if r.AllActive == (len(r.BannerIDs) > 0) {
+		return ErrInvalidBannerSet
+	}
+
+	return nil
+}
+
+// BannerRequest представляет запрос на создание или изменение баннера
+type BannerRequest struct {
+	Name     string `json:"name" binding:"required" example:"Summer sale"`
+	StartsAt string `json:"starts_at,omitempty" example:"2024-06-01T00:00:00Z"`
+	EndsAt   string `json:"ends_at,omitempty" example:"2024-09-01T00:00:00Z"`
+}
+
+// GetStartsAt возвращает начало окна показа (nil, если не задано)
+func (r *BannerRequest) GetStartsAt() (*time.Time, error) {
+	return parseOptionalTime(r.StartsAt)
+}
+
+// GetEndsAt возвращает окончание окна показа (nil, если не задано)
+func (r *BannerRequest) GetEndsAt() (*time.Time, error) {
+	return parseOptionalTime(r.EndsAt)
+}
+
+// APIKeyRequest представляет запрос на создание API ключа
+type APIKeyRequest struct {
+	Name   string   `json:"name" binding:"required" example:"Partner dashboard"`
+	Scopes []string `json:"scopes" binding:"required" example:"stats:read"`
+
+	// BannerIDs - баннеры, доступные ключу; пусто - все баннеры
+	BannerIDs []int64 `json:"banner_ids,omitempty" example:"1,2"`
+
+	// ExpiresAt - время истечения ключа; пусто - бессрочный
+	ExpiresAt string `json:"expires_at,omitempty" example:"2025-12-31T00:00:00Z"`
+}
+
+// GetScopes возвращает области доступа ключа
+func (r *APIKeyRequest) GetScopes() ([]apikey.Scope, error) {
+	scopes := make([]apikey.Scope, len(r.Scopes))
+	for i, value := range r.Scopes {
+		scope, err := apikey.ParseScope(value)
+		if err != nil {
+			return nil, err
+		}
+		scopes[i] = scope
+	}
+	return scopes, nil
+}
+
+// GetExpiresAt возвращает время истечения ключа (nil, если не задано)
+func (r *APIKeyRequest) GetExpiresAt() (*time.Time, error) {
+	return parseOptionalTime(r.ExpiresAt)
+}
+
+// parseOptionalTime разбирает необязательную временную метку в формате RFC3339
+func parseOptionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, ErrInvalidTimeFormat
+	}
+
+	return &t, nil
+}
+
+// ClickSearchRequest представляет параметры поиска кликов баннера (query string)
+type ClickSearchRequest struct {
+	From      string `form:"from" binding:"required" example:"2024-12-12T00:00:00Z"`
+	To        string `form:"to" binding:"required" example:"2024-12-13T00:00:00Z"`
+	IP        string `form:"ip" example:"203.0.113.0/24"`         // адрес или подсеть CIDR
+	UserAgent string `form:"user_agent" example:"HeadlessChrome"` // подстрока без учета регистра
+	Limit     int    `form:"limit" example:"100"`
+	Cursor    string `form:"cursor"` // next_cursor предыдущей страницы
+}
+
+// Filter преобразует параметры в фильтр поиска кликов баннера
+func (r *ClickSearchRequest) Filter(bannerID int64) (click.SearchFilter, error) {
+	filter := click.SearchFilter{
+		BannerID:  bannerID,
+		UserAgent: r.UserAgent,
+		Limit:     r.Limit,
+	}
+
+	var err error
+	if filter.From, err = time.Parse(time.RFC3339, r.From); err != nil {
+		return filter, ErrInvalidTimeFormat
+	}
+	if filter.To, err = time.Parse(time.RFC3339, r.To); err != nil {
+		return filter, ErrInvalidTimeFormat
+	}
+
+	if r.IP != "" {
+		if filter.IP, err = parseIPFilter(r.IP); err != nil {
+			return filter, ErrInvalidIPFilter
+		}
+	}
+
+	if r.Cursor != "" {
+		if filter.After, err = DecodeClickCursor(r.Cursor); err != nil {
+			return filter, ErrInvalidCursor
+		}
+	}
+
+	return filter, nil
+}
+
+// parseIPFilter разбирает адрес (префикс полной длины) или подсеть CIDR
+func parseIPFilter(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// EncodeClickCursor кодирует позицию пагинации кликов в непрозрачную строку
+func EncodeClickCursor(cursor *click.SearchCursor) string {
+	if cursor == nil {
+		return ""
+	}
+	raw := strconv.FormatInt(cursor.Timestamp.UnixMicro(), 10) + "." + strconv.FormatInt(cursor.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeClickCursor разбирает позицию пагинации, закодированную EncodeClickCursor
+func DecodeClickCursor(value string) (*click.SearchCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	micros, id, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	timestamp, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	clickID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &click.SearchCursor{Timestamp: time.UnixMicro(timestamp).UTC(), ID: clickID}, nil
+}
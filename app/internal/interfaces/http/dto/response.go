@@ -3,6 +3,9 @@ package dto
 import (
 	"time"
 
+	"github.com/clickcounter/app/internal/domain/apikey"
+	"github.com/clickcounter/app/internal/domain/banner"
+	"github.com/clickcounter/app/internal/domain/click"
 	"github.com/clickcounter/app/internal/domain/stats"
 )
 
@@ -10,19 +13,101 @@ import (
 type ClickResponse struct {
 	Success   bool   `json:"success" example:"true"`
 	BannerID  int64  `json:"banner_id" example:"1"`
+	Counted   bool   `json:"counted" example:"true"` // false - повторный клик или клик бота
 	Message   string `json:"message" example:"Click registered successfully"`
 	Timestamp string `json:"timestamp" example:"2024-12-12T10:00:00Z"`
 }
 
 // StatsResponse представляет ответ со статистикой
 type StatsResponse struct {
-	Stats []StatItem `json:"stats"`
+	Granularity string     `json:"granularity" example:"minute"`
+	Stats       []StatItem `json:"stats"`
+	Uniques     *int64     `json:"u,omitempty" example:"3"` // уникальные посетители за период (только при uniques)
+}
+
+// MultiStatsResponse представляет статистику набора баннеров
+type MultiStatsResponse struct {
+	Granularity string                `json:"granularity" example:"hour"`
+	Banners     []BannerStatsResponse `json:"banners"`
+	Total       int64                 `json:"total" example:"42"` // сумма кликов по всем баннерам
+}
+
+// BannerStatsResponse представляет ряд статистики баннера с итогами за период
+type BannerStatsResponse struct {
+	BannerID int64      `json:"banner_id" example:"1"`
+	Stats    []StatItem `json:"stats"`
+	Total    int64      `json:"total" example:"12"`
+	Uniques  *int64     `json:"u,omitempty" example:"9"` // уникальные посетители баннера за период (только при uniques)
 }
 
 // StatItem представляет элемент статистики
 type StatItem struct {
 	Timestamp string `json:"ts" example:"2024-12-12T10:00:00Z"`
 	Value     int64  `json:"v" example:"4"`
+	Uniques   *int64 `json:"u,omitempty" example:"3"`
+}
+
+// BannerResponse представляет баннер
+type BannerResponse struct {
+	ID        int64  `json:"id" example:"1"`
+	TenantID  int64  `json:"tenant_id" example:"1"`
+	Name      string `json:"name" example:"Summer sale"`
+	IsActive  bool   `json:"is_active" example:"true"`
+	IsLive    bool   `json:"is_live" example:"true"`
+	StartsAt  string `json:"starts_at,omitempty" example:"2024-06-01T00:00:00Z"`
+	EndsAt    string `json:"ends_at,omitempty" example:"2024-09-01T00:00:00Z"`
+	CreatedAt string `json:"created_at" example:"2024-12-01T00:00:00Z"`
+	UpdatedAt string `json:"updated_at" example:"2024-12-01T00:00:00Z"`
+}
+
+// BannerListResponse представляет страницу списка баннеров
+type BannerListResponse struct {
+	Banners []BannerResponse `json:"banners"`
+	Total   int64            `json:"total" example:"2"`
+	Limit   int              `json:"limit" example:"50"`
+	Offset  int              `json:"offset" example:"0"`
+}
+
+// APIKeyResponse представляет API ключ без самого ключа
+type APIKeyResponse struct {
+	ID        int64    `json:"id" example:"1"`
+	TenantID  int64    `json:"tenant_id" example:"1"`
+	Name      string   `json:"name" example:"Partner dashboard"`
+	Prefix    string   `json:"prefix" example:"cck_Zm9vYmFy"` // начало ключа для опознания
+	Scopes    []string `json:"scopes" example:"stats:read"`
+	BannerIDs []int64  `json:"banner_ids" example:"1,2"` // пусто - все баннеры
+	IsActive  bool     `json:"is_active" example:"true"`
+	CreatedAt string   `json:"created_at" example:"2024-12-01T00:00:00Z"`
+	ExpiresAt string   `json:"expires_at,omitempty" example:"2025-12-31T00:00:00Z"`
+	RevokedAt string   `json:"revoked_at,omitempty" example:"2024-12-15T00:00:00Z"`
+}
+
+// APIKeyCreatedResponse представляет созданный API ключ. Ключ возвращается только в этом ответе
+type APIKeyCreatedResponse struct {
+	APIKeyResponse
+	Key string `json:"key" example:"cck_Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6"`
+}
+
+// APIKeyListResponse представляет список API ключей
+type APIKeyListResponse struct {
+	APIKeys []APIKeyResponse `json:"api_keys"`
+}
+
+// ClickSearchResponse представляет страницу найденных кликов
+type ClickSearchResponse struct {
+	Clicks     []ClickItem `json:"clicks"`
+	Total      int64       `json:"total" example:"1234"`                                       // клики под фильтром на всех страницах
+	NextCursor string      `json:"next_cursor,omitempty" example:"MTczNDAwMDAwMDAwMDAwMC40Mg"` // пусто - последняя страница
+}
+
+// ClickItem представляет сохраненный клик
+type ClickItem struct {
+	ID          int64  `json:"id" example:"42"`
+	Timestamp   string `json:"timestamp" example:"2024-12-12T10:00:00.123456Z"`
+	UserIP      string `json:"user_ip,omitempty" example:"203.0.113.7"`
+	UserAgent   string `json:"user_agent,omitempty" example:"Mozilla/5.0"`
+	IsDuplicate bool   `json:"is_duplicate" example:"false"`
+	IsBot       bool   `json:"is_bot" example:"false"`
 }
 
 // ErrorResponse представляет ответ с ошибкой
@@ -40,11 +125,12 @@ type HealthResponse struct {
 }
 
 // NewClickResponse создает новый ответ для клика
-func NewClickResponse(bannerID int64) *ClickResponse {
+func NewClickResponse(bannerID int64, counted bool, message string) *ClickResponse {
 	return &ClickResponse{
 		Success:   true,
 		BannerID:  bannerID,
-		Message:   "Click registered successfully",
+		Counted:   counted,
+		Message:   message,
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	}
 }
@@ -56,11 +142,138 @@ func NewStatsResponse(domainStats *stats.StatsResponse) *StatsResponse {
 		items[i] = StatItem{
 			Timestamp: stat.Timestamp.Format(time.RFC3339),
 			Value:     stat.Value,
+			Uniques:   stat.Uniques,
 		}
 	}
 
 	return &StatsResponse{
-		Stats: items,
+		Granularity: string(domainStats.Granularity),
+		Stats:       items,
+		Uniques:     domainStats.Uniques,
+	}
+}
+
+// NewMultiStatsResponse создает ответ со статистикой набора баннеров
+func NewMultiStatsResponse(domainStats *stats.MultiStatsResponse) *MultiStatsResponse {
+	banners := make([]BannerStatsResponse, len(domainStats.Banners))
+	for i, bannerStats := range domainStats.Banners {
+		banners[i] = BannerStatsResponse{
+			BannerID: bannerStats.BannerID,
+			Stats:    NewStatsResponse(bannerStats).Stats,
+			Total:    bannerStats.Total,
+			Uniques:  bannerStats.Uniques,
+		}
+	}
+
+	return &MultiStatsResponse{
+		Granularity: string(domainStats.Granularity),
+		Banners:     banners,
+		Total:       domainStats.Total,
+	}
+}
+
+// NewBannerResponse создает ответ с баннером
+func NewBannerResponse(b *banner.Banner) *BannerResponse {
+	response := &BannerResponse{
+		ID:        b.ID,
+		TenantID:  b.TenantID,
+		Name:      b.Name,
+		IsActive:  b.IsActive,
+		IsLive:    b.IsLive(time.Now()),
+		CreatedAt: b.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt: b.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+
+	if b.StartsAt != nil {
+		response.StartsAt = b.StartsAt.UTC().Format(time.RFC3339)
+	}
+	if b.EndsAt != nil {
+		response.EndsAt = b.EndsAt.UTC().Format(time.RFC3339)
+	}
+
+	return response
+}
+
+// NewAPIKeyResponse создает ответ с API ключом
+func NewAPIKeyResponse(key *apikey.Key) *APIKeyResponse {
+	response := &APIKeyResponse{
+		ID:        key.ID,
+		TenantID:  key.TenantID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    make([]string, len(key.Scopes)),
+		BannerIDs: key.BannerIDs,
+		IsActive:  key.IsActive(time.Now()),
+		CreatedAt: key.CreatedAt.UTC().Format(time.RFC3339),
+	}
+
+	for i, scope := range key.Scopes {
+		response.Scopes[i] = string(scope)
+	}
+	if response.BannerIDs == nil {
+		response.BannerIDs = []int64{}
+	}
+	if key.ExpiresAt != nil {
+		response.ExpiresAt = key.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	if key.RevokedAt != nil {
+		response.RevokedAt = key.RevokedAt.UTC().Format(time.RFC3339)
+	}
+
+	return response
+}
+
+// NewAPIKeyCreatedResponse создает ответ с созданным API ключом
+func NewAPIKeyCreatedResponse(key *apikey.Key, token string) *APIKeyCreatedResponse {
+	return &APIKeyCreatedResponse{
+		APIKeyResponse: *NewAPIKeyResponse(key),
+		Key:            token,
+	}
+}
+
+// NewAPIKeyListResponse создает ответ со списком API ключей
+func NewAPIKeyListResponse(keys []*apikey.Key) *APIKeyListResponse {
+	items := make([]APIKeyResponse, len(keys))
+	for i, key := range keys {
+		items[i] = *NewAPIKeyResponse(key)
+	}
+
+	return &APIKeyListResponse{APIKeys: items}
+}
+
+// NewBannerListResponse создает ответ со страницей баннеров
+func NewBannerListResponse(banners []*banner.Banner, total int64, limit, offset int) *BannerListResponse {
+	items := make([]BannerResponse, len(banners))
+	for i, b := range banners {
+		items[i] = *NewBannerResponse(b)
+	}
+
+	return &BannerListResponse{
+		Banners: items,
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+	}
+}
+
+// NewClickSearchResponse создает ответ со страницей найденных кликов
+func NewClickSearchResponse(page *click.SearchPage) *ClickSearchResponse {
+	items := make([]ClickItem, len(page.Clicks))
+	for i, c := range page.Clicks {
+		items[i] = ClickItem{
+			ID:          c.ID,
+			Timestamp:   c.Timestamp.UTC().Format(time.RFC3339Nano),
+			UserIP:      c.UserIP,
+			UserAgent:   c.UserAgent,
+			IsDuplicate: c.Duplicate,
+			IsBot:       c.Bot,
+		}
+	}
+
+	return &ClickSearchResponse{
+		Clicks:     items,
+		Total:      page.Total,
+		NextCursor: EncodeClickCursor(page.Next),
 	}
 }
 
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/application/usecase"
+	"github.com/clickcounter/app/internal/domain/apikey"
+	"github.com/clickcounter/app/internal/domain/banner"
+	"github.com/clickcounter/app/internal/interfaces/http/dto"
+)
+
+// APIKeyHandler обрабатывает HTTP запросы управления API ключами
+type APIKeyHandler struct {
+	apiKeyUseCase *usecase.APIKeyUseCase
+	logger        *logrus.Logger
+}
+
+// NewAPIKeyHandler создает новый обработчик API ключей
+func NewAPIKeyHandler(apiKeyUseCase *usecase.APIKeyUseCase, logger *logrus.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyUseCase: apiKeyUseCase,
+		logger:        logger,
+	}
+}
+
+// ListAPIKeys возвращает список API ключей
+// @Summary Список API ключей
+// @Description Возвращает все API ключи, хранящиеся в БД, включая отозванные. Сами ключи не возвращаются
+// @Tags api-keys
+// @Produce json
+// @Security ApiKeyAuth
+// @Param X-Tenant-ID header int false "ID арендатора; учитывается для ключей из конфигурации, ключ из БД работает только со своим арендатором"
+// @Success 200 {object} dto.APIKeyListResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.apiKeyUseCase.ListAPIKeys(c.Request.Context())
+	if err != nil {
+		h.respondError(c, err, 0)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewAPIKeyListResponse(keys))
+}
+
+// CreateAPIKey создает API ключ
+// @Summary Создание API ключа
+// @Description Создает API ключ с областями доступа (click:write, stats:read, admin) и, опционально, списком доступных баннеров. Ключ возвращается только в этом ответе
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param X-Tenant-ID header int false "ID арендатора; учитывается для ключей из конфигурации, ключ из БД работает только со своим арендатором"
+// @Param request body dto.APIKeyRequest true "Параметры ключа"
+// @Success 201 {object} dto.APIKeyCreatedResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req dto.APIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to parse request body")
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			err,
+			"Invalid request body format",
+		))
+		return
+	}
+
+	scopes, err := req.GetScopes()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			err,
+			"Scopes must be click:write, stats:read or admin",
+		))
+		return
+	}
+
+	expiresAt, err := req.GetExpiresAt()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			err,
+			"Invalid 'expires_at' time format, expected RFC3339",
+		))
+		return
+	}
+
+	key, token, err := h.apiKeyUseCase.CreateAPIKey(c.Request.Context(), &usecase.APIKeyParams{
+		Name:      req.Name,
+		Scopes:    scopes,
+		BannerIDs: req.BannerIDs,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		h.respondError(c, err, 0)
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.NewAPIKeyCreatedResponse(key, token))
+}
+
+// RevokeAPIKey отзывает API ключ
+// @Summary Отзыв API ключа
+// @Description Отзывает API ключ. На других экземплярах сервиса ключ перестает действовать по истечении auth.cache_ttl
+// @Tags api-keys
+// @Produce json
+// @Security ApiKeyAuth
+// @Param X-Tenant-ID header int false "ID арендатора; учитывается для ключей из конфигурации, ключ из БД работает только со своим арендатором"
+// @Param keyID path int true "ID ключа"
+// @Success 200 {object} dto.APIKeyResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/api-keys/{keyID} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	keyIDStr := c.Param("keyID")
+	keyID, err := strconv.ParseInt(keyIDStr, 10, 64)
+	if err != nil || keyID <= 0 {
+		h.logger.WithField("keyID", keyIDStr).Error("Invalid api key ID")
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			apikey.ErrInvalidKeyID,
+			"API key ID must be a positive integer",
+		))
+		return
+	}
+
+	key, err := h.apiKeyUseCase.RevokeAPIKey(c.Request.Context(), keyID)
+	if err != nil {
+		h.respondError(c, err, keyID)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewAPIKeyResponse(key))
+}
+
+// apiKeyValidationErrors ошибки валидации ключа, возвращаемые клиенту как 400
+var apiKeyValidationErrors = []error{
+	apikey.ErrInvalidKeyID,
+	apikey.ErrEmptyName,
+	apikey.ErrNameTooLong,
+	apikey.ErrNoScopes,
+	apikey.ErrInvalidScope,
+	apikey.ErrInvalidBannerID,
+	apikey.ErrAdminRestricted,
+	apikey.ErrInvalidExpiry,
+}
+
+// respondError преобразует ошибку use case в HTTP ответ
+func (h *APIKeyHandler) respondError(c *gin.Context, err error, keyID int64) {
+	switch {
+	case errors.Is(err, apikey.ErrKeyNotFound):
+		c.JSON(http.StatusNotFound, dto.NewErrorResponse(
+			http.StatusNotFound,
+			apikey.ErrKeyNotFound,
+			"API key with specified ID not found",
+		))
+		return
+	case errors.Is(err, banner.ErrBannerNotFound):
+		c.JSON(http.StatusNotFound, dto.NewErrorResponse(
+			http.StatusNotFound,
+			err,
+			"Some of the banners in 'banner_ids' were not found",
+		))
+		return
+	}
+
+	for _, validationErr := range apiKeyValidationErrors {
+		if errors.Is(err, validationErr) {
+			c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+				http.StatusBadRequest,
+				validationErr,
+				"Invalid api key parameters",
+			))
+			return
+		}
+	}
+
+	h.logger.WithError(err).WithField("keyID", keyID).Error("Failed to process api key request")
+	c.JSON(http.StatusInternalServerError, dto.NewErrorResponse(
+		http.StatusInternalServerError,
+		err,
+		"Internal server error while processing api key request",
+	))
+}
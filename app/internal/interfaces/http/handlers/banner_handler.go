@@ -0,0 +1,364 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/application/usecase"
+	"github.com/clickcounter/app/internal/domain/banner"
+	"github.com/clickcounter/app/internal/interfaces/http/dto"
+)
+
+// Параметры пагинации списка баннеров по умолчанию
+const defaultBannersPageSize = 50
+
+// BannerHandler обрабатывает HTTP запросы для управления баннерами
+type BannerHandler struct {
+	bannerUseCase *usecase.BannerUseCase
+	logger        *logrus.Logger
+}
+
+// NewBannerHandler создает новый обработчик баннеров
+func NewBannerHandler(bannerUseCase *usecase.BannerUseCase, logger *logrus.Logger) *BannerHandler {
+	return &BannerHandler{
+		bannerUseCase: bannerUseCase,
+		logger:        logger,
+	}
+}
+
+// ListBanners возвращает список баннеров
+// @Summary Список баннеров
+// @Description Возвращает страницу списка баннеров, опционально фильтруя по активности
+// @Tags banners
+// @Produce json
+// @Security ApiKeyAuth
+// @Param X-Tenant-ID header int false "ID арендатора; учитывается для ключей из конфигурации, ключ из БД работает только со своим арендатором"
+// @Param active query bool false "Фильтр по активности"
+// @Param limit query int false "Размер страницы (1-500, по умолчанию 50)"
+// @Param offset query int false "Смещение"
+// @Success 200 {object} dto.BannerListResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/banners [get]
+func (h *BannerHandler) ListBanners(c *gin.Context) {
+	req := &usecase.ListBannersRequest{Limit: defaultBannersPageSize}
+
+	if value := c.Query("active"); value != "" {
+		active, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+				http.StatusBadRequest,
+				err,
+				"Parameter 'active' must be a boolean",
+			))
+			return
+		}
+		req.Active = &active
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+				http.StatusBadRequest,
+				err,
+				"Parameter 'limit' must be a valid integer",
+			))
+			return
+		}
+		req.Limit = limit
+	}
+
+	if value := c.Query("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+				http.StatusBadRequest,
+				err,
+				"Parameter 'offset' must be a valid integer",
+			))
+			return
+		}
+		req.Offset = offset
+	}
+
+	if req.Limit <= 0 || req.Limit > usecase.MaxBannersPageSize || req.Offset < 0 {
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			errors.New("invalid pagination parameters"),
+			"Parameter 'limit' must be between 1 and 500, 'offset' must be non-negative",
+		))
+		return
+	}
+
+	response, err := h.bannerUseCase.ListBanners(c.Request.Context(), req)
+	if err != nil {
+		h.respondError(c, err, 0)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewBannerListResponse(response.Banners, response.Total, response.Limit, response.Offset))
+}
+
+// GetBanner возвращает баннер по ID
+// @Summary Получение баннера
+// @Description Возвращает баннер по ID, в том числе неактивный
+// @Tags banners
+// @Produce json
+// @Security ApiKeyAuth
+// @Param X-Tenant-ID header int false "ID арендатора; учитывается для ключей из конфигурации, ключ из БД работает только со своим арендатором"
+// @Param bannerID path int true "ID баннера"
+// @Success 200 {object} dto.BannerResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/banners/{bannerID} [get]
+func (h *BannerHandler) GetBanner(c *gin.Context) {
+	bannerID, ok := h.parseBannerID(c)
+	if !ok {
+		return
+	}
+
+	b, err := h.bannerUseCase.GetBanner(c.Request.Context(), bannerID)
+	if err != nil {
+		h.respondError(c, err, bannerID)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewBannerResponse(b))
+}
+
+// CreateBanner создает новый баннер
+// @Summary Создание баннера
+// @Description Создает новый активный баннер с опциональным окном показа [starts_at, ends_at)
+// @Tags banners
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param X-Tenant-ID header int false "ID арендатора; учитывается для ключей из конфигурации, ключ из БД работает только со своим арендатором"
+// @Param request body dto.BannerRequest true "Параметры баннера"
+// @Success 201 {object} dto.BannerResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/banners [post]
+func (h *BannerHandler) CreateBanner(c *gin.Context) {
+	var req dto.BannerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to parse request body")
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			err,
+			"Invalid request body format",
+		))
+		return
+	}
+
+	params, ok := h.bannerParams(c, &req)
+	if !ok {
+		return
+	}
+
+	b, err := h.bannerUseCase.CreateBanner(c.Request.Context(), params)
+	if err != nil {
+		h.respondError(c, err, 0)
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.NewBannerResponse(b))
+}
+
+// UpdateBanner изменяет название и окно показа баннера
+// @Summary Изменение баннера
+// @Description Изменяет название и окно показа баннера; не переданные starts_at/ends_at снимают ограничение
+// @Tags banners
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param X-Tenant-ID header int false "ID арендатора; учитывается для ключей из конфигурации, ключ из БД работает только со своим арендатором"
+// @Param bannerID path int true "ID баннера"
+// @Param request body dto.BannerRequest true "Параметры баннера"
+// @Success 200 {object} dto.BannerResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/banners/{bannerID} [put]
+func (h *BannerHandler) UpdateBanner(c *gin.Context) {
+	bannerID, ok := h.parseBannerID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.BannerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to parse request body")
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			err,
+			"Invalid request body format",
+		))
+		return
+	}
+
+	params, ok := h.bannerParams(c, &req)
+	if !ok {
+		return
+	}
+
+	b, err := h.bannerUseCase.UpdateBanner(c.Request.Context(), bannerID, params)
+	if err != nil {
+		h.respondError(c, err, bannerID)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewBannerResponse(b))
+}
+
+// ActivateBanner активирует баннер
+// @Summary Активация баннера
+// @Description Включает прием кликов по баннеру
+// @Tags banners
+// @Produce json
+// @Security ApiKeyAuth
+// @Param X-Tenant-ID header int false "ID арендатора; учитывается для ключей из конфигурации, ключ из БД работает только со своим арендатором"
+// @Param bannerID path int true "ID баннера"
+// @Success 200 {object} dto.BannerResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/banners/{bannerID}/activate [post]
+func (h *BannerHandler) ActivateBanner(c *gin.Context) {
+	h.setActive(c, true)
+}
+
+// DeactivateBanner деактивирует баннер
+// @Summary Деактивация баннера
+// @Description Прекращает прием кликов по баннеру; статистика сохраняется
+// @Tags banners
+// @Produce json
+// @Security ApiKeyAuth
+// @Param X-Tenant-ID header int false "ID арендатора; учитывается для ключей из конфигурации, ключ из БД работает только со своим арендатором"
+// @Param bannerID path int true "ID баннера"
+// @Success 200 {object} dto.BannerResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/banners/{bannerID}/deactivate [post]
+func (h *BannerHandler) DeactivateBanner(c *gin.Context) {
+	h.setActive(c, false)
+}
+
+// setActive изменяет флаг активности баннера
+func (h *BannerHandler) setActive(c *gin.Context, active bool) {
+	bannerID, ok := h.parseBannerID(c)
+	if !ok {
+		return
+	}
+
+	b, err := h.bannerUseCase.SetBannerActive(c.Request.Context(), bannerID, active)
+	if err != nil {
+		h.respondError(c, err, bannerID)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewBannerResponse(b))
+}
+
+// bannerParams преобразует тело запроса в параметры use case
+func (h *BannerHandler) bannerParams(c *gin.Context, req *dto.BannerRequest) (*usecase.BannerParams, bool) {
+	startsAt, err := req.GetStartsAt()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			err,
+			"Invalid 'starts_at' time format, expected RFC3339",
+		))
+		return nil, false
+	}
+
+	endsAt, err := req.GetEndsAt()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			err,
+			"Invalid 'ends_at' time format, expected RFC3339",
+		))
+		return nil, false
+	}
+
+	return &usecase.BannerParams{
+		Name:     req.Name,
+		StartsAt: startsAt,
+		EndsAt:   endsAt,
+	}, true
+}
+
+// parseBannerID извлекает и валидирует ID баннера из URL
+func (h *BannerHandler) parseBannerID(c *gin.Context) (int64, bool) {
+	bannerIDStr := c.Param("bannerID")
+	bannerID, err := strconv.ParseInt(bannerIDStr, 10, 64)
+	if err != nil || bannerID <= 0 {
+		h.logger.WithField("bannerID", bannerIDStr).Error("Invalid banner ID")
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			dto.ErrInvalidBannerID,
+			"Banner ID must be a positive integer",
+		))
+		return 0, false
+	}
+
+	return bannerID, true
+}
+
+// bannerValidationErrors ошибки валидации баннера, возвращаемые клиенту как 400
+var bannerValidationErrors = []error{
+	banner.ErrInvalidBannerID,
+	banner.ErrEmptyName,
+	banner.ErrNameTooLong,
+	banner.ErrInvalidSchedule,
+}
+
+// respondError преобразует ошибку use case в HTTP ответ
+func (h *BannerHandler) respondError(c *gin.Context, err error, bannerID int64) {
+	if errors.Is(err, banner.ErrBannerNotFound) {
+		c.JSON(http.StatusNotFound, dto.NewErrorResponse(
+			http.StatusNotFound,
+			banner.ErrBannerNotFound,
+			"Banner with specified ID not found",
+		))
+		return
+	}
+
+	for _, validationErr := range bannerValidationErrors {
+		if errors.Is(err, validationErr) {
+			c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+				http.StatusBadRequest,
+				validationErr,
+				"Invalid banner parameters",
+			))
+			return
+		}
+	}
+
+	h.logger.WithError(err).WithField("bannerID", bannerID).Error("Failed to process banner request")
+	c.JSON(http.StatusInternalServerError, dto.NewErrorResponse(
+		http.StatusInternalServerError,
+		err,
+		"Internal server error while processing banner request",
+	))
+}
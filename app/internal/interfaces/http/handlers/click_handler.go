@@ -36,6 +36,7 @@ func NewClickHandler(clickUseCase *usecase.ClickUseCase, logger *logrus.Logger)
 // @Failure 400 {object} dto.ErrorResponse
 // @Failure 404 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
+// @Failure 503 {object} dto.ErrorResponse
 // @Router /counter/{bannerID} [get]
 func (h *ClickHandler) RegisterClick(c *gin.Context) {
 	// Извлекаем ID баннера из URL
@@ -82,6 +83,13 @@ func (h *ClickHandler) RegisterClick(c *gin.Context) {
 					err,
 					response.Message,
 				))
+			case "Click queue is full":
+				c.Header("Retry-After", "1")
+				c.JSON(http.StatusServiceUnavailable, dto.NewErrorResponse(
+					http.StatusServiceUnavailable,
+					err,
+					response.Message,
+				))
 			default:
 				c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
 					http.StatusBadRequest,
@@ -100,8 +108,11 @@ func (h *ClickHandler) RegisterClick(c *gin.Context) {
 	}
 
 	// Логируем успешную регистрацию
-	h.logger.WithField("bannerID", bannerID).Info("Click registered successfully")
+	h.logger.WithFields(logrus.Fields{
+		"bannerID": bannerID,
+		"counted":  response.Counted,
+	}).Info("Click registered successfully")
 
 	// Возвращаем успешный ответ
-	c.JSON(http.StatusOK, dto.NewClickResponse(bannerID))
+	c.JSON(http.StatusOK, dto.NewClickResponse(bannerID, response.Counted, response.Message))
 }
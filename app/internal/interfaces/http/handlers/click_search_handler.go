@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/application/usecase"
+	"github.com/clickcounter/app/internal/domain/banner"
+	"github.com/clickcounter/app/internal/domain/click"
+	"github.com/clickcounter/app/internal/interfaces/http/dto"
+)
+
+// ClickSearchHandler обрабатывает HTTP запросы поиска сохраненных кликов
+type ClickSearchHandler struct {
+	searchUseCase *usecase.ClickSearchUseCase
+	logger        *logrus.Logger
+}
+
+// NewClickSearchHandler создает новый обработчик поиска кликов
+func NewClickSearchHandler(searchUseCase *usecase.ClickSearchUseCase, logger *logrus.Logger) *ClickSearchHandler {
+	return &ClickSearchHandler{
+		searchUseCase: searchUseCase,
+		logger:        logger,
+	}
+}
+
+// clickSearchValidationErrors ошибки параметров поиска, возвращаемые клиенту как 400
+var clickSearchValidationErrors = []error{
+	click.ErrInvalidSearchPeriod,
+	click.ErrSearchPeriodTooLong,
+	click.ErrInvalidSearchLimit,
+}
+
+// SearchClicks возвращает страницу кликов баннера
+// @Summary Поиск кликов баннера
+// @Description Возвращает сохраненные клики баннера от новых к старым с фильтрами по периоду, IP/подсети и подстроке User-Agent. Пагинация по курсору next_cursor; total - количество кликов под фильтром
+// @Tags clicks
+// @Produce json
+// @Security ApiKeyAuth
+// @Param X-Tenant-ID header int false "ID арендатора; учитывается для ключей из конфигурации, ключ из БД работает только со своим арендатором"
+// @Param bannerID path int true "ID баннера"
+// @Param from query string true "Начало периода (RFC3339, включительно)"
+// @Param to query string true "Конец периода (RFC3339, не включительно), не более 31 дня от начала"
+// @Param ip query string false "IP адрес или подсеть CIDR"
+// @Param user_agent query string false "Подстрока User-Agent без учета регистра"
+// @Param limit query int false "Размер страницы (1-1000, по умолчанию 100)"
+// @Param cursor query string false "next_cursor предыдущей страницы"
+// @Success 200 {object} dto.ClickSearchResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/banners/{bannerID}/clicks [get]
+func (h *ClickSearchHandler) SearchClicks(c *gin.Context) {
+	bannerIDStr := c.Param("bannerID")
+	bannerID, err := strconv.ParseInt(bannerIDStr, 10, 64)
+	if err != nil || bannerID <= 0 {
+		h.logger.WithField("bannerID", bannerIDStr).Error("Invalid banner ID")
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			dto.ErrInvalidBannerID,
+			"Banner ID must be a positive integer",
+		))
+		return
+	}
+
+	var req dto.ClickSearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			err,
+			"Parameters 'from' and 'to' are required, 'limit' must be a valid integer",
+		))
+		return
+	}
+
+	filter, err := req.Filter(bannerID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			err,
+			"Invalid click search parameters",
+		))
+		return
+	}
+
+	page, err := h.searchUseCase.SearchClicks(c.Request.Context(), filter)
+	if err != nil {
+		h.respondError(c, err, bannerID)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewClickSearchResponse(page))
+}
+
+// respondError преобразует ошибку use case в HTTP ответ
+func (h *ClickSearchHandler) respondError(c *gin.Context, err error, bannerID int64) {
+	if errors.Is(err, banner.ErrBannerNotFound) {
+		c.JSON(http.StatusNotFound, dto.NewErrorResponse(
+			http.StatusNotFound,
+			banner.ErrBannerNotFound,
+			"Banner with specified ID not found",
+		))
+		return
+	}
+
+	for _, validationErr := range clickSearchValidationErrors {
+		if errors.Is(err, validationErr) {
+			c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+				http.StatusBadRequest,
+				validationErr,
+				"Period must be non-empty and at most 31 days, 'limit' must be between 1 and 1000",
+			))
+			return
+		}
+	}
+
+	h.logger.WithError(err).WithField("bannerID", bannerID).Error("Failed to search clicks")
+	c.JSON(http.StatusInternalServerError, dto.NewErrorResponse(
+		http.StatusInternalServerError,
+		err,
+		"Internal server error while searching clicks",
+	))
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/application/usecase"
+	"github.com/clickcounter/app/internal/domain/banner"
+	"github.com/clickcounter/app/internal/domain/stats"
+	"github.com/clickcounter/app/internal/interfaces/http/dto"
+)
+
+// exportBufferSize размер буфера записи выгрузки: строки отправляются клиенту порциями
+const exportBufferSize = 32 * 1024
+
+// exportFileTimeLayout формат времени в имени файла выгрузки
+const exportFileTimeLayout = "20060102T150405Z"
+
+// exportStats отдает статистику баннера потоком в формате CSV или NDJSON.
+// Ошибки до первой строки возвращаются обычным ответом JSON; ошибка посреди
+// выгрузки обрывает соединение, чтобы клиент не принял неполный файл за целый
+func (h *StatsHandler) exportStats(c *gin.Context, req *usecase.GetStatsRequest, format dto.StatsFormat) {
+	w := newStatsExportWriter(c, format, req.Uniques, fmt.Sprintf(
+		"banner-%d-stats-%s-%s.%s",
+		req.BannerID,
+		req.From.UTC().Format(exportFileTimeLayout),
+		req.To.UTC().Format(exportFileTimeLayout),
+		format,
+	))
+
+	exported, err := h.statsUseCase.ExportStats(c.Request.Context(), req, w.Write)
+	if err == nil {
+		err = w.Close()
+	}
+	if err == nil {
+		return
+	}
+
+	fields := logrus.Fields{
+		"bannerID": req.BannerID,
+		"from":     req.From,
+		"to":       req.To,
+		"format":   format,
+		"exported": exported,
+	}
+
+	if c.Writer.Written() {
+		if c.Request.Context().Err() != nil {
+			h.logger.WithFields(fields).Info("Stats export interrupted by client")
+			return
+		}
+		h.logger.WithError(err).WithFields(fields).Error("Stats export failed after streaming started")
+		abortConnection(c)
+		return
+	}
+
+	// Клиенту еще ничего не отправлено: заменяем выгрузку ответом с ошибкой
+	w.reset()
+	h.logger.WithError(err).WithFields(fields).Error("Failed to export stats")
+	switch {
+	case errors.Is(err, banner.ErrBannerNotFound):
+		c.JSON(http.StatusNotFound, dto.NewErrorResponse(
+			http.StatusNotFound,
+			banner.ErrBannerNotFound,
+			"Banner with specified ID not found",
+		))
+	case errors.Is(err, stats.ErrPeriodTooLarge), errors.Is(err, stats.ErrInvalidPeriod):
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			err,
+			"Requested time period is invalid or too large for export",
+		))
+	default:
+		c.JSON(http.StatusInternalServerError, dto.NewErrorResponse(
+			http.StatusInternalServerError,
+			err,
+			"Internal server error while exporting statistics",
+		))
+	}
+}
+
+// abortConnection закрывает соединение без завершения ответа: клиент получает ошибку
+// чтения вместо корректного конца неполного файла. HTTP/2 не поддерживает перехват
+// соединения - там ответ просто завершается
+func abortConnection(c *gin.Context) {
+	conn, _, err := http.NewResponseController(c.Writer).Hijack()
+	if err != nil {
+		return
+	}
+	_ = conn.Close()
+}
+
+// statsExportWriter пишет бакеты статистики в ответ через буфер. Пока буфер не
+// отправлен клиенту (gin.ResponseWriter.Written), ошибку еще можно вернуть обычным ответом
+type statsExportWriter struct {
+	c        *gin.Context
+	format   dto.StatsFormat
+	uniques  bool
+	filename string
+	started  bool
+
+	buf  *bufio.Writer
+	csv  *csv.Writer
+	json *json.Encoder
+}
+
+// newStatsExportWriter создает запись выгрузки в формате format
+func newStatsExportWriter(c *gin.Context, format dto.StatsFormat, uniques bool, filename string) *statsExportWriter {
+	return &statsExportWriter{
+		c:        c,
+		format:   format,
+		uniques:  uniques,
+		filename: filename,
+	}
+}
+
+// Write записывает бакет в выгрузку
+func (w *statsExportWriter) Write(stat *usecase.BucketStatDTO) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+
+	if w.format == dto.StatsFormatNDJSON {
+		return w.json.Encode(stat)
+	}
+
+	record := []string{stat.Timestamp.UTC().Format(time.RFC3339), strconv.FormatInt(stat.Count, 10)}
+	if w.uniques {
+		uniques := ""
+		if stat.Uniques != nil {
+			uniques = strconv.FormatInt(*stat.Uniques, 10)
+		}
+		record = append(record, uniques)
+	}
+	return w.csv.Write(record)
+}
+
+// Close дописывает буферизованные строки
+func (w *statsExportWriter) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return w.buf.Flush()
+}
+
+// reset отменяет заголовки выгрузки и отбрасывает буферизованные строки
+func (w *statsExportWriter) reset() {
+	header := w.c.Writer.Header()
+	header.Del("Content-Type")
+	header.Del("Content-Disposition")
+	header.Del("X-Accel-Buffering")
+	if w.buf != nil {
+		w.buf.Reset(w.c.Writer)
+	}
+}
+
+// start задает заголовки ответа и пишет заголовок CSV при первом вызове
+func (w *statsExportWriter) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+
+	// Большая выгрузка пишется дольше WriteTimeout сервера: снимаем дедлайн записи
+	_ = http.NewResponseController(w.c.Writer).SetWriteDeadline(time.Time{})
+
+	header := w.c.Writer.Header()
+	header.Set("Content-Type", w.format.ContentType())
+	header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", w.filename))
+	header.Set("X-Accel-Buffering", "no") // отключает буферизацию ответа в nginx
+	w.c.Status(http.StatusOK)
+
+	w.buf = bufio.NewWriterSize(w.c.Writer, exportBufferSize)
+	if w.format == dto.StatsFormatNDJSON {
+		w.json = json.NewEncoder(w.buf)
+		return nil
+	}
+
+	w.csv = csv.NewWriter(w.buf)
+	columns := []string{"ts", "v"}
+	if w.uniques {
+		columns = append(columns, "u")
+	}
+	return w.csv.Write(columns)
+}
@@ -1,13 +1,18 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 
 	"github.com/clickcounter/app/internal/application/usecase"
+	"github.com/clickcounter/app/internal/domain/apikey"
+	"github.com/clickcounter/app/internal/domain/banner"
 	"github.com/clickcounter/app/internal/domain/stats"
 	"github.com/clickcounter/app/internal/interfaces/http/dto"
 )
@@ -28,14 +33,20 @@ func NewStatsHandler(statsUseCase *usecase.StatsUseCase, logger *logrus.Logger)
 
 // GetStats возвращает статистику кликов по баннеру за период
 // @Summary Получение статистики
-// @Description Возвращает поминутную статистику кликов по баннеру за указанный период
+// @Description Возвращает статистику кликов по баннеру за указанный период с группировкой по минутам, часам, дням или неделям.
+// @Description Формат выбирается параметром format или заголовком Accept: CSV и NDJSON отдаются потоком без накопления в памяти
 // @Tags stats
 // @Accept json
-// @Produce json
+// @Produce json,text/csv,application/x-ndjson
+// @Security ApiKeyAuth
+// @Param X-Tenant-ID header int false "ID арендатора; учитывается для ключей из конфигурации, ключ из БД работает только со своим арендатором"
 // @Param bannerID path int true "ID баннера"
+// @Param format query string false "Формат ответа: json (по умолчанию), csv, ndjson"
 // @Param request body dto.StatsRequest true "Параметры запроса статистики"
 // @Success 200 {object} dto.StatsResponse
 // @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
 // @Failure 404 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
 // @Router /stats/{bannerID} [post]
@@ -64,6 +75,18 @@ func (h *StatsHandler) GetStats(c *gin.Context) {
 		return
 	}
 
+	// Формат ответа: JSON или потоковая выгрузка CSV/NDJSON
+	format, err := dto.StatsFormatFromRequest(c.Request)
+	if err != nil {
+		h.logger.WithError(err).WithField("format", c.Query("format")).Error("Invalid stats format")
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			err,
+			"Invalid format, expected one of: json, csv, ndjson",
+		))
+		return
+	}
+
 	// Парсим тело запроса
 	var req dto.StatsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -90,34 +113,24 @@ func (h *StatsHandler) GetStats(c *gin.Context) {
 		return
 	}
 
-	// Получаем временные метки
-	fromTime, err := req.GetFromTime()
-	if err != nil {
-		h.logger.WithError(err).WithField("from", req.From).Error("Invalid from time format")
-		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
-			http.StatusBadRequest,
-			dto.ErrInvalidTimeFormat,
-			"Invalid 'from' time format, expected RFC3339",
-		))
-		return
-	}
-
-	toTime, err := req.GetToTime()
-	if err != nil {
-		h.logger.WithError(err).WithField("to", req.To).Error("Invalid to time format")
-		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
-			http.StatusBadRequest,
-			dto.ErrInvalidTimeFormat,
-			"Invalid 'to' time format, expected RFC3339",
-		))
+	fromTime, toTime, granularity, ok := h.parseStatsQuery(c, &req)
+	if !ok {
 		return
 	}
 
 	// Получаем статистику
 	statsReq := &usecase.GetStatsRequest{
-		BannerID: bannerID,
-		From:     fromTime,
-		To:       toTime,
+		BannerID:    bannerID,
+		From:        fromTime,
+		To:          toTime,
+		IncludeBots: req.IncludeBots,
+		Uniques:     req.Uniques,
+		Granularity: granularity,
+	}
+
+	if format.Streamed() {
+		h.exportStats(c, statsReq, format)
+		return
 	}
 
 	statsResponse, err := h.statsUseCase.GetStats(c.Request.Context(), statsReq)
@@ -129,18 +142,18 @@ func (h *StatsHandler) GetStats(c *gin.Context) {
 		}).Error("Failed to get stats")
 
 		// Определяем тип ошибки для правильного HTTP статуса
-		switch err.Error() {
-		case "banner not found":
+		switch {
+		case errors.Is(err, banner.ErrBannerNotFound):
 			c.JSON(http.StatusNotFound, dto.NewErrorResponse(
 				http.StatusNotFound,
 				err,
 				"Banner with specified ID not found",
 			))
-		case "time period is too large":
+		case errors.Is(err, stats.ErrPeriodTooLarge), errors.Is(err, stats.ErrInvalidPeriod):
 			c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
 				http.StatusBadRequest,
 				err,
-				"Requested time period is too large",
+				"Requested time period is invalid or too large",
 			))
 		default:
 			c.JSON(http.StatusInternalServerError, dto.NewErrorResponse(
@@ -162,16 +175,190 @@ func (h *StatsHandler) GetStats(c *gin.Context) {
 
 	// Конвертируем в доменную модель для DTO
 	domainStats := &stats.StatsResponse{
-		Stats: make([]*stats.MinuteStat, len(statsResponse.Stats)),
+		Granularity: statsResponse.Granularity,
+		Stats:       make([]*stats.BucketStat, len(statsResponse.Stats)),
+		Uniques:     statsResponse.Uniques,
 	}
 
 	for i, stat := range statsResponse.Stats {
-		domainStats.Stats[i] = &stats.MinuteStat{
+		domainStats.Stats[i] = &stats.BucketStat{
 			Timestamp: stat.Timestamp,
 			Value:     stat.Count,
+			Uniques:   stat.Uniques,
 		}
 	}
 
 	// Возвращаем статистику
 	c.JSON(http.StatusOK, dto.NewStatsResponse(domainStats))
 }
+
+// GetMultiStats возвращает статистику набора баннеров за период
+// @Summary Статистика набора баннеров
+// @Description Возвращает ряды статистики и итоги за период для списка баннеров или всех активных баннеров одним запросом
+// @Tags stats
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param X-Tenant-ID header int false "ID арендатора; учитывается для ключей из конфигурации, ключ из БД работает только со своим арендатором"
+// @Param request body dto.MultiStatsRequest true "Баннеры и параметры запроса статистики"
+// @Success 200 {object} dto.MultiStatsResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/stats [post]
+func (h *StatsHandler) GetMultiStats(c *gin.Context) {
+	var req dto.MultiStatsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to parse request body")
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			err,
+			"Invalid request body format",
+		))
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"from":       req.From,
+			"to":         req.To,
+			"banners":    len(req.BannerIDs),
+			"all_active": req.AllActive,
+		}).Error("Invalid banner set stats request")
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			err,
+			"Invalid request parameters",
+		))
+		return
+	}
+
+	fromTime, toTime, granularity, ok := h.parseStatsQuery(c, &req.StatsRequest)
+	if !ok {
+		return
+	}
+
+	statsResponse, err := h.statsUseCase.GetMultiStats(c.Request.Context(), &usecase.GetMultiStatsRequest{
+		BannerIDs:   req.BannerIDs,
+		AllActive:   req.AllActive,
+		From:        fromTime,
+		To:          toTime,
+		IncludeBots: req.IncludeBots,
+		Uniques:     req.Uniques,
+		Granularity: granularity,
+	})
+	if err != nil {
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"banners":    len(req.BannerIDs),
+			"all_active": req.AllActive,
+			"from":       fromTime,
+			"to":         toTime,
+		}).Error("Failed to get banner set stats")
+
+		switch {
+		case errors.Is(err, banner.ErrBannerNotFound):
+			c.JSON(http.StatusNotFound, dto.NewErrorResponse(
+				http.StatusNotFound,
+				err,
+				"Some of the requested banners were not found",
+			))
+		case errors.Is(err, apikey.ErrBannerForbidden):
+			c.JSON(http.StatusForbidden, dto.NewErrorResponse(
+				http.StatusForbidden,
+				err,
+				"API key is not allowed to access some of the requested banners",
+			))
+		case errors.Is(err, stats.ErrInvalidBannerID), errors.Is(err, stats.ErrNoBanners):
+			c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+				http.StatusBadRequest,
+				err,
+				"Banner IDs must be positive integers",
+			))
+		case errors.Is(err, stats.ErrTooManyBanners):
+			c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+				http.StatusBadRequest,
+				err,
+				fmt.Sprintf("At most %d banners can be requested at once", stats.MaxBannersPerQuery),
+			))
+		case errors.Is(err, stats.ErrPeriodTooLarge):
+			c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+				http.StatusBadRequest,
+				err,
+				"Requested time period is too large",
+			))
+		default:
+			c.JSON(http.StatusInternalServerError, dto.NewErrorResponse(
+				http.StatusInternalServerError,
+				err,
+				"Internal server error while retrieving statistics",
+			))
+		}
+		return
+	}
+
+	// Конвертируем в доменную модель для DTO
+	domainStats := &stats.MultiStatsResponse{
+		Granularity: statsResponse.Granularity,
+		Banners:     make([]*stats.StatsResponse, len(statsResponse.Banners)),
+		Total:       statsResponse.Total,
+	}
+	for i, bannerStats := range statsResponse.Banners {
+		items := make([]*stats.BucketStat, len(bannerStats.Stats))
+		for j, stat := range bannerStats.Stats {
+			items[j] = &stats.BucketStat{
+				Timestamp: stat.Timestamp,
+				Value:     stat.Count,
+				Uniques:   stat.Uniques,
+			}
+		}
+		domainStats.Banners[i] = &stats.StatsResponse{
+			BannerID: bannerStats.BannerID,
+			Stats:    items,
+			Total:    bannerStats.Total,
+			Uniques:  bannerStats.Uniques,
+		}
+	}
+
+	c.JSON(http.StatusOK, dto.NewMultiStatsResponse(domainStats))
+}
+
+// parseStatsQuery разбирает период и размер бакета запроса статистики.
+// При ошибке отправляет ответ 400 и возвращает ok = false
+func (h *StatsHandler) parseStatsQuery(c *gin.Context, req *dto.StatsRequest) (from, to time.Time, granularity stats.Granularity, ok bool) {
+	from, err := req.GetFromTime()
+	if err != nil {
+		h.logger.WithError(err).WithField("from", req.From).Error("Invalid from time format")
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			dto.ErrInvalidTimeFormat,
+			"Invalid 'from' time format, expected RFC3339",
+		))
+		return from, to, granularity, false
+	}
+
+	to, err = req.GetToTime()
+	if err != nil {
+		h.logger.WithError(err).WithField("to", req.To).Error("Invalid to time format")
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			dto.ErrInvalidTimeFormat,
+			"Invalid 'to' time format, expected RFC3339",
+		))
+		return from, to, granularity, false
+	}
+
+	granularity, err = stats.ParseGranularity(req.Granularity)
+	if err != nil {
+		h.logger.WithError(err).WithField("granularity", req.Granularity).Error("Invalid granularity")
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			err,
+			"Invalid granularity, expected one of: minute, 5m, hour, day, week",
+		))
+		return from, to, granularity, false
+	}
+
+	return from, to, granularity, true
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/application/usecase"
+	"github.com/clickcounter/app/internal/domain/banner"
+	"github.com/clickcounter/app/internal/infrastructure/livestats"
+	"github.com/clickcounter/app/internal/interfaces/http/dto"
+)
+
+// StatsStreamHandler отдает live-статистику баннера через Server-Sent Events
+type StatsStreamHandler struct {
+	hub           *livestats.Hub
+	bannerUseCase *usecase.BannerUseCase
+	heartbeat     time.Duration
+	logger        *logrus.Logger
+}
+
+// NewStatsStreamHandler создает обработчик потока live-статистики.
+// heartbeat - период комментариев, удерживающих соединение через прокси
+func NewStatsStreamHandler(
+	hub *livestats.Hub,
+	bannerUseCase *usecase.BannerUseCase,
+	heartbeat time.Duration,
+	logger *logrus.Logger,
+) *StatsStreamHandler {
+	if heartbeat <= 0 {
+		heartbeat = 15 * time.Second
+	}
+
+	return &StatsStreamHandler{
+		hub:           hub,
+		bannerUseCase: bannerUseCase,
+		heartbeat:     heartbeat,
+		logger:        logger,
+	}
+}
+
+// Stream отдает поток обновлений текущей минуты баннера
+// @Summary Live-статистика баннера
+// @Description Server-Sent Events: событие running с текущим значением минуты и событие final с итогом закрытой минуты. Первым отправляется running с текущим значением
+// @Tags stats
+// @Produce text/event-stream
+// @Security ApiKeyAuth
+// @Param X-Tenant-ID header int false "ID арендатора; учитывается для ключей из конфигурации, ключ из БД работает только со своим арендатором"
+// @Param bannerID path int true "ID баннера"
+// @Success 200 {string} string "Поток событий"
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 503 {object} dto.ErrorResponse
+// @Router /api/v1/stats/{bannerID}/stream [get]
+func (h *StatsStreamHandler) Stream(c *gin.Context) {
+	bannerIDStr := c.Param("bannerID")
+	bannerID, err := strconv.ParseInt(bannerIDStr, 10, 64)
+	if err != nil || bannerID <= 0 {
+		h.logger.WithField("bannerID", bannerIDStr).Error("Invalid banner ID")
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(
+			http.StatusBadRequest,
+			dto.ErrInvalidBannerID,
+			"Banner ID must be a positive integer",
+		))
+		return
+	}
+
+	if _, err := h.bannerUseCase.GetBanner(c.Request.Context(), bannerID); err != nil {
+		if errors.Is(err, banner.ErrBannerNotFound) {
+			c.JSON(http.StatusNotFound, dto.NewErrorResponse(
+				http.StatusNotFound,
+				banner.ErrBannerNotFound,
+				"Banner with specified ID not found",
+			))
+			return
+		}
+
+		h.logger.WithError(err).WithField("bannerID", bannerID).Error("Failed to get banner")
+		c.JSON(http.StatusInternalServerError, dto.NewErrorResponse(
+			http.StatusInternalServerError,
+			err,
+			"Internal server error while opening stats stream",
+		))
+		return
+	}
+
+	sub, snapshot, err := h.hub.Subscribe(bannerID)
+	if err != nil {
+		h.logger.WithError(err).WithField("bannerID", bannerID).Warn("Failed to subscribe to live stats")
+		c.JSON(http.StatusServiceUnavailable, dto.NewErrorResponse(
+			http.StatusServiceUnavailable,
+			err,
+			"Live stats stream is not available",
+		))
+		return
+	}
+	defer sub.Close()
+
+	// Поток живет дольше WriteTimeout сервера: снимаем дедлайн записи для этого соединения
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.WithError(err).Debug("Failed to clear write deadline for stats stream")
+	}
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no") // отключает буферизацию ответа в nginx
+	c.Status(http.StatusOK)
+
+	if err := h.writeEvent(c, snapshot); err != nil {
+		return
+	}
+
+	h.logger.WithField("bannerID", bannerID).Debug("Stats stream opened")
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case update, ok := <-sub.Updates():
+			if !ok {
+				// Подписка закрыта хабом: медленный клиент или остановка сервера
+				reason := sub.Err()
+				event := "closed"
+				if errors.Is(reason, livestats.ErrSlowConsumer) {
+					event = "evicted"
+				}
+				h.logger.WithError(reason).WithField("bannerID", bannerID).Info("Stats stream closed by server")
+				fmt.Fprintf(c.Writer, "event: %s\ndata: %q\n\n", event, errorMessage(reason))
+				c.Writer.Flush()
+				return
+			}
+			if err := h.writeEvent(c, update); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-ctx.Done():
+			h.logger.WithField("bannerID", bannerID).Debug("Stats stream closed by client")
+			return
+		}
+	}
+}
+
+// writeEvent записывает обновление событием SSE с типом обновления в качестве имени
+func (h *StatsStreamHandler) writeEvent(c *gin.Context, update livestats.Update) error {
+	data, err := json.Marshal(update)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to encode stats stream event")
+		return err
+	}
+
+	if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", update.Type, data); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
+
+// errorMessage возвращает текст ошибки или пустую строку для nil
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/domain/apikey"
+)
+
+// Authenticator проверяет переданный клиентом API ключ
+type Authenticator func(ctx context.Context, token string) (*apikey.Key, error)
+
+// Authenticate создает middleware, пропускающий только запросы с действующим API ключом
+// в заголовке X-API-Key или Authorization: Bearer. Ключ сохраняется в контексте запроса
+// для RequireScope и проверки доступа к баннерам в use case
+func Authenticate(authenticate Authenticator, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, err := authenticate(c.Request.Context(), APIKeyFromRequest(c))
+		if err != nil {
+			if errors.Is(err, apikey.ErrInvalidKey) {
+				c.Header("WWW-Authenticate", `Bearer realm="clickcounter"`)
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error":   "Unauthorized",
+					"message": "A valid API key is required in the X-API-Key or Authorization header",
+				})
+				c.Abort()
+				return
+			}
+
+			logger.WithError(err).WithField("path", c.FullPath()).Error("Failed to authenticate API key")
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "Service Unavailable",
+				"message": "API key cannot be verified, please try again later",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(apikey.NewContext(c.Request.Context(), key))
+		c.Next()
+	}
+}
+
+// RequireScope создает middleware, проверяющий область доступа ключа запроса и, для
+// маршрутов с параметром bannerID, доступ ключа к баннеру. Подключается после Authenticate
+func RequireScope(scope apikey.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := apikey.FromContext(c.Request.Context())
+		if !ok {
+			c.Header("WWW-Authenticate", `Bearer realm="clickcounter"`)
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "Unauthorized",
+				"message": "A valid API key is required in the X-API-Key or Authorization header",
+			})
+			c.Abort()
+			return
+		}
+
+		if !key.HasScope(scope) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": fmt.Sprintf("API key does not have the %q scope", scope),
+			})
+			c.Abort()
+			return
+		}
+
+		// Некорректный ID баннера отклоняет обработчик с ответом 400
+		if bannerID, err := strconv.ParseInt(c.Param("bannerID"), 10, 64); err == nil && !key.AllowsBanner(bannerID) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": "API key is not allowed to access this banner",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
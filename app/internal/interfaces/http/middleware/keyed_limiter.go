@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// keyedBucket token bucket одного ключа
+type keyedBucket struct {
+	key      string
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// keyedLimiter хранит token bucket'ы по ключам с ограничением по памяти:
+// не более maxKeys bucket'ов (вытесняется давно неиспользуемый) и удаление
+// bucket'ов, простаивающих дольше idleTTL
+type keyedLimiter struct {
+	limit   rate.Limit
+	burst   int
+	maxKeys int
+	idleTTL time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*list.Element
+	lru       *list.List // начало - недавно использованные
+	lastSweep time.Time
+}
+
+// newKeyedLimiter создает хранилище bucket'ов
+func newKeyedLimiter(limit rate.Limit, burst, maxKeys int, idleTTL time.Duration) *keyedLimiter {
+	return &keyedLimiter{
+		limit:   limit,
+		burst:   burst,
+		maxKeys: maxKeys,
+		idleTTL: idleTTL,
+		buckets: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// get возвращает bucket ключа, создавая его при необходимости
+func (k *keyedLimiter) get(key string, now time.Time) *rate.Limiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.sweepLocked(now)
+
+	if elem, ok := k.buckets[key]; ok {
+		bucket := elem.Value.(*keyedBucket)
+		bucket.lastSeen = now
+		k.lru.MoveToFront(elem)
+		return bucket.limiter
+	}
+
+	// Вытесняем давно неиспользуемые bucket'ы при достижении лимита
+	for k.maxKeys > 0 && len(k.buckets) >= k.maxKeys {
+		k.removeLocked(k.lru.Back())
+	}
+
+	bucket := &keyedBucket{
+		key:      key,
+		limiter:  rate.NewLimiter(k.limit, k.burst),
+		lastSeen: now,
+	}
+	k.buckets[key] = k.lru.PushFront(bucket)
+
+	return bucket.limiter
+}
+
+// sweepLocked удаляет простаивающие bucket'ы не чаще раза в idleTTL/2.
+// Если idleTTL не меньше времени полного восстановления bucket'а (burst/rps),
+// удаление не меняет поведения лимита
+func (k *keyedLimiter) sweepLocked(now time.Time) {
+	if k.idleTTL <= 0 || now.Sub(k.lastSweep) < k.idleTTL/2 {
+		return
+	}
+	k.lastSweep = now
+
+	for elem := k.lru.Back(); elem != nil; elem = k.lru.Back() {
+		if now.Sub(elem.Value.(*keyedBucket).lastSeen) < k.idleTTL {
+			return
+		}
+		k.removeLocked(elem)
+	}
+}
+
+// removeLocked удаляет bucket из хранилища
+func (k *keyedLimiter) removeLocked(elem *list.Element) {
+	k.lru.Remove(elem)
+	delete(k.buckets, elem.Value.(*keyedBucket).key)
+}
@@ -1,28 +1,75 @@
 package middleware
 
 import (
-	"sync/atomic"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
-// Простые атомарные счетчики для базовых метрик
-var (
-	activeConnections int64
-	totalRequests     int64
-)
+// unmatchedRoute метка маршрута для запросов, не попавших ни в один маршрут
+const unmatchedRoute = "unmatched"
+
+// HTTPMetrics метрики HTTP запросов в формате Prometheus
+type HTTPMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+}
+
+// NewHTTPMetrics создает и регистрирует метрики HTTP запросов
+func NewHTTPMetrics(registerer prometheus.Registerer) *HTTPMetrics {
+	m := &HTTPMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "clickcounter",
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Количество обработанных HTTP запросов",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "clickcounter",
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Время обработки HTTP запросов",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"method", "route", "status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "clickcounter",
+			Subsystem: "http",
+			Name:      "requests_in_flight",
+			Help:      "Количество HTTP запросов в обработке",
+		}),
+	}
 
-// MetricsMiddleware создает middleware для сбора базовых метрик
-func MetricsMiddleware() gin.HandlerFunc {
+	registerer.MustRegister(m.requests, m.duration, m.inFlight)
+	return m
+}
+
+// MetricsMiddleware создает middleware для сбора метрик запросов по маршрутам.
+// Маршрут берется из шаблона gin (/api/v1/counter/:bannerID), а не из URL,
+// чтобы количество временных рядов не зависело от ID в запросах
+func MetricsMiddleware(metrics *HTTPMetrics) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Увеличиваем счетчик активных соединений
-		atomic.AddInt64(&activeConnections, 1)
-		atomic.AddInt64(&totalRequests, 1)
+		start := time.Now()
+		metrics.inFlight.Inc()
 
 		// Обрабатываем запрос
 		c.Next()
 
-		// Уменьшаем счетчик активных соединений
-		atomic.AddInt64(&activeConnections, -1)
+		metrics.inFlight.Dec()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		labels := prometheus.Labels{
+			"method": c.Request.Method,
+			"route":  route,
+			"status": strconv.Itoa(c.Writer.Status()),
+		}
+		metrics.requests.With(labels).Inc()
+		metrics.duration.With(labels).Observe(time.Since(start).Seconds())
 	}
 }
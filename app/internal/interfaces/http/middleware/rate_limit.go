@@ -1,20 +1,256 @@
 package middleware
 
 import (
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
 )
 
-// RateLimitMiddleware создает middleware для глобального ограничения частоты запросов
-func RateLimitMiddleware() gin.HandlerFunc {
-	// Создаем rate limiter: 5000 запросов в секунду с burst до 10000
-	// Это покрывает требования 1000-5000 RPS с запасом
-	limiter := rate.NewLimiter(rate.Limit(5000), 10000)
+// Источники ключа rate limiting
+const (
+	RateLimitKeyGlobal = "global"
+	RateLimitKeyIP     = "ip"
+	RateLimitKeyBanner = "banner"
+	RateLimitKeyAPIKey = "api_key"
+)
+
+// RateLimitRule правило ограничения частоты запросов
+type RateLimitRule struct {
+	Name   string
+	Key    string   // global, ip, banner, api_key или их комбинация через '+', например ip+banner
+	Routes []string // шаблоны маршрутов gin, например /counter/:bannerID; пусто - все запросы
+	RPS    float64
+	Burst  int
+}
+
+// RateLimitOptions ограничения памяти для bucket'ов
+type RateLimitOptions struct {
+	MaxKeys int           // максимум bucket'ов на правило (0 - без ограничения)
+	IdleTTL time.Duration // простаивающие дольше bucket'ы удаляются (0 - не удаляются)
+}
+
+// keyExtractor извлекает ключ запроса; false - правило к запросу не применяется
+type keyExtractor func(c *gin.Context) (string, bool)
+
+var keyExtractors = map[string]keyExtractor{
+	RateLimitKeyGlobal: func(c *gin.Context) (string, bool) {
+		return "", true
+	},
+	RateLimitKeyIP: func(c *gin.Context) (string, bool) {
+		ip := c.ClientIP()
+		return ip, ip != ""
+	},
+	RateLimitKeyBanner: func(c *gin.Context) (string, bool) {
+		bannerID := c.Param("bannerID")
+		return bannerID, bannerID != ""
+	},
+	RateLimitKeyAPIKey: func(c *gin.Context) (string, bool) {
+		apiKey := APIKeyFromRequest(c)
+		return apiKey, apiKey != ""
+	},
+}
+
+// APIKeyFromRequest возвращает API ключ из заголовка X-API-Key или Authorization: Bearer
+func APIKeyFromRequest(c *gin.Context) string {
+	if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+		return apiKey
+	}
+
+	const bearer = "Bearer "
+	if auth := c.GetHeader("Authorization"); len(auth) > len(bearer) && strings.EqualFold(auth[:len(bearer)], bearer) {
+		return strings.TrimSpace(auth[len(bearer):])
+	}
+
+	return ""
+}
+
+// parseKeyExtractor собирает извлекатель ключа из описания вида "ip+banner"
+func parseKeyExtractor(key string) (keyExtractor, error) {
+	parts := strings.Split(key, "+")
+	extractors := make([]keyExtractor, 0, len(parts))
+	for _, part := range parts {
+		extractor, ok := keyExtractors[strings.TrimSpace(part)]
+		if !ok {
+			return nil, fmt.Errorf("unknown rate limit key %q", part)
+		}
+		extractors = append(extractors, extractor)
+	}
+
+	if len(extractors) == 1 {
+		return extractors[0], nil
+	}
+
+	return func(c *gin.Context) (string, bool) {
+		values := make([]string, len(extractors))
+		for i, extractor := range extractors {
+			value, ok := extractor(c)
+			if !ok {
+				return "", false
+			}
+			values[i] = value
+		}
+		return strings.Join(values, "|"), true
+	}, nil
+}
+
+// rateLimitRule подготовленное правило с bucket'ами по ключам
+type rateLimitRule struct {
+	RateLimitRule
+	routes  map[string]struct{}
+	extract keyExtractor
+	buckets *keyedLimiter
+}
+
+// matches проверяет, применяется ли правило к маршруту
+func (r *rateLimitRule) matches(route string) bool {
+	if len(r.routes) == 0 {
+		return true
+	}
+	_, ok := r.routes[route]
+	return ok
+}
+
+// RateLimiter ограничивает частоту запросов по набору правил. Запрос проходит,
+// только если в bucket'е каждого применимого правила есть токен
+type RateLimiter struct {
+	rules []*rateLimitRule
+}
+
+// NewRateLimiter создает rate limiter по правилам
+func NewRateLimiter(rules []RateLimitRule, opts RateLimitOptions) (*RateLimiter, error) {
+	limiter := &RateLimiter{}
+
+	for _, rule := range rules {
+		if rule.RPS <= 0 || rule.Burst <= 0 {
+			return nil, fmt.Errorf("rate limit rule %q: rps and burst must be positive", rule.Name)
+		}
+
+		extract, err := parseKeyExtractor(rule.Key)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit rule %q: %w", rule.Name, err)
+		}
+
+		prepared := &rateLimitRule{
+			RateLimitRule: rule,
+			extract:       extract,
+			buckets:       newKeyedLimiter(rate.Limit(rule.RPS), rule.Burst, opts.MaxKeys, opts.IdleTTL),
+		}
+		if len(rule.Routes) > 0 {
+			prepared.routes = make(map[string]struct{}, len(rule.Routes))
+			for _, route := range rule.Routes {
+				prepared.routes[route] = struct{}{}
+			}
+		}
+
+		limiter.rules = append(limiter.rules, prepared)
+	}
+
+	return limiter, nil
+}
+
+// rateLimitResult итог проверки запроса по самому строгому правилу
+type rateLimitResult struct {
+	rule       *rateLimitRule
+	allowed    bool
+	remaining  int
+	reset      time.Duration // до полного восстановления bucket'а
+	retryAfter time.Duration // до появления токена (для отклоненных запросов)
+}
+
+// allow резервирует по токену во всех применимых правилах. Если хотя бы одно
+// правило отклоняет запрос, резервы отменяются, чтобы отклоненные запросы не
+// расходовали лимиты остальных правил
+func (l *RateLimiter) allow(c *gin.Context, now time.Time) rateLimitResult {
+	route := c.FullPath()
 
+	type reserved struct {
+		rule        *rateLimitRule
+		limiter     *rate.Limiter
+		reservation *rate.Reservation
+	}
+	reservations := make([]reserved, 0, len(l.rules))
+
+	result := rateLimitResult{allowed: true, remaining: math.MaxInt}
+	for _, rule := range l.rules {
+		if !rule.matches(route) {
+			continue
+		}
+
+		key, ok := rule.extract(c)
+		if !ok {
+			continue
+		}
+
+		limiter := rule.buckets.get(key, now)
+		reservation := limiter.ReserveN(now, 1)
+		reservations = append(reservations, reserved{rule, limiter, reservation})
+
+		if delay := reservation.DelayFrom(now); delay > 0 && delay > result.retryAfter {
+			result.allowed = false
+			result.rule = rule
+			result.retryAfter = delay
+		}
+	}
+
+	if !result.allowed {
+		for _, r := range reservations {
+			r.reservation.CancelAt(now)
+		}
+		result.remaining = 0
+		result.reset = fullRefill(result.rule, 0)
+		return result
+	}
+
+	for _, r := range reservations {
+		tokens := r.limiter.TokensAt(now)
+		if remaining := int(math.Max(tokens, 0)); remaining < result.remaining {
+			result.rule = r.rule
+			result.remaining = remaining
+			result.reset = fullRefill(r.rule, tokens)
+		}
+	}
+
+	return result
+}
+
+// fullRefill возвращает время до полного восстановления bucket'а
+func fullRefill(rule *rateLimitRule, tokens float64) time.Duration {
+	missing := float64(rule.Burst) - tokens
+	if missing <= 0 {
+		return 0
+	}
+	return time.Duration(missing / rule.RPS * float64(time.Second))
+}
+
+// ceilSeconds округляет длительность вверх до целых секунд
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
+
+// RateLimitMiddleware создает middleware для ограничения частоты запросов.
+// Заголовки X-RateLimit-* описывают самое строгое из примененных правил
+func RateLimitMiddleware(limiter *RateLimiter) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if !limiter.Allow() {
+		result := limiter.allow(c, time.Now())
+
+		if result.rule != nil {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(result.rule.Burst))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(result.remaining))
+			c.Header("X-RateLimit-Reset", strconv.FormatInt(ceilSeconds(result.reset), 10))
+		}
+
+		if !result.allowed {
+			retryAfter := ceilSeconds(result.retryAfter)
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error":   "Rate limit exceeded",
 				"message": "Too many requests, please try again later",
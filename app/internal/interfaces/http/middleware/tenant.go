@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/clickcounter/app/internal/domain/apikey"
+	"github.com/clickcounter/app/internal/domain/tenant"
+)
+
+// TenantHeader заголовок выбора арендатора запроса
+const TenantHeader = "X-Tenant-ID"
+
+// TenantLookup возвращает арендатора по ID
+type TenantLookup func(ctx context.Context, id int64) (*tenant.Tenant, error)
+
+// ResolveTenant создает middleware, определяющий арендатора запроса и сохраняющий его
+// в контексте: им ограничиваются запросы к БД и ключи кэша. Подключается после Authenticate.
+//
+// Ключ из БД работает только с данными своего арендатора: заголовок X-Tenant-ID с другим
+// арендатором отклоняется. Ключ из конфигурации выбирает арендатора заголовком X-Tenant-ID,
+// без заголовка - арендатор по умолчанию. Запрос без ключа (аутентификация отключена)
+// работает только с арендатором по умолчанию: иначе любой клиент выбрал бы чужого арендатора
+func ResolveTenant(lookup TenantLookup, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader(TenantHeader)
+
+		var requested int64
+		if header != "" {
+			id, err := tenant.ParseID(header)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Bad Request",
+					"message": "X-Tenant-ID must be a positive integer",
+				})
+				c.Abort()
+				return
+			}
+			requested = id
+		}
+
+		tenantID := tenant.DefaultID
+		key, authenticated := apikey.FromContext(c.Request.Context())
+		if !authenticated {
+			if requested != 0 && requested != tenant.DefaultID {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error":   "Forbidden",
+					"message": "X-Tenant-ID requires an API key",
+				})
+				c.Abort()
+				return
+			}
+		} else if !key.Bootstrap {
+			if requested != 0 && requested != key.TenantID {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error":   "Forbidden",
+					"message": "API key does not belong to the requested tenant",
+				})
+				c.Abort()
+				return
+			}
+			tenantID = key.TenantID
+		} else if requested != 0 {
+			if _, err := lookup(c.Request.Context(), requested); err != nil {
+				if errors.Is(err, tenant.ErrTenantNotFound) {
+					c.JSON(http.StatusNotFound, gin.H{
+						"error":   "Not Found",
+						"message": "Tenant specified in X-Tenant-ID not found",
+					})
+					c.Abort()
+					return
+				}
+
+				logger.WithError(err).WithField("tenant_id", requested).Error("Failed to resolve tenant")
+				c.JSON(http.StatusServiceUnavailable, gin.H{
+					"error":   "Service Unavailable",
+					"message": "Tenant cannot be verified, please try again later",
+				})
+				c.Abort()
+				return
+			}
+			tenantID = requested
+		}
+
+		c.Request = c.Request.WithContext(tenant.NewContext(c.Request.Context(), tenantID))
+		c.Next()
+	}
+}
@@ -8,9 +8,32 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// TimeoutMiddleware создает middleware для ограничения времени выполнения запроса
-func TimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+// SkipRoutes возвращает условие пропуска TimeoutMiddleware для маршрутов gin
+// (шаблонов, например "/api/v1/stats/:bannerID/stream")
+func SkipRoutes(routes ...string) func(*gin.Context) bool {
+	set := make(map[string]struct{}, len(routes))
+	for _, route := range routes {
+		set[route] = struct{}{}
+	}
+
+	return func(c *gin.Context) bool {
+		_, ok := set[c.FullPath()]
+		return ok
+	}
+}
+
+// TimeoutMiddleware создает middleware для ограничения времени выполнения запроса.
+// Запросы, для которых выполняется одно из условий skip, не ограничиваются:
+// это долгоживущие потоки и выгрузки
+func TimeoutMiddleware(timeout time.Duration, skip ...func(*gin.Context) bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		for _, shouldSkip := range skip {
+			if shouldSkip(c) {
+				c.Next()
+				return
+			}
+		}
+
 		// Создаем контекст с таймаутом
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 		defer cancel()
@@ -1,6 +1,7 @@
 package router
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -9,6 +10,8 @@ import (
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 
+	"github.com/clickcounter/app/internal/domain/apikey"
+	"github.com/clickcounter/app/internal/interfaces/http/dto"
 	"github.com/clickcounter/app/internal/interfaces/http/handlers"
 	"github.com/clickcounter/app/internal/interfaces/http/middleware"
 )
@@ -18,14 +21,40 @@ type Router struct {
 	engine        *gin.Engine
 	clickHandler  *handlers.ClickHandler
 	statsHandler  *handlers.StatsHandler
+	bannerHandler *handlers.BannerHandler
 	healthHandler *handlers.HealthHandler
 	logger        *logrus.Logger
+
+	// Метрики (nil - отключены)
+	httpMetrics    *middleware.HTTPMetrics
+	metricsPath    string
+	metricsHandler http.Handler
+
+	// Ограничение частоты запросов (nil - отключено)
+	rateLimiter *middleware.RateLimiter
+
+	// Поток live-статистики (nil - отключен)
+	statsStreamHandler *handlers.StatsStreamHandler
+
+	// Поиск кликов (nil - отключен)
+	clickSearchHandler *handlers.ClickSearchHandler
+
+	// Аутентификация /api/v1 по API ключам и управление ключами (nil - доступ без ключа)
+	auth          gin.HandlerFunc
+	apiKeyHandler *handlers.APIKeyHandler
+
+	// Определение арендатора запроса для /api/v1 и статистики (nil - без ограничения арендатором)
+	tenant gin.HandlerFunc
 }
 
+// statsStreamRoute маршрут потока live-статистики
+const statsStreamRoute = "/api/v1/stats/:bannerID/stream"
+
 // NewRouter создает новый HTTP роутер
 func NewRouter(
 	clickHandler *handlers.ClickHandler,
 	statsHandler *handlers.StatsHandler,
+	bannerHandler *handlers.BannerHandler,
 	healthHandler *handlers.HealthHandler,
 	logger *logrus.Logger,
 ) *Router {
@@ -42,11 +71,50 @@ func NewRouter(
 		engine:        engine,
 		clickHandler:  clickHandler,
 		statsHandler:  statsHandler,
+		bannerHandler: bannerHandler,
 		healthHandler: healthHandler,
 		logger:        logger,
 	}
 }
 
+// EnableMetrics включает сбор метрик HTTP запросов и endpoint экспорта метрик.
+// Должен вызываться до Setup
+func (r *Router) EnableMetrics(httpMetrics *middleware.HTTPMetrics, path string, handler http.Handler) {
+	r.httpMetrics = httpMetrics
+	r.metricsPath = path
+	r.metricsHandler = handler
+}
+
+// EnableRateLimit включает ограничение частоты запросов. Должен вызываться до Setup
+func (r *Router) EnableRateLimit(limiter *middleware.RateLimiter) {
+	r.rateLimiter = limiter
+}
+
+// EnableStatsStream включает поток live-статистики через Server-Sent Events.
+// Должен вызываться до Setup
+func (r *Router) EnableStatsStream(handler *handlers.StatsStreamHandler) {
+	r.statsStreamHandler = handler
+}
+
+// EnableClickSearch включает поиск сохраненных кликов. Поиск отдает IP и User-Agent
+// посетителей, поэтому подключается только вместе с EnableAuth. Должен вызываться до Setup
+func (r *Router) EnableClickSearch(handler *handlers.ClickSearchHandler) {
+	r.clickSearchHandler = handler
+}
+
+// EnableAuth включает проверку API ключей middleware auth для /api/v1 и статистики
+// и endpoints управления ключами. Должен вызываться до Setup
+func (r *Router) EnableAuth(auth gin.HandlerFunc, apiKeyHandler *handlers.APIKeyHandler) {
+	r.auth = auth
+	r.apiKeyHandler = apiKeyHandler
+}
+
+// EnableTenants включает определение арендатора запроса middleware tenant для /api/v1
+// и статистики: данные и кэш ограничиваются арендатором. Должен вызываться до Setup
+func (r *Router) EnableTenants(tenant gin.HandlerFunc) {
+	r.tenant = tenant
+}
+
 // Setup настраивает все маршруты и middleware
 func (r *Router) Setup() {
 	// Middleware
@@ -70,45 +138,122 @@ func (r *Router) setupMiddleware() {
 	// Логирование запросов
 	r.engine.Use(middleware.LoggingMiddleware(r.logger))
 
-	// CORS
+	// Метрики подключаются до rate limiting и таймаута, чтобы учитывать и отклоненные запросы
+	if r.httpMetrics != nil {
+		r.engine.Use(middleware.MetricsMiddleware(r.httpMetrics))
+	}
+
+	// CORS. Ключ передается в заголовке, а не в cookie: credentials не нужны,
+	// а с любым origin браузеры их и не допускают
 	r.engine.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
+		AllowOrigins:  []string{"*"},
+		AllowMethods:  []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders:  []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", "X-Tenant-ID", "X-Requested-With"},
+		ExposeHeaders: []string{"Content-Length"},
+		MaxAge:        12 * time.Hour,
 	}))
 
 	// Rate limiting middleware
-	r.engine.Use(middleware.RateLimitMiddleware())
+	if r.rateLimiter != nil {
+		r.engine.Use(middleware.RateLimitMiddleware(r.rateLimiter))
+	}
 
-	// Request timeout middleware
-	r.engine.Use(middleware.TimeoutMiddleware(30 * time.Second))
+	// Request timeout middleware. Поток live-статистики и выгрузки не ограничиваются по времени
+	r.engine.Use(middleware.TimeoutMiddleware(30*time.Second, middleware.SkipRoutes(statsStreamRoute), isStatsExport))
+}
 
-	// Metrics middleware
-	r.engine.Use(middleware.MetricsMiddleware())
+// isStatsExport сообщает, запрошена ли потоковая выгрузка статистики (CSV или NDJSON)
+func isStatsExport(c *gin.Context) bool {
+	switch c.FullPath() {
+	case "/api/v1/stats/:bannerID", "/stats/:bannerID":
+		format, err := dto.StatsFormatFromRequest(c.Request)
+		return err == nil && format.Streamed()
+	default:
+		return false
+	}
 }
 
 // setupAPIRoutes настраивает API маршруты
 func (r *Router) setupAPIRoutes() {
-	// API v1 группа
+	// Регистрация клика публичная, как и корневой /counter: регистрируется вне группы
+	// с аутентификацией и не ограничена арендатором
+	r.engine.GET("/api/v1/counter/:bannerID", r.clickHandler.RegisterClick)
+
+	// API v1 группа: остальные маршруты требуют API ключ с нужной областью доступа
 	v1 := r.engine.Group("/api/v1")
+	if r.auth != nil {
+		v1.Use(r.auth)
+	}
+	if r.tenant != nil {
+		v1.Use(r.tenant)
+	}
 	{
 		// Основные endpoints согласно ТЗ
-		v1.GET("/counter/:bannerID", r.clickHandler.RegisterClick)
-		v1.POST("/stats/:bannerID", r.statsHandler.GetStats)
+		v1.POST("/stats", r.scoped(apikey.ScopeStatsRead, r.statsHandler.GetMultiStats)...)
+		v1.POST("/stats/:bannerID", r.scoped(apikey.ScopeStatsRead, r.statsHandler.GetStats)...)
+
+		if r.statsStreamHandler != nil {
+			v1.GET("/stats/:bannerID/stream", r.scoped(apikey.ScopeStatsRead, r.statsStreamHandler.Stream)...)
+		}
+
+		// Управление баннерами
+		banners := v1.Group("/banners")
+		{
+			banners.GET("", r.scoped(apikey.ScopeAdmin, r.bannerHandler.ListBanners)...)
+			banners.POST("", r.scoped(apikey.ScopeAdmin, r.bannerHandler.CreateBanner)...)
+			banners.GET("/:bannerID", r.scoped(apikey.ScopeStatsRead, r.bannerHandler.GetBanner)...)
+			banners.PUT("/:bannerID", r.scoped(apikey.ScopeAdmin, r.bannerHandler.UpdateBanner)...)
+			banners.POST("/:bannerID/activate", r.scoped(apikey.ScopeAdmin, r.bannerHandler.ActivateBanner)...)
+			banners.POST("/:bannerID/deactivate", r.scoped(apikey.ScopeAdmin, r.bannerHandler.DeactivateBanner)...)
+
+			if r.clickSearchHandler != nil && r.auth != nil {
+				banners.GET("/:bannerID/clicks", r.scoped(apikey.ScopeStatsRead, r.clickSearchHandler.SearchClicks)...)
+			}
+		}
+
+		// Управление API ключами
+		if r.apiKeyHandler != nil {
+			apiKeys := v1.Group("/api-keys")
+			{
+				apiKeys.GET("", r.scoped(apikey.ScopeAdmin, r.apiKeyHandler.ListAPIKeys)...)
+				apiKeys.POST("", r.scoped(apikey.ScopeAdmin, r.apiKeyHandler.CreateAPIKey)...)
+				apiKeys.DELETE("/:keyID", r.scoped(apikey.ScopeAdmin, r.apiKeyHandler.RevokeAPIKey)...)
+			}
+		}
 	}
 
-	// Корневые маршруты (для совместимости с примером из ТЗ)
+	// Корневые маршруты (для совместимости с примером из ТЗ). Регистрация клика
+	// остается публичной и не ограничена арендатором, статистика требует ключ, как и в /api/v1
 	r.engine.GET("/counter/:bannerID", r.clickHandler.RegisterClick)
-	r.engine.POST("/stats/:bannerID", r.statsHandler.GetStats)
+
+	rootStats := r.scoped(apikey.ScopeStatsRead, r.statsHandler.GetStats)
+	if r.tenant != nil {
+		rootStats = append([]gin.HandlerFunc{r.tenant}, rootStats...)
+	}
+	if r.auth != nil {
+		rootStats = append([]gin.HandlerFunc{r.auth}, rootStats...)
+	}
+	r.engine.POST("/stats/:bannerID", rootStats...)
+}
+
+// scoped возвращает цепочку обработчиков маршрута с проверкой области доступа
+// API ключа. Без аутентификации проверка не добавляется
+func (r *Router) scoped(scope apikey.Scope, handler gin.HandlerFunc) []gin.HandlerFunc {
+	if r.auth == nil {
+		return []gin.HandlerFunc{handler}
+	}
+	return []gin.HandlerFunc{middleware.RequireScope(scope), handler}
 }
 
 // setupHealthRoutes настраивает маршруты для проверки здоровья
 func (r *Router) setupHealthRoutes() {
 	// Основной health check маршрут
 	r.engine.GET("/health", r.healthHandler.HealthCheck)
+
+	// Метрики в формате Prometheus
+	if r.metricsHandler != nil {
+		r.engine.GET(r.metricsPath, gin.WrapH(r.metricsHandler))
+	}
 }
 
 // setupSwagger настраивает Swagger документацию
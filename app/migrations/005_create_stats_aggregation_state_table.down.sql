@@ -0,0 +1,2 @@
+-- Drop stats aggregation state table
+DROP TABLE IF EXISTS stats_aggregation_state;
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- Create stats aggregation state table
+CREATE TABLE IF NOT EXISTS stats_aggregation_state (
+    name VARCHAR(64) PRIMARY KEY,
+    last_click_id BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Initial high-water mark for the clicks aggregator
+INSERT INTO stats_aggregation_state (name, last_click_id, updated_at)
+VALUES ('clicks', 0, NOW())
+ON CONFLICT (name) DO NOTHING;
+
+-- Add comments
+COMMENT ON TABLE stats_aggregation_state IS 'Состояние фоновой агрегации кликов в статистику';
+COMMENT ON COLUMN stats_aggregation_state.name IS 'Имя агрегатора';
+COMMENT ON COLUMN stats_aggregation_state.last_click_id IS 'ID последнего клика, учтенного в статистике (high-water mark)';
+COMMENT ON COLUMN stats_aggregation_state.updated_at IS 'Время последнего обновления записи';
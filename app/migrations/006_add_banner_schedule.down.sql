@@ -0,0 +1,6 @@
+-- Drop campaign schedule window from banners
+ALTER TABLE banners DROP CONSTRAINT IF EXISTS chk_banners_schedule;
+
+ALTER TABLE banners
+    DROP COLUMN IF EXISTS ends_at,
+    DROP COLUMN IF EXISTS starts_at;
@@ -0,0 +1,12 @@
+-- Add campaign schedule window to banners
+ALTER TABLE banners
+    ADD COLUMN IF NOT EXISTS starts_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS ends_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE banners
+    ADD CONSTRAINT chk_banners_schedule CHECK (starts_at IS NULL OR ends_at IS NULL OR ends_at > starts_at);
+
+-- Add comments
+COMMENT ON COLUMN banners.starts_at IS 'Начало показа баннера (NULL - без ограничения)';
+COMMENT ON COLUMN banners.ends_at IS 'Окончание показа баннера, не включительно (NULL - без ограничения)';
+COMMENT ON CONSTRAINT chk_banners_schedule ON banners IS 'Окончание показа позже начала';
@@ -0,0 +1,3 @@
+-- Drop repeat click flag
+ALTER TABLE clicks
+    DROP COLUMN IF EXISTS is_duplicate;
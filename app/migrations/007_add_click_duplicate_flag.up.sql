@@ -0,0 +1,6 @@
+-- Flag repeat clicks from the same fingerprint within the dedup window
+ALTER TABLE clicks
+    ADD COLUMN IF NOT EXISTS is_duplicate BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Add comments
+COMMENT ON COLUMN clicks.is_duplicate IS 'Повторный клик в окне дедупликации (не учитывается в статистике)';
@@ -0,0 +1,10 @@
+-- Drop bot flag and bot counters
+ALTER TABLE stats DROP CONSTRAINT IF EXISTS chk_stats_bot_count_non_negative;
+
+ALTER TABLE stats
+    DROP COLUMN IF EXISTS bot_count;
+
+ALTER TABLE clicks
+    DROP COLUMN IF EXISTS is_bot;
+
+COMMENT ON COLUMN stats.count IS 'Количество кликов за минуту';
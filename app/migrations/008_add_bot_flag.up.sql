@@ -0,0 +1,15 @@
+-- Flag clicks detected by the bot filter and count them separately in stats
+ALTER TABLE clicks
+    ADD COLUMN IF NOT EXISTS is_bot BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE stats
+    ADD COLUMN IF NOT EXISTS bot_count BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE stats DROP CONSTRAINT IF EXISTS chk_stats_bot_count_non_negative;
+ALTER TABLE stats
+    ADD CONSTRAINT chk_stats_bot_count_non_negative CHECK (bot_count >= 0);
+
+-- Add comments
+COMMENT ON COLUMN clicks.is_bot IS 'Клик признан фильтром ботов';
+COMMENT ON COLUMN stats.count IS 'Количество кликов за минуту без учета ботов';
+COMMENT ON COLUMN stats.bot_count IS 'Количество кликов ботов за минуту';
@@ -0,0 +1,3 @@
+-- Drop unique visitors sketches
+ALTER TABLE stats
+    DROP COLUMN IF EXISTS visitors_hll;
@@ -0,0 +1,6 @@
+-- Store a HyperLogLog sketch of unique visitors (IP + User-Agent) per banner minute
+ALTER TABLE stats
+    ADD COLUMN IF NOT EXISTS visitors_hll BYTEA;
+
+-- Add comments
+COMMENT ON COLUMN stats.visitors_hll IS 'HyperLogLog скетч уникальных посетителей за минуту без учета ботов';
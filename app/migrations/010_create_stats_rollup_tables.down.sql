@@ -0,0 +1,6 @@
+-- Drop stats rollups
+DROP INDEX IF EXISTS idx_stats_updated_at;
+
+DROP TABLE IF EXISTS stats_rollup_state;
+DROP TABLE IF EXISTS stats_daily;
+DROP TABLE IF EXISTS stats_hourly;
@@ -0,0 +1,51 @@
+-- Create hourly and daily rollups of minute stats
+CREATE TABLE IF NOT EXISTS stats_hourly (
+    banner_id BIGINT NOT NULL,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+    count BIGINT NOT NULL DEFAULT 0,
+    bot_count BIGINT NOT NULL DEFAULT 0,
+    visitors_hll BYTEA,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT pk_stats_hourly PRIMARY KEY (banner_id, timestamp),
+    CONSTRAINT fk_stats_hourly_banner_id FOREIGN KEY (banner_id) REFERENCES banners(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS stats_daily (
+    banner_id BIGINT NOT NULL,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+    count BIGINT NOT NULL DEFAULT 0,
+    bot_count BIGINT NOT NULL DEFAULT 0,
+    visitors_hll BYTEA,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT pk_stats_daily PRIMARY KEY (banner_id, timestamp),
+    CONSTRAINT fk_stats_daily_banner_id FOREIGN KEY (banner_id) REFERENCES banners(id) ON DELETE CASCADE
+);
+
+-- Rollup state per level
+CREATE TABLE IF NOT EXISTS stats_rollup_state (
+    name VARCHAR(64) PRIMARY KEY,
+    source_updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT 'epoch',
+    complete_before TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT 'epoch',
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO stats_rollup_state (name)
+VALUES ('hourly'), ('daily')
+ON CONFLICT (name) DO NOTHING;
+
+-- Indexes for finding source rows changed since the previous rollup pass
+CREATE INDEX IF NOT EXISTS idx_stats_updated_at ON stats(updated_at);
+CREATE INDEX IF NOT EXISTS idx_stats_hourly_updated_at ON stats_hourly(updated_at);
+
+-- Index for timestamp range queries across all banners (retention)
+CREATE INDEX IF NOT EXISTS idx_stats_hourly_timestamp ON stats_hourly(timestamp);
+CREATE INDEX IF NOT EXISTS idx_stats_daily_timestamp ON stats_daily(timestamp);
+
+-- Add comments
+COMMENT ON TABLE stats_hourly IS 'Почасовая статистика кликов, свернутая из минутной';
+COMMENT ON TABLE stats_daily IS 'Дневная статистика кликов (UTC), свернутая из почасовой';
+COMMENT ON COLUMN stats_hourly.visitors_hll IS 'HyperLogLog скетч уникальных посетителей за час без учета ботов';
+COMMENT ON COLUMN stats_daily.visitors_hll IS 'HyperLogLog скетч уникальных посетителей за день без учета ботов';
+COMMENT ON TABLE stats_rollup_state IS 'Состояние сворачивания статистики по уровням';
+COMMENT ON COLUMN stats_rollup_state.source_updated_at IS 'Изменения исходной таблицы до этого момента (по updated_at) учтены';
+COMMENT ON COLUMN stats_rollup_state.complete_before IS 'Бакеты раньше этой границы свернуты и используются для чтения';
@@ -0,0 +1,42 @@
+-- Convert partitioned clicks back to a single table.
+-- Unlike the up migration this copies every click under an exclusive lock:
+-- run it only with the service stopped
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM pg_partitioned_table
+        WHERE partrelid = to_regclass('clicks')
+    ) THEN
+        ALTER TABLE clicks RENAME TO clicks_partitioned;
+        ALTER INDEX clicks_pkey RENAME TO clicks_partitioned_pkey;
+        ALTER INDEX idx_clicks_banner_timestamp RENAME TO idx_clicks_partitioned_banner_timestamp;
+        ALTER INDEX idx_clicks_timestamp RENAME TO idx_clicks_partitioned_timestamp;
+        ALTER INDEX idx_clicks_banner_id RENAME TO idx_clicks_partitioned_banner_id;
+        ALTER TABLE clicks_partitioned RENAME CONSTRAINT fk_clicks_banner_id TO fk_clicks_partitioned_banner_id;
+
+        CREATE TABLE clicks (
+            id BIGINT PRIMARY KEY DEFAULT nextval('clicks_id_seq'),
+            banner_id BIGINT NOT NULL,
+            timestamp TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+            user_ip INET,
+            user_agent TEXT,
+            is_duplicate BOOLEAN NOT NULL DEFAULT FALSE,
+            is_bot BOOLEAN NOT NULL DEFAULT FALSE,
+            CONSTRAINT fk_clicks_banner_id FOREIGN KEY (banner_id) REFERENCES banners(id) ON DELETE CASCADE
+        );
+
+        ALTER SEQUENCE clicks_id_seq OWNED BY clicks.id;
+
+        INSERT INTO clicks (id, banner_id, timestamp, user_ip, user_agent, is_duplicate, is_bot)
+        SELECT id, banner_id, timestamp, user_ip, user_agent, is_duplicate, is_bot
+        FROM clicks_partitioned;
+
+        DROP TABLE clicks_partitioned;
+
+        CREATE INDEX IF NOT EXISTS idx_clicks_banner_timestamp ON clicks(banner_id, timestamp);
+        CREATE INDEX IF NOT EXISTS idx_clicks_timestamp ON clicks(timestamp);
+        CREATE INDEX IF NOT EXISTS idx_clicks_banner_id ON clicks(banner_id);
+
+        COMMENT ON TABLE clicks IS 'Таблица кликов по баннерам';
+    END IF;
+END $$;
@@ -0,0 +1,94 @@
+-- Convert clicks to a table partitioned by click time.
+-- Existing rows are not copied: the old table is attached as a single partition
+-- covering all existing clicks (up to the end of the current month). A validated
+-- CHECK constraint lets ATTACH PARTITION skip its own scan, and the existing
+-- indexes and foreign key are reused; only the (id, timestamp) primary key index
+-- required by the partitioned table is built. Partitions for new clicks are
+-- created ahead of time by the partition manager (click_partitions config);
+-- retention drops the legacy partition once all of it is older than the
+-- retention period.
+-- The conversion is skipped if clicks is already partitioned
+DO $$
+DECLARE
+    legacy_from TIMESTAMPTZ;
+    legacy_to TIMESTAMPTZ;
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM pg_partitioned_table
+        WHERE partrelid = to_regclass('clicks')
+    ) THEN
+        RETURN;
+    END IF;
+
+    ALTER TABLE clicks RENAME TO clicks_legacy;
+    ALTER INDEX IF EXISTS clicks_pkey RENAME TO clicks_legacy_pkey;
+    ALTER INDEX IF EXISTS idx_clicks_banner_timestamp RENAME TO idx_clicks_legacy_banner_timestamp;
+    ALTER INDEX IF EXISTS idx_clicks_timestamp RENAME TO idx_clicks_legacy_timestamp;
+    ALTER INDEX IF EXISTS idx_clicks_banner_id RENAME TO idx_clicks_legacy_banner_id;
+    ALTER TABLE clicks_legacy RENAME CONSTRAINT fk_clicks_banner_id TO fk_clicks_legacy_banner_id;
+
+    -- Monthly bounds (UTC) of existing clicks; MIN and MAX are read from the timestamp index
+    SELECT
+        COALESCE(date_trunc('month', MIN(timestamp), 'UTC'), date_trunc('month', NOW(), 'UTC')),
+        GREATEST(
+            date_trunc('month', NOW(), 'UTC') + INTERVAL '1 month',
+            COALESCE(date_trunc('month', MAX(timestamp), 'UTC') + INTERVAL '1 month', '-infinity')
+        )
+    INTO legacy_from, legacy_to
+    FROM clicks_legacy;
+
+    -- The primary key of a partitioned table must include the partition key
+    CREATE TABLE clicks (
+        id BIGINT NOT NULL DEFAULT nextval('clicks_id_seq'),
+        banner_id BIGINT NOT NULL,
+        timestamp TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+        user_ip INET,
+        user_agent TEXT,
+        is_duplicate BOOLEAN NOT NULL DEFAULT FALSE,
+        is_bot BOOLEAN NOT NULL DEFAULT FALSE,
+        CONSTRAINT clicks_pkey PRIMARY KEY (id, timestamp),
+        CONSTRAINT fk_clicks_banner_id FOREIGN KEY (banner_id) REFERENCES banners(id) ON DELETE CASCADE
+    ) PARTITION BY RANGE (timestamp);
+
+    ALTER SEQUENCE clicks_id_seq OWNED BY clicks.id;
+
+    -- Indexes are created on every partition automatically
+    CREATE INDEX idx_clicks_banner_timestamp ON clicks(banner_id, timestamp);
+    CREATE INDEX idx_clicks_timestamp ON clicks(timestamp);
+    CREATE INDEX idx_clicks_banner_id ON clicks(banner_id);
+
+    -- Clicks outside of existing partitions land here instead of failing the batch
+    CREATE TABLE clicks_default PARTITION OF clicks DEFAULT;
+
+    -- Proves the partition bound, so ATTACH PARTITION does not scan the table again
+    EXECUTE format(
+        'ALTER TABLE clicks_legacy ADD CONSTRAINT clicks_legacy_timestamp_range CHECK (timestamp >= %L AND timestamp < %L) NOT VALID',
+        legacy_from,
+        legacy_to
+    );
+    ALTER TABLE clicks_legacy VALIDATE CONSTRAINT clicks_legacy_timestamp_range;
+
+    -- The partition must have a primary key matching the partitioned table
+    ALTER TABLE clicks_legacy DROP CONSTRAINT clicks_legacy_pkey;
+    ALTER TABLE clicks_legacy ADD CONSTRAINT clicks_legacy_pkey PRIMARY KEY (id, timestamp);
+
+    EXECUTE format(
+        'ALTER TABLE clicks ATTACH PARTITION clicks_legacy FOR VALUES FROM (%L) TO (%L)',
+        legacy_from,
+        legacy_to
+    );
+
+    -- The partition bound now enforces the range
+    ALTER TABLE clicks_legacy DROP CONSTRAINT clicks_legacy_timestamp_range;
+END $$;
+
+-- Add comments
+COMMENT ON TABLE clicks IS 'Таблица кликов по баннерам, секционированная по времени клика';
+COMMENT ON TABLE clicks_default IS 'Клики вне созданных секций (должна оставаться пустой)';
+COMMENT ON COLUMN clicks.id IS 'Уникальный идентификатор клика';
+COMMENT ON COLUMN clicks.banner_id IS 'Идентификатор баннера';
+COMMENT ON COLUMN clicks.timestamp IS 'Время клика';
+COMMENT ON COLUMN clicks.user_ip IS 'IP адрес пользователя';
+COMMENT ON COLUMN clicks.user_agent IS 'User-Agent браузера пользователя';
+COMMENT ON COLUMN clicks.is_duplicate IS 'Повторный клик в окне дедупликации (не учитывается в статистике)';
+COMMENT ON COLUMN clicks.is_bot IS 'Клик признан фильтром ботов';
@@ -0,0 +1,26 @@
+-- Create API keys table: only SHA-256 of a key is stored
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(32) NOT NULL,
+    key_hash CHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL,
+    banner_ids BIGINT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT uq_api_keys_key_hash UNIQUE (key_hash),
+    CONSTRAINT chk_api_keys_scopes CHECK (
+        cardinality(scopes) > 0 AND scopes <@ ARRAY['click:write', 'stats:read', 'admin']::TEXT[]
+    )
+);
+
+-- Add comments
+COMMENT ON TABLE api_keys IS 'API ключи доступа к /api/v1';
+COMMENT ON COLUMN api_keys.name IS 'Название ключа (владелец, назначение)';
+COMMENT ON COLUMN api_keys.prefix IS 'Начало ключа для опознания';
+COMMENT ON COLUMN api_keys.key_hash IS 'SHA-256 ключа в hex';
+COMMENT ON COLUMN api_keys.scopes IS 'Области доступа: click:write, stats:read, admin';
+COMMENT ON COLUMN api_keys.banner_ids IS 'Баннеры, доступные ключу; пустой массив - все баннеры';
+COMMENT ON COLUMN api_keys.expires_at IS 'Время истечения ключа; NULL - бессрочный';
+COMMENT ON COLUMN api_keys.revoked_at IS 'Время отзыва ключа';
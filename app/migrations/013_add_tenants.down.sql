@@ -0,0 +1,8 @@
+-- Drop tenant columns and tenants table
+DROP INDEX IF EXISTS idx_api_keys_tenant_id;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant_id;
+
+DROP INDEX IF EXISTS idx_banners_tenant_id;
+ALTER TABLE banners DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS tenants;
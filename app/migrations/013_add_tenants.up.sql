@@ -0,0 +1,31 @@
+-- Create tenants table: business units whose data is isolated from each other
+CREATE TABLE IF NOT EXISTS tenants (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_tenants_name UNIQUE (name)
+);
+
+-- Default tenant owns all existing data
+INSERT INTO tenants (id, name) VALUES (1, 'default') ON CONFLICT (id) DO NOTHING;
+SELECT setval('tenants_id_seq', (SELECT MAX(id) FROM tenants));
+
+-- Banners belong to a tenant; clicks and stats are scoped through the banner FK
+ALTER TABLE banners
+    ADD COLUMN IF NOT EXISTS tenant_id BIGINT NOT NULL DEFAULT 1
+        CONSTRAINT fk_banners_tenant_id REFERENCES tenants(id);
+
+CREATE INDEX IF NOT EXISTS idx_banners_tenant_id ON banners (tenant_id, id);
+
+-- API keys act on behalf of a tenant
+ALTER TABLE api_keys
+    ADD COLUMN IF NOT EXISTS tenant_id BIGINT NOT NULL DEFAULT 1
+        CONSTRAINT fk_api_keys_tenant_id REFERENCES tenants(id);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_tenant_id ON api_keys (tenant_id);
+
+-- Add comments
+COMMENT ON TABLE tenants IS 'Арендаторы: бизнес-подразделения с изолированными данными';
+COMMENT ON COLUMN tenants.name IS 'Название арендатора';
+COMMENT ON COLUMN banners.tenant_id IS 'Арендатор баннера; клики и статистика принадлежат арендатору баннера';
+COMMENT ON COLUMN api_keys.tenant_id IS 'Арендатор, к данным которого ключ дает доступ';
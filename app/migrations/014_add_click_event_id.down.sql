@@ -0,0 +1,5 @@
+-- Drop click idempotency key
+DROP INDEX IF EXISTS idx_clicks_event_id;
+
+ALTER TABLE clicks
+    DROP COLUMN IF EXISTS event_id;
@@ -0,0 +1,16 @@
+-- Idempotency key of a registered click: replaying the write-ahead log or retrying
+-- a batch whose commit outcome is unknown must not store the same click twice
+ALTER TABLE clicks
+    ADD COLUMN IF NOT EXISTS event_id UUID;
+
+-- A unique index of a partitioned table must include the partition key;
+-- a click keeps its timestamp on replay, so (event_id, timestamp) identifies it.
+-- Only the (invalid) parent index is created here: building it on every partition
+-- in one statement would block click inserts for the whole build. The partition
+-- manager builds the index on existing partitions with CREATE INDEX CONCURRENTLY
+-- and attaches them; the parent index becomes valid once all are attached.
+-- Partitions created afterwards get the index automatically
+CREATE UNIQUE INDEX IF NOT EXISTS idx_clicks_event_id ON ONLY clicks (event_id, timestamp);
+
+-- Add comments
+COMMENT ON COLUMN clicks.event_id IS 'Ключ идемпотентности клика (NULL для кликов, записанных в обход сервиса)';
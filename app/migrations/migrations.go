@@ -0,0 +1,9 @@
+// Package migrations содержит SQL миграции схемы, встроенные в бинарный файл сервера
+package migrations
+
+import "embed"
+
+// FS файлы миграций вида NNN_name.up.sql и NNN_name.down.sql
+//
+//go:embed *.sql
+var FS embed.FS
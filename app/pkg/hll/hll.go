@@ -0,0 +1,215 @@
+// Package hll реализует HyperLogLog - вероятностную оценку количества
+// уникальных элементов с фиксированным объемом памяти. Скетчи с одинаковой
+// точностью объединяются без потери точности, поэтому уникальные значения
+// за произвольный период получаются слиянием скетчей его частей
+package hll
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// DefaultPrecision точность по умолчанию: 2^11 регистров, стандартная ошибка ~2.3%
+const DefaultPrecision = 11
+
+const (
+	minPrecision = 4
+	maxPrecision = 16
+
+	encodingSparse byte = 1
+	encodingDense  byte = 2
+)
+
+var (
+	// ErrInvalidPrecision возвращается при точности вне диапазона [4, 16]
+	ErrInvalidPrecision = errors.New("invalid HyperLogLog precision")
+
+	// ErrPrecisionMismatch возвращается при слиянии скетчей разной точности
+	ErrPrecisionMismatch = errors.New("HyperLogLog precision mismatch")
+
+	// ErrCorruptedSketch возвращается при некорректном бинарном представлении
+	ErrCorruptedSketch = errors.New("corrupted HyperLogLog sketch")
+)
+
+// Sketch скетч HyperLogLog над 64-битными хэшами
+type Sketch struct {
+	precision uint8
+	registers []uint8
+}
+
+// New создает пустой скетч с заданной точностью
+func New(precision uint8) (*Sketch, error) {
+	if precision < minPrecision || precision > maxPrecision {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidPrecision, precision)
+	}
+
+	return &Sketch{
+		precision: precision,
+		registers: make([]uint8, 1<<precision),
+	}, nil
+}
+
+// NewDefault создает пустой скетч с точностью DefaultPrecision
+func NewDefault() *Sketch {
+	s, _ := New(DefaultPrecision)
+	return s
+}
+
+// Precision возвращает точность скетча
+func (s *Sketch) Precision() uint8 {
+	return s.precision
+}
+
+// Add учитывает элемент по его 64-битному хэшу. Хэш должен быть равномерно
+// распределен по всем битам (см. Mix64)
+func (s *Sketch) Add(hash uint64) {
+	index := hash >> (64 - s.precision)
+	// Ранг - позиция первой единицы в оставшихся битах; сторожевой бит ограничивает ранг
+	rest := hash<<s.precision | 1<<(s.precision-1)
+	rank := uint8(bits.LeadingZeros64(rest)) + 1
+	if rank > s.registers[index] {
+		s.registers[index] = rank
+	}
+}
+
+// Merge объединяет другой скетч с этим
+func (s *Sketch) Merge(other *Sketch) error {
+	if other == nil {
+		return nil
+	}
+	if other.precision != s.precision {
+		return fmt.Errorf("%w: %d and %d", ErrPrecisionMismatch, s.precision, other.precision)
+	}
+
+	for i, rank := range other.registers {
+		if rank > s.registers[i] {
+			s.registers[i] = rank
+		}
+	}
+	return nil
+}
+
+// Estimate возвращает оценку количества уникальных элементов
+func (s *Sketch) Estimate() int64 {
+	m := float64(len(s.registers))
+
+	sum := 0.0
+	zeros := 0
+	for _, rank := range s.registers {
+		sum += 1 / float64(uint64(1)<<rank)
+		if rank == 0 {
+			zeros++
+		}
+	}
+
+	estimate := alpha(len(s.registers)) * m * m / sum
+
+	// Для малых кардинальностей точнее линейный подсчет по пустым регистрам
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return int64(estimate + 0.5)
+}
+
+// alpha корректирующая константа HyperLogLog для m регистров
+func alpha(m int) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	default:
+		return 0.7213 / (1 + 1.079/float64(m))
+	}
+}
+
+// MarshalBinary кодирует скетч. Пока заполнено мало регистров, используется
+// разреженное представление (индекс и ранг непустых регистров), иначе плотное
+func (s *Sketch) MarshalBinary() ([]byte, error) {
+	nonZero := 0
+	for _, rank := range s.registers {
+		if rank != 0 {
+			nonZero++
+		}
+	}
+
+	if sparseSize := 2 + nonZero*3; sparseSize < 2+len(s.registers) {
+		data := make([]byte, 2, sparseSize)
+		data[0], data[1] = encodingSparse, s.precision
+		for i, rank := range s.registers {
+			if rank != 0 {
+				data = binary.BigEndian.AppendUint16(data, uint16(i))
+				data = append(data, rank)
+			}
+		}
+		return data, nil
+	}
+
+	data := make([]byte, 2+len(s.registers))
+	data[0], data[1] = encodingDense, s.precision
+	copy(data[2:], s.registers)
+	return data, nil
+}
+
+// UnmarshalBinary восстанавливает скетч из представления MarshalBinary
+func (s *Sketch) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return ErrCorruptedSketch
+	}
+
+	decoded, err := New(data[1])
+	if err != nil {
+		return err
+	}
+
+	payload := data[2:]
+	switch data[0] {
+	case encodingSparse:
+		if len(payload)%3 != 0 {
+			return ErrCorruptedSketch
+		}
+		for i := 0; i < len(payload); i += 3 {
+			index := int(binary.BigEndian.Uint16(payload[i:]))
+			if index >= len(decoded.registers) {
+				return ErrCorruptedSketch
+			}
+			decoded.registers[index] = payload[i+2]
+		}
+	case encodingDense:
+		if len(payload) != len(decoded.registers) {
+			return ErrCorruptedSketch
+		}
+		copy(decoded.registers, payload)
+	default:
+		return ErrCorruptedSketch
+	}
+
+	*s = *decoded
+	return nil
+}
+
+// Decode восстанавливает скетч из бинарного представления
+func Decode(data []byte) (*Sketch, error) {
+	s := &Sketch{}
+	if err := s.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Mix64 перемешивает биты хэша (финализатор MurmurHash3), чтобы хэш-функции
+// с плохим лавинным эффектом давали равномерное распределение для Add
+func Mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
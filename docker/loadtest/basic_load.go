@@ -19,8 +19,11 @@ type TestResult struct {
 	TotalRequests int64
 	Successful    int64
 	Failed        int64
+	Rejected      int64 // ответы 503 (переполнение очереди кликов)
 	AvgLatency    time.Duration
+	P50Latency    time.Duration
 	P95Latency    time.Duration
+	P99Latency    time.Duration
 }
 
 func runTest(baseURL string, targetRPS int, duration time.Duration) TestResult {
@@ -33,6 +36,7 @@ func runTest(baseURL string, targetRPS int, duration time.Duration) TestResult {
 	var totalRequests int64
 	var successful int64
 	var failed int64
+	var rejected int64
 	var latencies []time.Duration
 	var latenciesMu sync.Mutex
 
@@ -94,6 +98,9 @@ func runTest(baseURL string, targetRPS int, duration time.Duration) TestResult {
 								atomic.AddInt64(&successful, 1)
 							} else {
 								atomic.AddInt64(&failed, 1)
+								if resp.StatusCode == http.StatusServiceUnavailable {
+									atomic.AddInt64(&rejected, 1)
+								}
 							}
 						}
 
@@ -125,14 +132,16 @@ func runTest(baseURL string, targetRPS int, duration time.Duration) TestResult {
 		return latencies[i] < latencies[j]
 	})
 
-	var avgLatency, p95 time.Duration
+	var avgLatency, p50, p95, p99 time.Duration
 	if len(latencies) > 0 {
 		var total time.Duration
 		for _, lat := range latencies {
 			total += lat
 		}
 		avgLatency = total / time.Duration(len(latencies))
+		p50 = latencies[len(latencies)*50/100]
 		p95 = latencies[len(latencies)*95/100]
+		p99 = latencies[len(latencies)*99/100]
 	}
 	latenciesMu.Unlock()
 
@@ -143,8 +152,11 @@ func runTest(baseURL string, targetRPS int, duration time.Duration) TestResult {
 		TotalRequests: finalTotal,
 		Successful:    finalSuccessful,
 		Failed:        finalFailed,
+		Rejected:      atomic.LoadInt64(&rejected),
 		AvgLatency:    avgLatency,
+		P50Latency:    p50,
 		P95Latency:    p95,
+		P99Latency:    p99,
 	}
 
 	fmt.Printf("📈 Results:\n")
@@ -154,8 +166,11 @@ func runTest(baseURL string, targetRPS int, duration time.Duration) TestResult {
 	fmt.Printf("   Total Requests: %d\n", result.TotalRequests)
 	fmt.Printf("   Successful: %d\n", result.Successful)
 	fmt.Printf("   Failed: %d\n", result.Failed)
+	fmt.Printf("   Rejected (503): %d\n", result.Rejected)
 	fmt.Printf("   Avg Latency: %v\n", result.AvgLatency)
+	fmt.Printf("   P50 Latency: %v\n", result.P50Latency)
 	fmt.Printf("   P95 Latency: %v\n", result.P95Latency)
+	fmt.Printf("   P99 Latency: %v\n", result.P99Latency)
 	fmt.Printf("\n")
 
 	return result
@@ -207,9 +222,9 @@ func main() {
 	// Итоговый отчет
 	fmt.Printf("\n📊 FINAL SUMMARY\n")
 	fmt.Printf("================\n")
-	fmt.Printf("%-10s | %-10s | %-10s | %-12s | %-12s | %s\n",
-		"Target", "Actual", "Success%", "AvgLatency", "P95Latency", "Status")
-	fmt.Printf("------------------------------------------------------------------------\n")
+	fmt.Printf("%-10s | %-10s | %-10s | %-12s | %-12s | %-12s | %s\n",
+		"Target", "Actual", "Success%", "AvgLatency", "P95Latency", "P99Latency", "Status")
+	fmt.Printf("---------------------------------------------------------------------------------------\n")
 
 	maxSuccessfulRPS := 0
 	maxActualRPS := 0.0
@@ -232,12 +247,13 @@ func main() {
 			maxActualRPS = result.ActualRPS
 		}
 
-		fmt.Printf("%-10d | %-10.1f | %-9.1f%% | %-12v | %-12v | %s\n",
+		fmt.Printf("%-10d | %-10.1f | %-9.1f%% | %-12v | %-12v | %-12v | %s\n",
 			result.TargetRPS,
 			result.ActualRPS,
 			result.SuccessRate,
 			result.AvgLatency.Truncate(time.Microsecond),
 			result.P95Latency.Truncate(time.Microsecond),
+			result.P99Latency.Truncate(time.Microsecond),
 			status)
 	}
 